@@ -0,0 +1,96 @@
+package abeliantest
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+)
+
+// SetPeers sets the peers reported by getpeerinfo.
+func (s *Server) SetPeers(peers []*abelian.PeerInfo) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.peers = peers
+}
+
+// Peers returns the current peers, including the ones added with addnode.
+func (s *Server) Peers() []*abelian.PeerInfo {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]*abelian.PeerInfo{}, s.peers...)
+}
+
+func (s *Server) handleGetPeerInfo(params []json.RawMessage) (any, *abelian.RPCError) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]*abelian.PeerInfo{}, s.peers...), nil
+}
+
+func (s *Server) handleGetConnectionCount(params []json.RawMessage) (any, *abelian.RPCError) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.peers), nil
+}
+
+func (s *Server) handleGetNetTotals(params []json.RawMessage) (any, *abelian.RPCError) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	totals := &abelian.NetTotals{TimeMillis: time.Now().UnixMilli()}
+	for _, peer := range s.peers {
+		totals.TotalBytesRecv += peer.BytesRecv
+		totals.TotalBytesSent += peer.BytesSent
+	}
+	return totals, nil
+}
+
+func (s *Server) handleAddNode(params []json.RawMessage) (any, *abelian.RPCError) {
+	var addr string
+	var command abelian.AddNodeCommand
+	if rpcErr := Param(params, 0, &addr); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := Param(params, 1, &command); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	switch command {
+	case abelian.AddNodeAdd, abelian.AddNodeOneTry:
+		for _, peer := range s.peers {
+			if peer.Addr == addr {
+				return nil, &abelian.RPCError{Code: abelian.RPC_ERR_MISC, Message: "peer already connected"}
+			}
+		}
+		s.peers = append(s.peers, &abelian.PeerInfo{
+			ID:       int32(len(s.peers) + 1),
+			Addr:     addr,
+			ConnTime: time.Now().Unix(),
+		})
+	case abelian.AddNodeRemove:
+		for i, peer := range s.peers {
+			if peer.Addr == addr {
+				s.peers = append(s.peers[:i], s.peers[i+1:]...)
+				return nil, nil
+			}
+		}
+		return nil, &abelian.RPCError{Code: abelian.RPC_ERR_MISC, Message: "peer not found"}
+	default:
+		return nil, &abelian.RPCError{Code: abelian.RPC_ERR_INVALID_PARAMETER, Message: fmt.Sprintf("invalid subcommand for addnode: %s", command)}
+	}
+	return nil, nil
+}
+
+func (s *Server) handlePing(params []json.RawMessage) (any, *abelian.RPCError) {
+	return nil, nil
+}
+
+func (s *Server) handleUptime(params []json.RawMessage) (any, *abelian.RPCError) {
+	return int64(time.Since(s.startTime).Seconds()), nil
+}
+
+func (s *Server) handleStop(params []json.RawMessage) (any, *abelian.RPCError) {
+	return "abec stopping.", nil
+}
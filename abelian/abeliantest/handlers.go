@@ -0,0 +1,329 @@
+package abeliantest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/pqabelian/abec/chainhash"
+	"github.com/pqabelian/abec/wire"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/crypto"
+)
+
+func (s *Server) handleGetInfo(params []json.RawMessage) (any, *abelian.RPCError) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	info := s.info
+	info.NumBlocks = int64(len(s.blocks)) - 1
+	return &info, nil
+}
+
+func (s *Server) handleGetBlockHash(params []json.RawMessage) (any, *abelian.RPCError) {
+	var height int64
+	if rpcErr := Param(params, 0, &height); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if height < 0 || height >= int64(len(s.blocks)) {
+		return nil, &abelian.RPCError{Code: abelian.RPC_ERR_MISC, Message: "Block number out of range"}
+	}
+	return s.blocks[height].BlockHash, nil
+}
+
+func (s *Server) handleGetBlock(params []json.RawMessage) (any, *abelian.RPCError) {
+	var blockHash string
+	verbosity := 1
+	if rpcErr := Param(params, 0, &blockHash); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := Param(params, 1, &verbosity); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	stored, ok := s.blockByHash[blockHash]
+	if !ok {
+		return nil, &abelian.RPCError{Code: abelian.RPC_ERR_INVALID_ADDRESS_OR_KEY, Message: "Block not found"}
+	}
+	if verbosity == 0 {
+		blockBytes, ok := s.blockBytes[blockHash]
+		if !ok {
+			blockBytes = s.serializeBlock(stored)
+		}
+		return hex.EncodeToString(blockBytes), nil
+	}
+	// like abec, which fails to get the height of a block out of the main chain
+	if s.confirmations(stored) < 0 {
+		return nil, &abelian.RPCError{Code: abelian.RPC_ERR_INTERNAL, Message: fmt.Sprintf("block %s is not in the main chain", blockHash)}
+	}
+
+	block := *stored
+	block.Confirmations = s.confirmations(stored)
+	if block.Confirmations > 0 && block.Height+1 < int64(len(s.blocks)) {
+		block.NextBlockHash = s.blocks[block.Height+1].BlockHash
+	}
+	// like abec, verbosity 2 replaces the hashes of the transactions with the transactions
+	if verbosity > 1 {
+		block.RawTxs = make([]*abelian.Tx, 0, len(block.TxHashes))
+		for _, txID := range block.TxHashes {
+			block.RawTxs = append(block.RawTxs, s.txResult(s.txs[txID]))
+		}
+		block.TxHashes = nil
+	}
+	return &block, nil
+}
+
+// serializeBlock returns block serialized without witness, or nil if one of its transactions has no valid serialized form.
+func (s *Server) serializeBlock(block *abelian.Block) []byte {
+	header, err := blockHeader(block)
+	if err != nil {
+		return nil
+	}
+	msgBlock := &wire.MsgBlockAbe{Header: *header}
+	for _, txID := range block.TxHashes {
+		txBytes, err := hex.DecodeString(s.txs[txID].Hex)
+		if err != nil {
+			return nil
+		}
+		msgTx := &wire.MsgTxAbe{}
+		err = msgTx.Deserialize(bytes.NewReader(txBytes))
+		if err != nil {
+			return nil
+		}
+		msgBlock.AddTransaction(msgTx)
+	}
+	var buf bytes.Buffer
+	err = msgBlock.SerializeNoWitness(&buf)
+	if err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// blockHeader returns the header of block.
+func blockHeader(block *abelian.Block) (*wire.BlockHeader, error) {
+	prevBlock, err := chainhash.NewHashFromStr(block.PrevBlockHash)
+	if err != nil {
+		return nil, err
+	}
+	merkleRoot := &chainhash.Hash{}
+	if block.MerkleRoot != "" {
+		merkleRoot, err = chainhash.NewHashFromStr(block.MerkleRoot)
+		if err != nil {
+			return nil, err
+		}
+	}
+	bits, _ := strconv.ParseUint(block.Bits, 16, 32)
+	header := wire.NewBlockHeaderEthash(int32(block.Version), prevBlock, merkleRoot, uint32(bits), int32(block.Height), block.Nonce, &chainhash.Hash{})
+	header.Timestamp = time.Unix(block.Time, 0)
+	return header, nil
+}
+
+func (s *Server) handleGetRawTransaction(params []json.RawMessage) (any, *abelian.RPCError) {
+	var txID string
+	var verbose bool
+	if rpcErr := Param(params, 0, &txID); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := Param(params, 1, &verbose); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	tx, ok := s.txs[txID]
+	if !ok {
+		return nil, &abelian.RPCError{Code: abelian.RPC_ERR_INVALID_ADDRESS_OR_KEY, Message: fmt.Sprintf("No information available about transaction %v", txID)}
+	}
+	if !verbose {
+		return tx.Hex, nil
+	}
+	return s.txResult(tx), nil
+}
+
+// txResult returns a copy of tx with its confirmations at the current tip.
+func (s *Server) txResult(tx *abelian.Tx) *abelian.Tx {
+	res := *tx
+	if block, ok := s.blockByHash[tx.BlockHash]; ok {
+		res.Confirmations = s.confirmations(block)
+	}
+	return &res
+}
+
+func (s *Server) handleGetRawMempool(params []json.RawMessage) (any, *abelian.RPCError) {
+	var verbose bool
+	if rpcErr := Param(params, 0, &verbose); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if !verbose {
+		return append([]string{}, s.mempool...), nil
+	}
+	res := make(map[string]*abelian.MempoolTx, len(s.mempool))
+	for _, txID := range s.mempool {
+		tx := s.txs[txID]
+		res[txID] = &abelian.MempoolTx{
+			Size:     tx.Size,
+			FullSize: tx.FullSize,
+			Fee:      tx.Fee,
+			Time:     tx.Time,
+			Height:   int64(len(s.blocks)) - 1,
+		}
+	}
+	return res, nil
+}
+
+func (s *Server) handleSendRawTransaction(params []json.RawMessage) (any, *abelian.RPCError) {
+	var txHex string
+	if rpcErr := Param(params, 0, &txHex); rpcErr != nil {
+		return nil, rpcErr
+	}
+	txBytes, err := hex.DecodeString(txHex)
+	if err != nil || len(txBytes) == 0 {
+		return nil, &abelian.RPCError{Code: abelian.RPC_ERR_DESERIALIZATION, Message: fmt.Sprintf("TX decode failed: %v", err)}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	txID := TxID(txBytes)
+	if tx, ok := s.txs[txID]; ok {
+		if tx.BlockHash != "" {
+			return nil, &abelian.RPCError{Code: abelian.RPC_ERR_TX_ALREADY_IN_CHAIN, Message: fmt.Sprintf("TX rejected: transaction already exists: %v", txID)}
+		}
+		return nil, &abelian.RPCError{Code: abelian.RPC_ERR_TX_REJECTED, Message: fmt.Sprintf("TX rejected: already have transaction %v", txID)}
+	}
+	s.addMempoolTx(&abelian.Tx{
+		Hex:      txHex,
+		TxID:     txID,
+		TxHash:   txID,
+		Size:     int64(len(txBytes)),
+		FullSize: int64(len(txBytes)),
+	})
+	return txID, nil
+}
+
+// TxID returns the id the fake server assigns to a transaction broadcast with sendrawtransactionabe:
+// its real id if it deserializes, or a hash of its bytes otherwise.
+func TxID(txBytes []byte) string {
+	txID, err := crypto.GetTxID(txBytes)
+	if err != nil {
+		return hashOf("tx", hex.EncodeToString(txBytes))
+	}
+	return txID
+}
+
+func (s *Server) handleGetBestBlockHash(params []json.RawMessage) (any, *abelian.RPCError) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.blocks[len(s.blocks)-1].BlockHash, nil
+}
+
+func (s *Server) handleGetBlockCount(params []json.RawMessage) (any, *abelian.RPCError) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return int64(len(s.blocks)) - 1, nil
+}
+
+// handleGetBlockHeader serves the verbose header, or as raw header the block hash, since fake blocks have no serialized header.
+func (s *Server) handleGetBlockHeader(params []json.RawMessage) (any, *abelian.RPCError) {
+	var blockHash string
+	verbose := true
+	if rpcErr := Param(params, 0, &blockHash); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := Param(params, 1, &verbose); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	block, ok := s.blockByHash[blockHash]
+	if !ok {
+		return nil, &abelian.RPCError{Code: abelian.RPC_ERR_INVALID_ADDRESS_OR_KEY, Message: "Block not found"}
+	}
+	if !verbose {
+		header, err := blockHeader(block)
+		if err != nil {
+			return nil, &abelian.RPCError{Code: abelian.RPC_ERR_INTERNAL, Message: err.Error()}
+		}
+		var buf bytes.Buffer
+		if err := header.Serialize(&buf); err != nil {
+			return nil, &abelian.RPCError{Code: abelian.RPC_ERR_INTERNAL, Message: err.Error()}
+		}
+		return hex.EncodeToString(buf.Bytes()), nil
+	}
+	header := &abelian.BlockHeader{
+		BlockHash:     block.BlockHash,
+		Confirmations: s.confirmations(block),
+		Height:        block.Height,
+		Version:       block.Version,
+		VersionHex:    block.VersionHex,
+		MerkleRoot:    block.MerkleRoot,
+		Time:          block.Time,
+		Nonce:         block.Nonce,
+		Bits:          block.Bits,
+		Difficulty:    block.Difficulty,
+		PrevBlockHash: block.PrevBlockHash,
+	}
+	if header.Confirmations > 0 && block.Height+1 < int64(len(s.blocks)) {
+		header.NextBlockHash = s.blocks[block.Height+1].BlockHash
+	}
+	return header, nil
+}
+
+func (s *Server) handleGetBlockChainInfo(params []json.RawMessage) (any, *abelian.RPCError) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	tip := s.blocks[len(s.blocks)-1]
+	return &abelian.BlockChainInfo{
+		Chain:         abelian.NetworkID(s.info.NetID).String(),
+		Blocks:        tip.Height,
+		Headers:       tip.Height,
+		BestBlockHash: tip.BlockHash,
+		Difficulty:    tip.Difficulty,
+		MedianTime:    tip.Time,
+	}, nil
+}
+
+func (s *Server) handleGetMempoolInfo(params []json.RawMessage) (any, *abelian.RPCError) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	info := &abelian.MempoolInfo{Size: int64(len(s.mempool))}
+	for _, txID := range s.mempool {
+		info.Bytes += s.txs[txID].Size
+	}
+	return info, nil
+}
+
+func (s *Server) handleGetDifficulty(params []json.RawMessage) (any, *abelian.RPCError) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.blocks[len(s.blocks)-1].Difficulty, nil
+}
+
+func (s *Server) handleGetUtxoRing(params []json.RawMessage) (any, *abelian.RPCError) {
+	ring := &abelian.TXORing{}
+	if rpcErr := Param(params, 0, ring); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, utxoRing := range s.utxoRings {
+		if reflect.DeepEqual(utxoRing.TXORing.BlockHashes, ring.BlockHashes) &&
+			reflect.DeepEqual(utxoRing.TXORing.OutPoints, ring.OutPoints) {
+			return utxoRing, nil
+		}
+	}
+	return nil, &abelian.RPCError{Code: abelian.RPC_ERR_UTXO_RING_NO_INFO, Message: "No information available about utxoRing"}
+}
@@ -0,0 +1,92 @@
+package abeliantest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+)
+
+// SubmittedBlocks returns the blocks received by submitblock, in submission order.
+// They are not decoded, so they do not extend the chain of the server.
+func (s *Server) SubmittedBlocks() [][]byte {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([][]byte{}, s.submittedBlocks...)
+}
+
+func (s *Server) handleGetBlockTemplate(params []json.RawMessage) (any, *abelian.RPCError) {
+	request := &abelian.BlockTemplateRequest{}
+	if rpcErr := Param(params, 0, request); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if request.Mode != "" && request.Mode != "template" {
+		return nil, &abelian.RPCError{Code: abelian.RPC_ERR_INVALID_PARAMETER, Message: fmt.Sprintf("Invalid mode: %s", request.Mode)}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	prev := s.blocks[len(s.blocks)-1]
+	now := time.Now().Unix()
+	template := &abelian.BlockTemplate{
+		Bits:          prev.Bits,
+		CurTime:       now,
+		Height:        prev.Height + 1,
+		PrevBlockHash: prev.BlockHash,
+		SizeLimit:     8000000,
+		Transactions:  make([]*abelian.BlockTemplateTx, 0, len(s.mempool)),
+		Version:       prev.Version,
+		LongPollID:    fmt.Sprintf("%s-%d", prev.BlockHash, len(s.mempool)),
+		Target:        hex.EncodeToString(make([]byte, 32)),
+		MinTime:       prev.Time + 1,
+		MaxTime:       now + 7200,
+		Mutable:       []string{"time", "transactions/add", "prevblock", "coinbase/append"},
+		NonceRange:    "00000000ffffffff",
+		Capabilities:  []string{"proposal"},
+	}
+	for _, txID := range s.mempool {
+		tx := s.txs[txID]
+		template.Transactions = append(template.Transactions, &abelian.BlockTemplateTx{
+			Data:   tx.Hex,
+			TxHash: tx.TxID,
+			Size:   tx.Size,
+		})
+	}
+	template.WorkID = hashOf("work", template.LongPollID)[:16]
+	return template, nil
+}
+
+func (s *Server) handleSubmitBlock(params []json.RawMessage) (any, *abelian.RPCError) {
+	var blockHex string
+	if rpcErr := Param(params, 0, &blockHex); rpcErr != nil {
+		return nil, rpcErr
+	}
+	blockBytes, err := hex.DecodeString(blockHex)
+	if err != nil || len(blockBytes) == 0 {
+		return nil, &abelian.RPCError{Code: abelian.RPC_ERR_DESERIALIZATION, Message: "Block decode failed"}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, submitted := range s.submittedBlocks {
+		if string(submitted) == string(blockBytes) {
+			return "rejected: duplicate block", nil
+		}
+	}
+	s.submittedBlocks = append(s.submittedBlocks, blockBytes)
+	return nil, nil
+}
+
+func (s *Server) handleGetMiningInfo(params []json.RawMessage) (any, *abelian.RPCError) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	tip := s.blocks[len(s.blocks)-1]
+	return &abelian.MiningInfo{
+		Blocks:     tip.Height,
+		Difficulty: tip.Difficulty,
+		PooledTx:   uint64(len(s.mempool)),
+		TestNet:    s.info.IsTestnet,
+	}, nil
+}
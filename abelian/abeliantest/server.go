@@ -0,0 +1,586 @@
+// Package abeliantest provides an in-process fake of the abec JSON-RPC server,
+// so that code built on abelian.Client can be tested without a running node.
+package abeliantest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+)
+
+// HandlerFunc serves one JSON-RPC method of the fake server.
+type HandlerFunc func(params []json.RawMessage) (any, *abelian.RPCError)
+
+// Fault describes a failure injected into the responses of the fake server.
+type Fault struct {
+	RPCError   *abelian.RPCError // answer with this RPC error
+	HTTPStatus int               // answer with this HTTP status instead of a JSON-RPC response
+	Drop       bool              // close the connection without answering
+	Latency    time.Duration     // delay the answer
+}
+
+type injectedFault struct {
+	fault *Fault
+	times int // remaining number of requests to fail, negative for all requests
+}
+
+// Server is a fake abec node serving an in-memory chain over HTTP.
+// The chain starts with a genesis block without transactions at height 0.
+type Server struct {
+	*httptest.Server
+
+	username        string
+	password        string
+	limitedUsername string
+	limitedPassword string
+	batch           bool
+	startTime       time.Time
+
+	mtx             sync.Mutex
+	info            abelian.ChainInfo
+	blocks          []*abelian.Block
+	blockBytes      map[string][]byte
+	blockByHash     map[string]*abelian.Block
+	txs             map[string]*abelian.Tx
+	txSeq           int
+	reorgs          int // mixed into the hashes of the blocks added after a reorganization
+	mempool         []string
+	utxoRings       []*abelian.UtxoRing
+	peers           []*abelian.PeerInfo
+	submittedBlocks [][]byte
+	handlers        map[string]HandlerFunc
+	faults          map[string][]*injectedFault
+	latency         map[string]time.Duration
+	calls           map[string]int
+	wsClients       map[*wsClient]struct{}
+}
+
+// Option change the fake server
+type Option func(*Server)
+
+// WithAuth requires the basic auth credentials on every request.
+func WithAuth(username string, password string) Option {
+	return func(s *Server) {
+		s.username = username
+		s.password = password
+	}
+}
+
+// WithLimitedAuth accepts the credentials of a limited user, which may only call the methods abec allows to rpclimituser.
+func WithLimitedAuth(username string, password string) Option {
+	return func(s *Server) {
+		s.limitedUsername = username
+		s.limitedPassword = password
+	}
+}
+
+// WithNetID sets the network id reported by getinfo.
+func WithNetID(netID abelian.NetworkID) Option {
+	return func(s *Server) {
+		s.info.NetID = uint8(netID)
+	}
+}
+
+// WithVersion sets the node version and protocol version reported by getinfo.
+func WithVersion(version abelian.NodeVersion, protocolVersion int64) Option {
+	return func(s *Server) {
+		s.info.Version = version.Major*1000000 + version.Minor*10000 + version.Patch*100
+		s.info.ProtocolVersion = protocolVersion
+	}
+}
+
+// WithBatch makes the server accept JSON-RPC batch requests, which abec itself does not.
+func WithBatch() Option {
+	return func(s *Server) {
+		s.batch = true
+	}
+}
+
+// NewServer starts a fake abec server. Close it when done.
+func NewServer(options ...Option) *Server {
+	s := &Server{
+		info: abelian.ChainInfo{
+			Version:         1000000, // abec 1.0.0
+			ProtocolVersion: 70002,
+			RelayFee:        10, // neutrino per kB, 1e-06 ABEL
+		},
+		blockBytes:  map[string][]byte{},
+		blockByHash: map[string]*abelian.Block{},
+		txs:         map[string]*abelian.Tx{},
+		faults:      map[string][]*injectedFault{},
+		latency:     map[string]time.Duration{},
+		calls:       map[string]int{},
+		wsClients:   map[*wsClient]struct{}{},
+		startTime:   time.Now(),
+	}
+	s.handlers = map[string]HandlerFunc{
+		"getinfo":               s.handleGetInfo,
+		"getblockhash":          s.handleGetBlockHash,
+		"getblock":              s.handleGetBlock,
+		"getblockabe":           s.handleGetBlock,
+		"getrawtransaction":     s.handleGetRawTransaction,
+		"getrawmempool":         s.handleGetRawMempool,
+		"sendrawtransaction":    s.handleSendRawTransaction,
+		"sendrawtransactionabe": s.handleSendRawTransaction,
+		"getbestblockhash":      s.handleGetBestBlockHash,
+		"getblockcount":         s.handleGetBlockCount,
+		"getblockheader":        s.handleGetBlockHeader,
+		"getblockchaininfo":     s.handleGetBlockChainInfo,
+		"getmempoolinfo":        s.handleGetMempoolInfo,
+		"getdifficulty":         s.handleGetDifficulty,
+		"getutxoring":           s.handleGetUtxoRing,
+		"getpeerinfo":           s.handleGetPeerInfo,
+		"getconnectioncount":    s.handleGetConnectionCount,
+		"getnettotals":          s.handleGetNetTotals,
+		"addnode":               s.handleAddNode,
+		"ping":                  s.handlePing,
+		"uptime":                s.handleUptime,
+		"stop":                  s.handleStop,
+		"getblocktemplate":      s.handleGetBlockTemplate,
+		"submitblock":           s.handleSubmitBlock,
+		"getmininginfo":         s.handleGetMiningInfo,
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	s.AddBlock()
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// ClientConfig returns a config for a client connecting to the fake server.
+func (s *Server) ClientConfig(options ...abelian.ClientOption) *abelian.ClientConfig {
+	options = append([]abelian.ClientOption{abelian.WithAuth(s.username, s.password)}, options...)
+	return abelian.NewClientConfig(s.URL, options...)
+}
+
+// NewClient returns a client connected to the fake server.
+func (s *Server) NewClient(options ...abelian.ClientOption) (*abelian.Client, error) {
+	return abelian.NewClient(s.ClientConfig(options...))
+}
+
+// Transport returns a transport serving the calls in memory, without going through the network,
+// authenticated as the admin user. Use it with abelian.WithTransport.
+func (s *Server) Transport() abelian.Transport {
+	return abelian.TransportFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(s.username, s.password)
+		recorder := httptest.NewRecorder()
+		s.serveHTTP(recorder, req)
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		if recorder.Code != http.StatusOK {
+			return nil, &abelian.HTTPError{StatusCode: recorder.Code, Message: strings.TrimSpace(recorder.Body.String())}
+		}
+		return recorder.Body.Bytes(), nil
+	})
+}
+
+// Handle registers handler for method, replacing the built-in one if any.
+func (s *Server) Handle(method string, handler HandlerFunc) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.handlers[method] = handler
+}
+
+// SetChainInfo overrides the getinfo result. The number of blocks is always derived from the chain.
+func (s *Server) SetChainInfo(info abelian.ChainInfo) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.info = info
+}
+
+// AddBlock appends a block containing txs to the chain and returns it.
+// Missing hashes of the transactions are derived from their hex, and the
+// transactions are removed from the mempool. getblockabe with verbosity 0
+// returns the block serialized from the hex of its transactions, or nothing
+// if one of them is not a serialized transaction.
+func (s *Server) AddBlock(txs ...*abelian.Tx) *abelian.Block {
+	return s.AddBlockWithBytes(nil, txs...)
+}
+
+// AddBlockWithBytes is like AddBlock, additionally setting the serialized block returned by getblockabe with verbosity 0.
+func (s *Server) AddBlockWithBytes(blockBytes []byte, txs ...*abelian.Tx) *abelian.Block {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	height := int64(len(s.blocks))
+	block := &abelian.Block{
+		Height:     height,
+		Version:    0x10000000,
+		VersionHex: "10000000",
+		Time:       1650198200 + height*256,
+		Difficulty: 1,
+		Bits:       "1d00ffff",
+		TxHashes:   make([]string, 0, len(txs)),
+	}
+	if height > 0 {
+		prev := s.blocks[height-1]
+		block.PrevBlockHash = prev.BlockHash
+	} else {
+		block.PrevBlockHash = hex.EncodeToString(make([]byte, sha256.Size))
+	}
+	for _, tx := range txs {
+		s.assignTxID(tx)
+		block.TxHashes = append(block.TxHashes, tx.TxID)
+	}
+	block.BlockHash = hashOf("block", block.PrevBlockHash, fmt.Sprint(block.TxHashes))
+	if s.reorgs > 0 {
+		block.BlockHash = hashOf("block", block.PrevBlockHash, fmt.Sprint(block.TxHashes), fmt.Sprint(s.reorgs))
+	}
+
+	for _, tx := range txs {
+		tx.BlockHash = block.BlockHash
+		tx.BlockTime = block.Time
+		tx.Time = block.Time
+		s.txs[tx.TxID] = tx
+		s.removeFromMempool(tx.TxID)
+	}
+	s.blocks = append(s.blocks, block)
+	s.blockByHash[block.BlockHash] = block
+	if blockBytes != nil {
+		s.blockBytes[block.BlockHash] = blockBytes
+	}
+	s.notifyLocked("blockabeconnected", block.BlockHash, block.Height, block.Time)
+	return block
+}
+
+// Reorg disconnects the depth blocks at the tip, as if a longer chain forked below them, and returns
+// them. Like the side chain blocks of abec, they can still be fetched serialized by hash but
+// no longer described, and their transactions return to the mempool. Blocks added afterwards form the new branch.
+func (s *Server) Reorg(depth int) []*abelian.Block {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	depth = min(depth, len(s.blocks)-1) // the genesis block stays
+	first := len(s.blocks) - depth
+	disconnected := append([]*abelian.Block{}, s.blocks[first:]...)
+	s.blocks = s.blocks[:first]
+	for i := len(disconnected) - 1; i >= 0; i-- {
+		block := disconnected[i]
+		s.notifyLocked("blockabedisconnected", block.BlockHash, block.Height, block.Time)
+	}
+	for _, block := range disconnected {
+		for _, txID := range block.TxHashes {
+			tx := s.txs[txID]
+			tx.BlockHash = ""
+			tx.BlockTime = 0
+			s.mempool = append(s.mempool, txID)
+		}
+	}
+	s.reorgs++
+	return disconnected
+}
+
+// confirmations returns the confirmations of block at the current tip, -1 if it is not on the main chain.
+func (s *Server) confirmations(block *abelian.Block) int64 {
+	if block.Height >= int64(len(s.blocks)) || s.blocks[block.Height] != block {
+		return -1
+	}
+	return int64(len(s.blocks)) - block.Height
+}
+
+// AddMempoolTx adds tx to the mempool. A missing hash is derived from its hex.
+func (s *Server) AddMempoolTx(tx *abelian.Tx) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.addMempoolTx(tx)
+}
+
+func (s *Server) addMempoolTx(tx *abelian.Tx) {
+	s.assignTxID(tx)
+	if tx.Time == 0 {
+		tx.Time = time.Now().Unix()
+	}
+	s.txs[tx.TxID] = tx
+	s.mempool = append(s.mempool, tx.TxID)
+	s.notifyLocked("txaccepted", tx.TxID, int64(len(s.blocks))-1)
+}
+
+// assignTxID sets the missing hashes of tx, derived from its serialized form if any.
+func (s *Server) assignTxID(tx *abelian.Tx) {
+	if tx.TxID == "" {
+		if txBytes, err := hex.DecodeString(tx.Hex); err == nil && len(txBytes) > 0 {
+			tx.TxID = TxID(txBytes)
+		} else if tx.Hex != "" {
+			tx.TxID = hashOf("tx", tx.Hex)
+		} else {
+			s.txSeq++
+			tx.TxID = hashOf("tx", tx.Memo, fmt.Sprint(s.txSeq))
+		}
+	}
+	if tx.TxHash == "" {
+		tx.TxHash = tx.TxID
+	}
+}
+
+func (s *Server) removeFromMempool(txID string) {
+	for i, id := range s.mempool {
+		if id == txID {
+			s.mempool = append(s.mempool[:i], s.mempool[i+1:]...)
+			return
+		}
+	}
+}
+
+// AddUtxoRing makes ring the getutxoring answer for its TXO ring.
+func (s *Server) AddUtxoRing(ring *abelian.UtxoRing) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.utxoRings = append(s.utxoRings, ring)
+}
+
+// Height returns the height of the tip.
+func (s *Server) Height() int64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return int64(len(s.blocks)) - 1
+}
+
+// BlockByHeight returns the block at height, or nil.
+func (s *Server) BlockByHeight(height int64) *abelian.Block {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if height < 0 || height >= int64(len(s.blocks)) {
+		return nil
+	}
+	return s.blocks[height]
+}
+
+// Mempool returns the ids of the transactions in the mempool.
+func (s *Server) Mempool() []string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]string{}, s.mempool...)
+}
+
+// InjectFault makes the next times requests of method fail with fault.
+// An empty method matches all methods, and a negative times fails all subsequent requests.
+func (s *Server) InjectFault(method string, fault *Fault, times int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.faults[method] = append(s.faults[method], &injectedFault{fault: fault, times: times})
+}
+
+// InjectError makes the next times requests of method fail with the RPC error.
+func (s *Server) InjectError(method string, rpcErr *abelian.RPCError, times int) {
+	s.InjectFault(method, &Fault{RPCError: rpcErr}, times)
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.faults = map[string][]*injectedFault{}
+}
+
+// SetLatency delays every answer to method by latency. An empty method matches all methods.
+func (s *Server) SetLatency(method string, latency time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.latency[method] = latency
+}
+
+// CallCount returns the number of requests received for method. An empty method counts all requests.
+func (s *Server) CallCount(method string) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.calls[method]
+}
+
+type rpcRequest struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	ID     json.RawMessage   `json:"id"`
+}
+
+type rpcResponse struct {
+	Result any               `json:"result"`
+	Error  *abelian.RPCError `json:"error"`
+	ID     json.RawMessage   `json:"id"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	isAdmin := true
+	if s.username != "" || s.password != "" || s.limitedUsername != "" || s.limitedPassword != "" {
+		username, password, ok := r.BasicAuth()
+		switch {
+		case ok && username == s.username && password == s.password:
+		case ok && username == s.limitedUsername && password == s.limitedPassword:
+			isAdmin = false
+		default:
+			w.Header().Add("WWW-Authenticate", `Basic realm="abec RPC"`)
+			http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if r.URL.Path == "/ws" {
+		s.serveWebsocket(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("400 error reading JSON message: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var reqs []*rpcRequest
+	isBatch := s.batch && json.Unmarshal(body, &reqs) == nil
+	if !isBatch {
+		req := &rpcRequest{}
+		err = json.Unmarshal(body, req)
+		if err != nil {
+			writeJSON(w, &rpcResponse{
+				Error: &abelian.RPCError{Code: abelian.RPC_ERR_PARSE, Message: "Parse error"},
+				ID:    json.RawMessage("null"),
+			})
+			return
+		}
+		reqs = []*rpcRequest{req}
+	}
+
+	resps := make([]*rpcResponse, 0, len(reqs))
+	for _, req := range reqs {
+		fault, latency := s.prepare(req.Method)
+		if latency > 0 {
+			time.Sleep(latency)
+		}
+		if fault != nil {
+			if fault.Drop {
+				if hj, ok := w.(http.Hijacker); ok {
+					conn, _, err := hj.Hijack()
+					if err == nil {
+						conn.Close()
+						return
+					}
+				}
+				http.Error(w, "connection dropped", http.StatusInternalServerError)
+				return
+			}
+			if fault.HTTPStatus != 0 {
+				http.Error(w, fmt.Sprintf("%d %s", fault.HTTPStatus, http.StatusText(fault.HTTPStatus)), fault.HTTPStatus)
+				return
+			}
+			if fault.RPCError != nil {
+				resps = append(resps, &rpcResponse{Error: fault.RPCError, ID: req.ID})
+				continue
+			}
+		}
+		if !isAdmin && !limitedMethods[req.Method] {
+			resps = append(resps, &rpcResponse{
+				Error: &abelian.RPCError{Code: abelian.RPC_ERR_INVALID_PARAMS, Message: "limited user not authorized for this method"},
+				ID:    req.ID,
+			})
+			continue
+		}
+		result, rpcErr := s.dispatch(req)
+		resps = append(resps, &rpcResponse{Result: result, Error: rpcErr, ID: req.ID})
+	}
+
+	if isBatch {
+		writeJSON(w, resps)
+		return
+	}
+	writeJSON(w, resps[0])
+}
+
+// limitedMethods are the methods abec allows to its limited user.
+var limitedMethods = map[string]bool{
+	"loadtxfilter": true, "notifyblocks": true, "notifynewtransactions": true, "notifyreceived": true,
+	"notifyspent": true, "rescan": true, "rescanblocks": true, "session": true, "help": true,
+	"createrawtransaction": true, "decoderawtransaction": true, "decodescript": true, "estimatefee": true,
+	"getbestblock": true, "getbestblockhash": true, "getblock": true, "getblockcount": true,
+	"getblockhash": true, "getblockheader": true, "getcfilter": true, "getcfilterheader": true,
+	"getcurrentnet": true, "getdifficulty": true, "getheaders": true, "getinfo": true,
+	"getnettotals": true, "getnetworkhashps": true, "getrawmempool": true, "getrawtransaction": true,
+	"gettxout": true, "searchrawtransactions": true, "sendrawtransaction": true, "submitblock": true,
+	"uptime": true, "validateaddress": true, "verifymessage": true, "version": true,
+}
+
+// prepare counts the request and returns the fault and latency applying to it.
+func (s *Server) prepare(method string) (*Fault, time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.calls[method]++
+	s.calls[""]++
+
+	latency := s.latency[""] + s.latency[method]
+	for _, key := range []string{method, ""} {
+		faults := s.faults[key]
+		for len(faults) > 0 && faults[0].times == 0 {
+			faults = faults[1:]
+		}
+		s.faults[key] = faults
+		if len(faults) == 0 {
+			continue
+		}
+		if faults[0].times > 0 {
+			faults[0].times--
+		}
+		return faults[0].fault, latency + faults[0].fault.Latency
+	}
+	return nil, latency
+}
+
+func (s *Server) dispatch(req *rpcRequest) (any, *abelian.RPCError) {
+	s.mtx.Lock()
+	handler, ok := s.handlers[req.Method]
+	s.mtx.Unlock()
+	if !ok {
+		return nil, &abelian.RPCError{Code: abelian.RPC_ERR_METHOD_NOT_FOUND, Message: "Method not found"}
+	}
+	return handler(req.Params)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func hashOf(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		var l [8]byte
+		binary.LittleEndian.PutUint64(l[:], uint64(len(part)))
+		h.Write(l[:])
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ParamError returns the RPC error abec answers for invalid parameters.
+func ParamError(format string, a ...any) *abelian.RPCError {
+	return &abelian.RPCError{Code: abelian.RPC_ERR_INVALID_PARAMETER, Message: fmt.Sprintf(format, a...)}
+}
+
+// Param decodes the i-th parameter into v. A missing parameter leaves v unchanged.
+func Param(params []json.RawMessage, i int, v any) *abelian.RPCError {
+	if i >= len(params) {
+		return nil
+	}
+	err := json.Unmarshal(params[i], v)
+	if err != nil {
+		return &abelian.RPCError{Code: abelian.RPC_ERR_INVALID_PARAMS, Message: fmt.Sprintf("Invalid parameters: %v", err)}
+	}
+	return nil
+}
@@ -0,0 +1,412 @@
+package abeliantest_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/pqabelian/abec/wire"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+type rpcResponse struct {
+	Result json.RawMessage   `json:"result"`
+	Error  *abelian.RPCError `json:"error"`
+}
+
+// post sends body to server with the credentials, and returns the HTTP status and the response body.
+func post(t *testing.T, server *abeliantest.Server, username string, password string, body any) (int, []byte) {
+	t.Helper()
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, buf.Bytes()
+}
+
+func request(method string, params ...any) map[string]any {
+	if params == nil {
+		params = []any{}
+	}
+	return map[string]any{"jsonrpc": "1.0", "id": 1, "method": method, "params": params}
+}
+
+// call calls method on server without credentials, and decodes its result into res.
+func call(t *testing.T, server *abeliantest.Server, res any, method string, params ...any) *abelian.RPCError {
+	t.Helper()
+	status, body := post(t, server, "", "", request(method, params...))
+	if status != http.StatusOK {
+		t.Fatalf("%s: HTTP status %d: %s", method, status, body)
+	}
+	resp := &rpcResponse{}
+	if err := json.Unmarshal(body, resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if res != nil {
+		if err := json.Unmarshal(resp.Result, res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return nil
+}
+
+// coinbaseTx returns a serialized coinbase transaction of height and its id.
+func coinbaseTx(t *testing.T, height int32) (string, string) {
+	t.Helper()
+	msgTx := wire.NewMsgTxAbe(wire.TxVersion)
+	txIn, err := wire.NewStandardCoinbaseTxIn(height, wire.TxVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgTx.AddTxIn(txIn)
+	var buf bytes.Buffer
+	if err := msgTx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(buf.Bytes()), msgTx.TxHash().String()
+}
+
+func TestServerChain(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	txHex := "aa01"
+	txID := abeliantest.TxID([]byte{0xaa, 0x01})
+	server.AddMempoolTx(&abelian.Tx{Hex: txHex})
+	if mempool := server.Mempool(); !reflect.DeepEqual(mempool, []string{txID}) {
+		t.Fatalf("mempool = %v, want %s", mempool, txID)
+	}
+	block := server.AddBlock(&abelian.Tx{Hex: txHex})
+
+	if height := server.Height(); height != 1 || server.BlockByHeight(1) != block {
+		t.Fatalf("tip at %d is %v, want %v at 1", height, server.BlockByHeight(height), block)
+	}
+	if block.PrevBlockHash != server.BlockByHeight(0).BlockHash || !reflect.DeepEqual(block.TxHashes, []string{txID}) {
+		t.Errorf("block = %+v, want a child of the genesis block with %s", block, txID)
+	}
+	if mempool := server.Mempool(); len(mempool) != 0 {
+		t.Errorf("mempool = %v after mining its transaction", mempool)
+	}
+	var blockHash string
+	if rpcErr := call(t, server, &blockHash, "getblockhash", 1); rpcErr != nil || blockHash != block.BlockHash {
+		t.Errorf("getblockhash 1 = %s, %v, want %s", blockHash, rpcErr, block.BlockHash)
+	}
+	if rpcErr := call(t, server, nil, "getblockhash", 2); rpcErr == nil || rpcErr.Code != -1 {
+		t.Errorf("getblockhash beyond the tip = %v, want RPC error -1", rpcErr)
+	}
+}
+
+func TestServerGetBlock(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	txHex := "aa01"
+	txID := abeliantest.TxID([]byte{0xaa, 0x01})
+	block := server.AddBlockWithBytes([]byte{1, 2, 3}, &abelian.Tx{Hex: txHex})
+	next := server.AddBlock()
+
+	tests := []struct {
+		name      string
+		verbosity int
+		wantTxs   bool // whether the transactions are given in full
+	}{
+		{"bytes", 0, false},
+		{"verbose", 1, false},
+		{"verbose with transactions", 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.verbosity == 0 {
+				var blockHex string
+				if rpcErr := call(t, server, &blockHex, "getblockabe", block.BlockHash, 0); rpcErr != nil {
+					t.Fatal(rpcErr)
+				}
+				if blockHex != hex.EncodeToString([]byte{1, 2, 3}) {
+					t.Errorf("block bytes = %s, want 010203", blockHex)
+				}
+				return
+			}
+
+			res := &abelian.Block{}
+			if rpcErr := call(t, server, res, "getblockabe", block.BlockHash, tt.verbosity); rpcErr != nil {
+				t.Fatal(rpcErr)
+			}
+			if res.Confirmations != 2 || res.NextBlockHash != next.BlockHash {
+				t.Errorf("confirmations %d, next block %s, want 2 and %s", res.Confirmations, res.NextBlockHash, next.BlockHash)
+			}
+			// like abec, the transactions replace their hashes
+			if tt.wantTxs != (len(res.TxHashes) == 0) || (!tt.wantTxs && !reflect.DeepEqual(res.TxHashes, []string{txID})) {
+				t.Errorf("transaction hashes = %v, want %s", res.TxHashes, txID)
+			}
+			if tt.wantTxs != (len(res.RawTxs) == 1 && res.RawTxs[0].Hex == txHex) {
+				t.Errorf("transactions = %+v", res.RawTxs)
+			}
+		})
+	}
+	if rpcErr := call(t, server, nil, "getblockabe", txID, 1); rpcErr == nil || rpcErr.Code != -5 {
+		t.Errorf("getblockabe of an unknown block = %v, want RPC error -5", rpcErr)
+	}
+}
+
+func TestServerSerializeBlock(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	txHex, txID := coinbaseTx(t, 1)
+	tests := []struct {
+		name    string
+		block   *abelian.Block
+		wantTxs []string // nil if the block can not be serialized
+	}{
+		{"serialized transactions", server.AddBlock(&abelian.Tx{Hex: txHex}), []string{txID}},
+		{"no transaction", server.AddBlock(), []string{}},
+		{"invalid transactions", server.AddBlock(&abelian.Tx{Hex: "00ff"}), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var blockHex string
+			if rpcErr := call(t, server, &blockHex, "getblockabe", tt.block.BlockHash, 0); rpcErr != nil {
+				t.Fatal(rpcErr)
+			}
+			if tt.wantTxs == nil {
+				if blockHex != "" {
+					t.Errorf("block bytes = %s, want none", blockHex)
+				}
+				return
+			}
+			blockBytes, err := hex.DecodeString(blockHex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msgBlock := &wire.MsgBlockAbe{}
+			if err := msgBlock.DeserializeNoWitness(bytes.NewReader(blockBytes)); err != nil {
+				t.Fatal(err)
+			}
+			txIDs := make([]string, 0, len(msgBlock.Transactions))
+			for _, msgTx := range msgBlock.Transactions {
+				txIDs = append(txIDs, msgTx.TxHash().String())
+			}
+			if !reflect.DeepEqual(txIDs, tt.wantTxs) || msgBlock.Header.PrevBlock.String() != tt.block.PrevBlockHash ||
+				msgBlock.Header.Timestamp.Unix() != tt.block.Time {
+				t.Errorf("serialized block with %v after %s, want %+v", txIDs, msgBlock.Header.PrevBlock, tt.block)
+			}
+		})
+	}
+}
+
+func TestServerReorg(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	txHex, txID := coinbaseTx(t, 1)
+	server.AddBlock(&abelian.Tx{Hex: txHex})
+	orphaned := server.Reorg(1)
+	if len(orphaned) != 1 || server.Height() != 0 {
+		t.Fatalf("Reorg(1) = %v with the tip at %d, want 1 block and the tip at 0", orphaned, server.Height())
+	}
+	if mempool := server.Mempool(); !reflect.DeepEqual(mempool, []string{txID}) {
+		t.Errorf("mempool = %v, want %s back", mempool, txID)
+	}
+	next := server.AddBlock(&abelian.Tx{Hex: txHex})
+	if next.BlockHash == orphaned[0].BlockHash {
+		t.Errorf("block of the new branch has the hash of the orphaned one")
+	}
+
+	// like abec, side chain blocks are served serialized but not described
+	var blockHex string
+	if rpcErr := call(t, server, &blockHex, "getblockabe", orphaned[0].BlockHash, 0); rpcErr != nil || blockHex == "" {
+		t.Errorf("getblockabe of the orphaned block with verbosity 0 = %q, %v", blockHex, rpcErr)
+	}
+	for _, verbosity := range []int{1, 2} {
+		rpcErr := call(t, server, nil, "getblockabe", orphaned[0].BlockHash, verbosity)
+		if rpcErr == nil || rpcErr.Code != abelian.RPC_ERR_INTERNAL {
+			t.Errorf("getblockabe of the orphaned block with verbosity %d = %v, want RPC error %d", verbosity, rpcErr, abelian.RPC_ERR_INTERNAL)
+		}
+	}
+}
+
+func TestServerSendRawTransaction(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	mined := &abelian.Tx{Hex: "aa01"}
+	server.AddBlock(mined)
+	tests := []struct {
+		name     string
+		txHex    string
+		wantCode int
+	}{
+		{"new", "bb01", 0},
+		{"in the mempool", "bb01", -26},
+		{"in a block", mined.Hex, -27},
+		{"undecodable", "zz", -22},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var txID string
+			rpcErr := call(t, server, &txID, "sendrawtransactionabe", tt.txHex)
+			if tt.wantCode != 0 {
+				if rpcErr == nil || rpcErr.Code != tt.wantCode {
+					t.Errorf("error = %v, want RPC error %d", rpcErr, tt.wantCode)
+				}
+				return
+			}
+			txBytes, _ := hex.DecodeString(tt.txHex)
+			if rpcErr != nil || txID != abeliantest.TxID(txBytes) {
+				t.Errorf("txid = %s, %v, want %s", txID, rpcErr, abeliantest.TxID(txBytes))
+			}
+			if mempool := server.Mempool(); !reflect.DeepEqual(mempool, []string{txID}) {
+				t.Errorf("mempool = %v, want %s", mempool, txID)
+			}
+		})
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string // method of the fault
+		fault      *abeliantest.Fault
+		times      int
+		wantFailed int // failed calls of getblockhash out of 3
+		wantStatus int
+	}{
+		{"rpc error once", "getblockhash", &abeliantest.Fault{RPCError: &abelian.RPCError{Code: -28}}, 1, 1, http.StatusOK},
+		{"http status twice", "getblockhash", &abeliantest.Fault{HTTPStatus: http.StatusServiceUnavailable}, 2, 2, http.StatusServiceUnavailable},
+		{"always", "getblockhash", &abeliantest.Fault{RPCError: &abelian.RPCError{Code: -1}}, -1, 3, http.StatusOK},
+		{"all methods", "", &abeliantest.Fault{RPCError: &abelian.RPCError{Code: -1}}, 1, 1, http.StatusOK},
+		{"other method", "getinfo", &abeliantest.Fault{RPCError: &abelian.RPCError{Code: -1}}, -1, 0, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := abeliantest.NewServer()
+			defer server.Close()
+			server.InjectFault(tt.method, tt.fault, tt.times)
+			failed := 0
+			for i := 0; i < 3; i++ {
+				status, body := post(t, server, "", "", request("getblockhash", 0))
+				resp := &rpcResponse{}
+				if status == http.StatusOK {
+					if err := json.Unmarshal(body, resp); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if status != http.StatusOK || resp.Error != nil {
+					failed++
+					if status != tt.wantStatus {
+						t.Errorf("HTTP status = %d, want %d", status, tt.wantStatus)
+					}
+				}
+			}
+			if failed != tt.wantFailed {
+				t.Errorf("%d failed calls, want %d", failed, tt.wantFailed)
+			}
+			if calls := server.CallCount("getblockhash"); calls != 3 {
+				t.Errorf("getblockhash calls = %d, want 3", calls)
+			}
+		})
+	}
+}
+
+func TestServerAuth(t *testing.T) {
+	server := abeliantest.NewServer(abeliantest.WithAuth("admin", "secret"))
+	defer server.Close()
+	tests := []struct {
+		name       string
+		username   string
+		password   string
+		method     string
+		wantStatus int
+		wantCode   int
+	}{
+		{"no credentials", "", "", "getinfo", http.StatusUnauthorized, 0},
+		{"wrong password", "admin", "wrong", "getinfo", http.StatusUnauthorized, 0},
+		{"admin", "admin", "secret", "getinfo", http.StatusOK, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := post(t, server, tt.username, tt.password, request(tt.method))
+			if status != tt.wantStatus {
+				t.Fatalf("HTTP status = %d, want %d", status, tt.wantStatus)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			resp := &rpcResponse{}
+			if err := json.Unmarshal(body, resp); err != nil {
+				t.Fatal(err)
+			}
+			if (resp.Error == nil && tt.wantCode != 0) || (resp.Error != nil && resp.Error.Code != tt.wantCode) {
+				t.Errorf("error = %v, want code %d", resp.Error, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestServerBatch(t *testing.T) {
+	batch := []any{request("getinfo"), request("getblockhash", 0), request("nosuchmethod")}
+	tests := []struct {
+		name    string
+		options []abeliantest.Option
+	}{
+		{"batch", []abeliantest.Option{abeliantest.WithBatch()}},
+		{"no batch, like abec", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := abeliantest.NewServer(tt.options...)
+			defer server.Close()
+			_, body := post(t, server, "", "", batch)
+			if tt.options == nil {
+				resp := &rpcResponse{}
+				if err := json.Unmarshal(body, resp); err != nil || resp.Error == nil || resp.Error.Code != -32700 {
+					t.Errorf("response = %s, want a parse error", body)
+				}
+				return
+			}
+			var resps []*rpcResponse
+			if err := json.Unmarshal(body, &resps); err != nil {
+				t.Fatal(err)
+			}
+			if len(resps) != 3 || resps[0].Error != nil || resps[1].Error != nil ||
+				resps[2].Error == nil || resps[2].Error.Code != -32601 {
+				t.Errorf("responses = %s, want 2 results and a method not found", body)
+			}
+		})
+	}
+}
+
+func TestServerHandle(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	server.Handle("getblockhash", func(params []json.RawMessage) (any, *abelian.RPCError) {
+		return "0102", nil
+	})
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if blockHash, err := client.GetBlockHash(5); err != nil || blockHash != "0102" {
+		t.Errorf("GetBlockHash = %v, %v, want 0102", blockHash, err)
+	}
+}
@@ -0,0 +1,161 @@
+package abeliantest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+)
+
+// wsSendBuffer is the number of messages queued for a websocket client before it is dropped.
+const wsSendBuffer = 256
+
+// wsClient is a websocket connection to the fake server, with its subscriptions.
+type wsClient struct {
+	conn *websocket.Conn
+	send chan []byte
+	once sync.Once
+
+	notifyBlocks bool
+	notifyTxs    bool
+}
+
+func (c *wsClient) close() {
+	c.once.Do(func() {
+		close(c.send)
+		c.conn.Close()
+	})
+}
+
+type wsNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+	ID      any    `json:"id"`
+}
+
+var wsUpgrader = websocket.Upgrader{}
+
+// serveWebsocket serves the /ws endpoint of abec: JSON-RPC requests, including notifyblocks and
+// notifynewtransactions, answered on the connection, and the notifications of the subscriptions.
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	client := &wsClient{conn: conn, send: make(chan []byte, wsSendBuffer)}
+	s.mtx.Lock()
+	s.wsClients[client] = struct{}{}
+	s.mtx.Unlock()
+
+	go func() {
+		for msg := range client.send {
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				conn.Close()
+			}
+		}
+	}()
+
+	defer func() {
+		s.mtx.Lock()
+		delete(s.wsClients, client)
+		s.mtx.Unlock()
+		client.close()
+	}()
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		req := &rpcRequest{}
+		if err := json.Unmarshal(data, req); err != nil {
+			continue
+		}
+		s.prepare(req.Method)
+		result, rpcErr := s.dispatchWebsocket(client, req)
+		resp, _ := json.Marshal(&rpcResponse{Result: result, Error: rpcErr, ID: req.ID})
+
+		s.mtx.Lock()
+		s.sendLocked(client, resp)
+		s.mtx.Unlock()
+	}
+}
+
+func (s *Server) dispatchWebsocket(client *wsClient, req *rpcRequest) (any, *abelian.RPCError) {
+	s.mtx.Lock()
+	switch req.Method {
+	case "notifyblocks":
+		client.notifyBlocks = true
+	case "stopnotifyblocks":
+		client.notifyBlocks = false
+	case "notifynewtransactions":
+		client.notifyTxs = true
+	case "stopnotifynewtransactions":
+		client.notifyTxs = false
+	default:
+		s.mtx.Unlock()
+		return s.dispatch(req)
+	}
+	s.mtx.Unlock()
+	return nil, nil
+}
+
+// sendLocked queues msg for client, and drops a client which does not keep up.
+func (s *Server) sendLocked(client *wsClient, msg []byte) {
+	if _, ok := s.wsClients[client]; !ok {
+		return
+	}
+	select {
+	case client.send <- msg:
+	default:
+		delete(s.wsClients, client)
+		client.close()
+	}
+}
+
+// notifyLocked sends a notification to the websocket clients subscribed to it.
+func (s *Server) notifyLocked(method string, params ...any) {
+	msg, _ := json.Marshal(&wsNotification{JSONRPC: "1.0", Method: method, Params: params})
+	for client := range s.wsClients {
+		switch method {
+		case "blockabeconnected", "blockabedisconnected":
+			if !client.notifyBlocks {
+				continue
+			}
+		case "txaccepted":
+			if !client.notifyTxs {
+				continue
+			}
+		}
+		s.sendLocked(client, msg)
+	}
+}
+
+// DropWebsockets closes the websocket connections, as when the node restarts.
+func (s *Server) DropWebsockets() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for client := range s.wsClients {
+		delete(s.wsClients, client)
+		client.close()
+	}
+}
+
+// WebsocketSubscribers returns the number of websocket connections subscribed to blocks and
+// to transactions.
+func (s *Server) WebsocketSubscribers() (blocks int, txs int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for client := range s.wsClients {
+		if client.notifyBlocks {
+			blocks++
+		}
+		if client.notifyTxs {
+			txs++
+		}
+	}
+	return blocks, txs
+}
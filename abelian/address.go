@@ -73,6 +73,19 @@ func NewAbelAddress(data []byte) (*AbelAddress, error) {
 	return &abelAddress, nil
 }
 
+// NewAbelAddressForNetwork decodes an address like NewAbelAddress, and returns *NetworkMismatchError
+// if it is not on the network netID.
+func NewAbelAddressForNetwork(data []byte, netID NetworkID) (*AbelAddress, error) {
+	abelAddress, err := NewAbelAddress(data)
+	if err != nil {
+		return nil, err
+	}
+	if abelAddress.netID != netID {
+		return nil, &NetworkMismatchError{Expected: netID, Actual: abelAddress.netID}
+	}
+	return abelAddress, nil
+}
+
 func NewAbelAddressFromCryptoAddress(netID NetworkID, cryptoAddress *crypto.CryptoAddress) *AbelAddress {
 	instanceAddress := abelAddr.NewInstanceAddress(byte(netID), cryptoAddress.Data())
 	serializedInstanceAddress := instanceAddress.Serialize()
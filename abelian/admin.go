@@ -0,0 +1,143 @@
+package abelian
+
+import (
+	"context"
+	"time"
+)
+
+// NodeAdmin calls the networking and administration RPCs of abec. Most of them are reserved to the
+// admin user of abec (rpcuser), so NodeAdmin is created from its own config, keeping the admin
+// credentials apart from the ones of the chain API of Client, which may be the limited user (rpclimituser).
+type NodeAdmin struct {
+	client *Client
+}
+
+func NewNodeAdmin(config *ClientConfig) (*NodeAdmin, error) {
+	client, err := NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeAdmin{client: client}, nil
+}
+
+// Close releases the resources of the underlying client.
+func (admin *NodeAdmin) Close() {
+	admin.client.Close()
+}
+
+type PeerInfo struct {
+	ID             int32   `json:"id"`
+	Addr           string  `json:"addr"`
+	AddrLocal      string  `json:"addrlocal"`
+	Services       string  `json:"services"`
+	RelayTxes      bool    `json:"relaytxes"`
+	LastSend       int64   `json:"lastsend"`
+	LastRecv       int64   `json:"lastrecv"`
+	BytesSent      uint64  `json:"bytessent"`
+	BytesRecv      uint64  `json:"bytesrecv"`
+	ConnTime       int64   `json:"conntime"`
+	TimeOffset     int64   `json:"timeoffset"`
+	PingTime       float64 `json:"pingtime"` // in microseconds
+	PingWait       float64 `json:"pingwait"` // in microseconds
+	Version        uint32  `json:"version"`
+	SubVer         string  `json:"subver"`
+	Inbound        bool    `json:"inbound"`
+	StartingHeight int64   `json:"startingheight"`
+	CurrentHeight  int64   `json:"currentheight"`
+	BanScore       int32   `json:"banscore"`
+	FeeFilter      int64   `json:"feefilter"`
+	SyncNode       bool    `json:"syncnode"`
+}
+
+type NetTotals struct {
+	TotalBytesRecv uint64 `json:"totalbytesrecv"`
+	TotalBytesSent uint64 `json:"totalbytessent"`
+	TimeMillis     int64  `json:"timemillis"`
+}
+
+// NetworkInfo is the networking part of getinfo, as abec has no getnetworkinfo.
+type NetworkInfo struct {
+	Version         int64  `json:"version"`
+	ProtocolVersion int64  `json:"protocolversion"`
+	TimeOffset      int64  `json:"timeoffset"`
+	Connections     int64  `json:"connections"`
+	Proxy           string `json:"proxy"`
+	RelayFee        Amount `json:"relayfee"`
+	NodeType        string `json:"nodetype"`
+	Errors          string `json:"errors"`
+}
+
+// AddNodeCommand is the action of addnode.
+type AddNodeCommand string
+
+const (
+	AddNodeAdd    AddNodeCommand = "add"    // add the peer as persistent peer
+	AddNodeRemove AddNodeCommand = "remove" // remove the persistent peer
+	AddNodeOneTry AddNodeCommand = "onetry" // connect to the peer once, without making it persistent
+)
+
+func (admin *NodeAdmin) GetPeerInfo() (res []*PeerInfo, err error) {
+	return admin.GetPeerInfoCtx(context.Background())
+}
+func (admin *NodeAdmin) GetPeerInfoCtx(ctx context.Context) (res []*PeerInfo, err error) {
+	err = admin.client.DoCtx(ctx, "getpeerinfo", nil, &res)
+	return res, err
+}
+
+func (admin *NodeAdmin) GetNetworkInfo() (res *NetworkInfo, err error) {
+	return admin.GetNetworkInfoCtx(context.Background())
+}
+func (admin *NodeAdmin) GetNetworkInfoCtx(ctx context.Context) (res *NetworkInfo, err error) {
+	err = admin.client.DoCtx(ctx, "getinfo", nil, &res)
+	return res, err
+}
+
+func (admin *NodeAdmin) GetConnectionCount() (res int64, err error) {
+	return admin.GetConnectionCountCtx(context.Background())
+}
+func (admin *NodeAdmin) GetConnectionCountCtx(ctx context.Context) (res int64, err error) {
+	err = admin.client.DoCtx(ctx, "getconnectioncount", nil, &res)
+	return res, err
+}
+
+func (admin *NodeAdmin) GetNetTotals() (res *NetTotals, err error) {
+	return admin.GetNetTotalsCtx(context.Background())
+}
+func (admin *NodeAdmin) GetNetTotalsCtx(ctx context.Context) (res *NetTotals, err error) {
+	err = admin.client.DoCtx(ctx, "getnettotals", nil, &res)
+	return res, err
+}
+
+// AddNode adds or removes a peer of the node, addr being host:port.
+func (admin *NodeAdmin) AddNode(addr string, command AddNodeCommand) (err error) {
+	return admin.AddNodeCtx(context.Background(), addr, command)
+}
+func (admin *NodeAdmin) AddNodeCtx(ctx context.Context, addr string, command AddNodeCommand) (err error) {
+	return admin.client.DoCtx(ctx, "addnode", []interface{}{addr, command}, nil)
+}
+
+// Ping queues a ping to all peers, whose round trip times show up later in GetPeerInfo.
+func (admin *NodeAdmin) Ping() (err error) {
+	return admin.PingCtx(context.Background())
+}
+func (admin *NodeAdmin) PingCtx(ctx context.Context) (err error) {
+	return admin.client.DoCtx(ctx, "ping", nil, nil)
+}
+
+func (admin *NodeAdmin) Uptime() (res time.Duration, err error) {
+	return admin.UptimeCtx(context.Background())
+}
+func (admin *NodeAdmin) UptimeCtx(ctx context.Context) (res time.Duration, err error) {
+	var seconds int64
+	err = admin.client.DoCtx(ctx, "uptime", nil, &seconds)
+	return time.Duration(seconds) * time.Second, err
+}
+
+// Stop shuts the node down.
+func (admin *NodeAdmin) Stop() (res string, err error) {
+	return admin.StopCtx(context.Background())
+}
+func (admin *NodeAdmin) StopCtx(ctx context.Context) (res string, err error) {
+	err = admin.client.DoCtx(ctx, "stop", nil, &res)
+	return res, err
+}
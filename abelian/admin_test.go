@@ -0,0 +1,127 @@
+package abelian_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+func newAdminServer(t *testing.T) *abeliantest.Server {
+	t.Helper()
+	server := abeliantest.NewServer(abeliantest.WithAuth("admin", "adminpass"), abeliantest.WithLimitedAuth("user", "userpass"))
+	server.SetPeers([]*abelian.PeerInfo{
+		{ID: 1, Addr: "10.0.0.1:8666", BytesRecv: 100, BytesSent: 10, SyncNode: true},
+		{ID: 2, Addr: "10.0.0.2:8666", BytesRecv: 200, BytesSent: 20, Inbound: true},
+	})
+	return server
+}
+
+func TestNodeAdmin(t *testing.T) {
+	server := newAdminServer(t)
+	defer server.Close()
+	admin, err := abelian.NewNodeAdmin(server.ClientConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+
+	peers, err := admin.GetPeerInfo()
+	if err != nil || !reflect.DeepEqual(peers, server.Peers()) {
+		t.Errorf("GetPeerInfo = %v, %v, want %v", peers, err, server.Peers())
+	}
+	if count, err := admin.GetConnectionCount(); err != nil || count != 2 {
+		t.Errorf("GetConnectionCount = %d, %v, want 2", count, err)
+	}
+	totals, err := admin.GetNetTotals()
+	if err != nil || totals.TotalBytesRecv != 300 || totals.TotalBytesSent != 30 || totals.TimeMillis == 0 {
+		t.Errorf("GetNetTotals = %+v, %v, want 300 bytes received and 30 sent", totals, err)
+	}
+	info, err := admin.GetNetworkInfo()
+	if err != nil || info.Version != 1000000 || info.ProtocolVersion != 70002 {
+		t.Errorf("GetNetworkInfo = %+v, %v, want version 1000000 and protocol 70002", info, err)
+	}
+	if err := admin.Ping(); err != nil {
+		t.Errorf("Ping = %v", err)
+	}
+	if uptime, err := admin.Uptime(); err != nil || uptime < 0 {
+		t.Errorf("Uptime = %v, %v", uptime, err)
+	}
+	if res, err := admin.Stop(); err != nil || res != "abec stopping." {
+		t.Errorf("Stop = %q, %v", res, err)
+	}
+}
+
+func TestNodeAdminAddNode(t *testing.T) {
+	server := newAdminServer(t)
+	defer server.Close()
+	admin, err := abelian.NewNodeAdmin(server.ClientConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+
+	steps := []struct {
+		addr      string
+		command   abelian.AddNodeCommand
+		wantErr   bool
+		wantPeers int
+	}{
+		{"10.0.0.3:8666", abelian.AddNodeAdd, false, 3},
+		{"10.0.0.3:8666", abelian.AddNodeAdd, true, 3},
+		{"10.0.0.4:8666", abelian.AddNodeOneTry, false, 4},
+		{"10.0.0.1:8666", abelian.AddNodeRemove, false, 3},
+		{"10.0.0.1:8666", abelian.AddNodeRemove, true, 3},
+		{"10.0.0.5:8666", "connect", true, 3},
+	}
+	for _, step := range steps {
+		err := admin.AddNode(step.addr, step.command)
+		if (err != nil) != step.wantErr {
+			t.Errorf("AddNode(%s, %s) = %v, want error %v", step.addr, step.command, err, step.wantErr)
+		}
+		if count, err := admin.GetConnectionCount(); err != nil || count != int64(step.wantPeers) {
+			t.Errorf("GetConnectionCount after AddNode(%s, %s) = %d, %v, want %d", step.addr, step.command, count, err, step.wantPeers)
+		}
+	}
+}
+
+func TestNodeAdminUnauthorized(t *testing.T) {
+	server := newAdminServer(t)
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		username   string
+		password   string
+		wantUptime bool // uptime is allowed to the limited user of abec
+	}{
+		{"limited user", "user", "userpass", true},
+		{"wrong password", "admin", "wrong", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			admin, err := abelian.NewNodeAdmin(abelian.NewClientConfig(server.URL, abelian.WithAuth(tt.username, tt.password)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer admin.Close()
+			if _, err := admin.GetPeerInfo(); !errors.Is(err, abelian.ErrUnauthorized) {
+				t.Errorf("GetPeerInfo = %v, want ErrUnauthorized", err)
+			}
+			if err := admin.AddNode("10.0.0.3:8666", abelian.AddNodeAdd); !errors.Is(err, abelian.ErrUnauthorized) {
+				t.Errorf("AddNode = %v, want ErrUnauthorized", err)
+			}
+			if _, err := admin.Stop(); !errors.Is(err, abelian.ErrUnauthorized) {
+				t.Errorf("Stop = %v, want ErrUnauthorized", err)
+			}
+			if _, err := admin.Uptime(); (err == nil) != tt.wantUptime {
+				t.Errorf("Uptime = %v, want success %v", err, tt.wantUptime)
+			}
+			if peers := server.Peers(); len(peers) != 2 {
+				t.Errorf("%d peers, want 2", len(peers))
+			}
+		})
+	}
+}
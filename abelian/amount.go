@@ -0,0 +1,167 @@
+package abelian
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	NEUTRINO_PER_ABEL   = 10_000_000
+	ABEL_DECIMAL_DIGITS = 7
+	MAX_AMOUNT          = Amount(1<<51 - 1) // neutrino, the largest amount accepted by abec
+)
+
+// Amount is an amount of ABEL counted in neutrino, the smallest unit.
+// It is formatted, parsed and marshaled to JSON in ABEL, as the node does.
+type Amount int64
+
+// ParseAmount parses a decimal string of ABEL such as "12.3456789" exactly.
+func ParseAmount(s string) (Amount, error) {
+	str := strings.TrimSpace(s)
+	negative := strings.HasPrefix(str, "-")
+	if negative || strings.HasPrefix(str, "+") {
+		str = str[1:]
+	}
+
+	integral, fractional, _ := strings.Cut(str, ".")
+	if integral == "" && fractional == "" || !isDigits(integral) || !isDigits(fractional) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if len(fractional) > ABEL_DECIMAL_DIGITS {
+		if !isZeros(fractional[ABEL_DECIMAL_DIGITS:]) {
+			return 0, fmt.Errorf("%w: %q has more than %d decimal digits", ErrInvalidAmount, s, ABEL_DECIMAL_DIGITS)
+		}
+		fractional = fractional[:ABEL_DECIMAL_DIGITS]
+	}
+	fractional += strings.Repeat("0", ABEL_DECIMAL_DIGITS-len(fractional))
+
+	neutrino, err := strconv.ParseInt(integral+fractional, 10, 64)
+	if err != nil || neutrino > int64(MAX_AMOUNT) {
+		return 0, fmt.Errorf("%w: %q", ErrAmountOverflow, s)
+	}
+	if negative {
+		neutrino = -neutrino
+	}
+	return Amount(neutrino), nil
+}
+
+// NewAmountFromAbel converts an amount of ABEL as a float, rounding to the nearest neutrino.
+func NewAmountFromAbel(abelAmount float64) (Amount, error) {
+	if math.IsNaN(abelAmount) || math.IsInf(abelAmount, 0) {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidAmount, abelAmount)
+	}
+	neutrino := math.Round(abelAmount * NEUTRINO_PER_ABEL)
+	if math.Abs(neutrino) > float64(MAX_AMOUNT) {
+		return 0, fmt.Errorf("%w: %v", ErrAmountOverflow, abelAmount)
+	}
+	return Amount(neutrino), nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeros(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+// Neutrino returns the amount in neutrino.
+func (amount Amount) Neutrino() int64 {
+	return int64(amount)
+}
+
+// ToAbel returns the amount in ABEL as a float, which may not be exact.
+func (amount Amount) ToAbel() float64 {
+	return float64(amount) / NEUTRINO_PER_ABEL
+}
+
+// String formats the amount in ABEL without trailing zeros, e.g. "12.3456789" or "20".
+func (amount Amount) String() string {
+	neutrino := uint64(amount)
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		neutrino = -neutrino
+	}
+	integral := neutrino / NEUTRINO_PER_ABEL
+	fractional := neutrino % NEUTRINO_PER_ABEL
+	if fractional == 0 {
+		return fmt.Sprintf("%s%d", sign, integral)
+	}
+	digits := strings.TrimRight(fmt.Sprintf("%07d", fractional), "0")
+	return fmt.Sprintf("%s%d.%s", sign, integral, digits)
+}
+
+// Add returns amount + other, or ErrAmountOverflow if the sum is beyond MAX_AMOUNT.
+func (amount Amount) Add(other Amount) (Amount, error) {
+	sum := amount + other
+	if (other > 0 && sum < amount) || (other < 0 && sum > amount) || sum > MAX_AMOUNT || sum < -MAX_AMOUNT {
+		return 0, fmt.Errorf("%w: %v + %v", ErrAmountOverflow, amount, other)
+	}
+	return sum, nil
+}
+
+// Sub returns amount - other, or ErrAmountOverflow if the difference is beyond MAX_AMOUNT.
+func (amount Amount) Sub(other Amount) (Amount, error) {
+	if other == math.MinInt64 {
+		return 0, fmt.Errorf("%w: %v - %v", ErrAmountOverflow, amount, other)
+	}
+	return amount.Add(-other)
+}
+
+// SumAmounts adds up amounts, or returns ErrAmountOverflow.
+func SumAmounts(amounts ...Amount) (Amount, error) {
+	sum := Amount(0)
+	var err error
+	for _, amount := range amounts {
+		sum, err = sum.Add(amount)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return sum, nil
+}
+
+// MarshalJSON writes the amount as a JSON number of ABEL with its exact decimal digits.
+func (amount Amount) MarshalJSON() ([]byte, error) {
+	return []byte(amount.String()), nil
+}
+
+// UnmarshalJSON reads a JSON number or string of ABEL. Numbers in exponent form, which the node
+// writes for small amounts such as 1e-06, are rounded to the nearest neutrino.
+func (amount *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	if bytes.ContainsAny(data, "eE") {
+		abelAmount, err := strconv.ParseFloat(string(data), 64)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidAmount, data)
+		}
+		*amount, err = NewAmountFromAbel(abelAmount)
+		return err
+	}
+	parsed, err := ParseAmount(string(data))
+	if err != nil {
+		return err
+	}
+	*amount = parsed
+	return nil
+}
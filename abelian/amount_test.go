@@ -0,0 +1,185 @@
+package abelian_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    abelian.Amount
+		wantErr error
+	}{
+		{"0.3", 3_000_000, nil},
+		{"12.3456789", 123_456_789, nil},
+		{"20", 200_000_000, nil},
+		{".5", 5_000_000, nil},
+		{"7.", 70_000_000, nil},
+		{" 1.25 ", 12_500_000, nil},
+		{"+1", 10_000_000, nil},
+		{"-0.0000001", -1, nil},
+		{"-12.5", -125_000_000, nil},
+		{"1.00000010", 10_000_001, nil},
+		{"0.00000001", 0, abelian.ErrInvalidAmount},
+		{"1.23456789", 0, abelian.ErrInvalidAmount},
+		{"1e-6", 0, abelian.ErrInvalidAmount},
+		{"1E3", 0, abelian.ErrInvalidAmount},
+		{"", 0, abelian.ErrInvalidAmount},
+		{".", 0, abelian.ErrInvalidAmount},
+		{"-", 0, abelian.ErrInvalidAmount},
+		{"1,5", 0, abelian.ErrInvalidAmount},
+		{"--1", 0, abelian.ErrInvalidAmount},
+		{"225179981.3685247", abelian.MAX_AMOUNT, nil},
+		{"-225179981.3685247", -abelian.MAX_AMOUNT, nil},
+		{"225179981.3685248", 0, abelian.ErrAmountOverflow},
+		{"99999999999999999999", 0, abelian.ErrAmountOverflow},
+	}
+	for _, tt := range tests {
+		amount, err := abelian.ParseAmount(tt.s)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseAmount(%q) = %v, %v, want %v", tt.s, amount, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || amount != tt.want {
+			t.Errorf("ParseAmount(%q) = %d, %v, want %d", tt.s, amount, err, tt.want)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	tests := []struct {
+		amount abelian.Amount
+		want   string
+	}{
+		{0, "0"},
+		{1, "0.0000001"},
+		{3_000_000, "0.3"},
+		{123_456_789, "12.3456789"},
+		{200_000_000, "20"},
+		{-125_000_000, "-12.5"},
+		{abelian.MAX_AMOUNT, "225179981.3685247"},
+		{-abelian.MAX_AMOUNT, "-225179981.3685247"},
+	}
+	for _, tt := range tests {
+		if s := tt.amount.String(); s != tt.want {
+			t.Errorf("Amount(%d).String() = %q, want %q", tt.amount, s, tt.want)
+		}
+		// the string parses back to the same amount
+		if amount, err := abelian.ParseAmount(tt.want); err != nil || amount != tt.amount {
+			t.Errorf("ParseAmount(%q) = %d, %v, want %d", tt.want, amount, err, tt.amount)
+		}
+	}
+}
+
+func TestNewAmountFromAbel(t *testing.T) {
+	tests := []struct {
+		abel    float64
+		want    abelian.Amount
+		wantErr error
+	}{
+		{0.3, 3_000_000, nil},
+		{1e-06, 10, nil},
+		{12.3456789, 123_456_789, nil},
+		{-0.1, -1_000_000, nil},
+		{3e8, 0, abelian.ErrAmountOverflow},
+	}
+	for _, tt := range tests {
+		amount, err := abelian.NewAmountFromAbel(tt.abel)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewAmountFromAbel(%v) = %v, %v, want %v", tt.abel, amount, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || amount != tt.want {
+			t.Errorf("NewAmountFromAbel(%v) = %d, %v, want %d", tt.abel, amount, err, tt.want)
+		}
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	tests := []struct {
+		json    string
+		want    abelian.Amount
+		wantErr error
+	}{
+		{`0.3`, 3_000_000, nil},
+		{`1e-06`, 10, nil},
+		{`1E-7`, 1, nil},
+		{`0.0001`, 1_000, nil},
+		{`12.3456789`, 123_456_789, nil},
+		{`"12.3456789"`, 123_456_789, nil},
+		{`-0.5`, -5_000_000, nil},
+		{`null`, 0, nil},
+		{`0.00000001`, 0, abelian.ErrInvalidAmount},
+		{`"abc"`, 0, abelian.ErrInvalidAmount},
+		{`1e9`, 0, abelian.ErrAmountOverflow},
+	}
+	for _, tt := range tests {
+		// as the fee of a transaction returned by the node
+		var tx abelian.MempoolTx
+		err := json.Unmarshal([]byte(`{"fee": `+tt.json+`}`), &tx)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("unmarshal fee %s = %v, %v, want %v", tt.json, tx.Fee, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || tx.Fee != tt.want {
+			t.Errorf("unmarshal fee %s = %d, %v, want %d", tt.json, tx.Fee, err, tt.want)
+		}
+	}
+
+	for _, amount := range []abelian.Amount{0, 1, 3_000_000, -125_000_000, abelian.MAX_AMOUNT} {
+		data, err := json.Marshal(amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded abelian.Amount
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded != amount {
+			t.Errorf("%s unmarshals to %d, %v, want %d", data, decoded, err, amount)
+		}
+	}
+}
+
+func TestAmountArithmetic(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      func() (abelian.Amount, error)
+		want    abelian.Amount
+		wantErr bool
+	}{
+		{"add", func() (abelian.Amount, error) { return abelian.Amount(3).Add(4) }, 7, false},
+		{"add up to the max", func() (abelian.Amount, error) { return (abelian.MAX_AMOUNT - 1).Add(1) }, abelian.MAX_AMOUNT, false},
+		{"add beyond the max", func() (abelian.Amount, error) { return abelian.MAX_AMOUNT.Add(1) }, 0, true},
+		{"add beyond the min", func() (abelian.Amount, error) { return (-abelian.MAX_AMOUNT).Add(-1) }, 0, true},
+		{"add overflowing int64", func() (abelian.Amount, error) { return abelian.Amount(1 << 62).Add(1 << 62) }, 0, true},
+		{"sub", func() (abelian.Amount, error) { return abelian.Amount(3).Sub(4) }, -1, false},
+		{"sub down to the min", func() (abelian.Amount, error) { return abelian.Amount(0).Sub(abelian.MAX_AMOUNT) }, -abelian.MAX_AMOUNT, false},
+		{"sub beyond the max", func() (abelian.Amount, error) { return abelian.MAX_AMOUNT.Sub(-1) }, 0, true},
+		{"sub beyond the min", func() (abelian.Amount, error) { return (-abelian.MAX_AMOUNT).Sub(1) }, 0, true},
+		{"sub of the min int64", func() (abelian.Amount, error) { return abelian.Amount(0).Sub(-1 << 63) }, 0, true},
+		{"sum", func() (abelian.Amount, error) { return abelian.SumAmounts(1, 2, 3) }, 6, false},
+		{"sum of nothing", func() (abelian.Amount, error) { return abelian.SumAmounts() }, 0, false},
+		{"sum up to the max", func() (abelian.Amount, error) { return abelian.SumAmounts(abelian.MAX_AMOUNT-2, 1, 1) }, abelian.MAX_AMOUNT, false},
+		{"sum beyond the max", func() (abelian.Amount, error) { return abelian.SumAmounts(abelian.MAX_AMOUNT, 1, -1) }, 0, true},
+	}
+	for _, tt := range tests {
+		amount, err := tt.op()
+		if tt.wantErr {
+			if !errors.Is(err, abelian.ErrAmountOverflow) {
+				t.Errorf("%s = %d, %v, want ErrAmountOverflow", tt.name, amount, err)
+			}
+			continue
+		}
+		if err != nil || amount != tt.want {
+			t.Errorf("%s = %d, %v, want %d", tt.name, amount, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package abelian
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// BATCH_METHOD is the method with which a batch request goes through the middlewares. Its params are
+// the *JSONRPCRequest of the calls, and its result is the JSON array of their responses.
+const BATCH_METHOD = "batch"
+
+// Batch collects several RPC calls which are sent to abec as one JSON-RPC array in a single HTTP request.
+//
+//	results, err := client.Batch().GetRawTx(a).GetRawTx(b).Send(ctx)
+//
+// If the node does not accept batch requests, the calls are sent one by one instead.
+type Batch struct {
+	client *Client
+	calls  []*batchCall
+}
+
+type batchCall struct {
+	method string
+	params []interface{}
+	decode func(raw json.RawMessage) (any, error)
+}
+
+// BatchResult is the outcome of one call of a batch.
+// Result has the same type as the corresponding Client method returns, and
+// Err is the RPCError (or decoding error) of this call only.
+type BatchResult struct {
+	Method string
+	Result any
+	Err    error
+}
+
+func (client *Client) Batch() *Batch {
+	return &Batch{client: client}
+}
+
+func (batch *Batch) Len() int {
+	return len(batch.calls)
+}
+
+// Call appends an arbitrary call whose result is returned as json.RawMessage.
+func (batch *Batch) Call(method string, params []interface{}) *Batch {
+	return batch.add(method, params, func(raw json.RawMessage) (any, error) {
+		return raw, nil
+	})
+}
+
+func (batch *Batch) GetChainInfo() *Batch {
+	return batch.add("getinfo", nil, func(raw json.RawMessage) (any, error) {
+		var res *ChainInfo
+		err := json.Unmarshal(raw, &res)
+		return res, err
+	})
+}
+
+func (batch *Batch) GetRawMempool() *Batch {
+	return batch.add("getrawmempool", []interface{}{false}, func(raw json.RawMessage) (any, error) {
+		var res []string
+		err := json.Unmarshal(raw, &res)
+		return res, err
+	})
+}
+
+func (batch *Batch) GetBlockHash(height int64) *Batch {
+	return batch.add("getblockhash", []interface{}{height}, decodeString)
+}
+
+func (batch *Batch) GetBlock(blockID string) *Batch {
+	return batch.add("getblockabe", []interface{}{blockID, 1}, func(raw json.RawMessage) (any, error) {
+		var res *Block
+		err := json.Unmarshal(raw, &res)
+		return res, err
+	})
+}
+
+func (batch *Batch) GetBlockBytes(blockID string) *Batch {
+	return batch.add("getblockabe", []interface{}{blockID, 0}, decodeHexString)
+}
+
+func (batch *Batch) GetTxBytes(txID string) *Batch {
+	return batch.add("getrawtransaction", []interface{}{txID, false}, decodeHexString)
+}
+
+func (batch *Batch) GetRawTx(txID string) *Batch {
+	return batch.add("getrawtransaction", []interface{}{txID, true}, func(raw json.RawMessage) (any, error) {
+		var res *Tx
+		err := json.Unmarshal(raw, &res)
+		return res, err
+	})
+}
+
+func (batch *Batch) add(method string, params []interface{}, decode func(raw json.RawMessage) (any, error)) *Batch {
+	batch.calls = append(batch.calls, &batchCall{
+		method: method,
+		params: params,
+		decode: decode,
+	})
+	return batch
+}
+
+func decodeString(raw json.RawMessage) (any, error) {
+	var res string
+	err := json.Unmarshal(raw, &res)
+	return res, err
+}
+
+func decodeHexString(raw json.RawMessage) (any, error) {
+	var resHex string
+	err := json.Unmarshal(raw, &resHex)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(resHex)
+}
+
+// Send issues all calls of the batch and returns their results in the order they were added.
+// The returned error is only set if the batch as a whole failed, e.g. the HTTP request could not be done.
+// Like single calls, batches are limited, go through the middlewares and are retried by the RetryPolicy:
+// a failed request is sent again if all its calls are retryable, and so are the calls which failed
+// with a retryable error.
+func (batch *Batch) Send(ctx context.Context) ([]*BatchResult, error) {
+	if len(batch.calls) == 0 {
+		return nil, nil
+	}
+	if batch.client.pool != nil {
+		var results []*BatchResult
+		methods := make([]string, len(batch.calls))
+		for i, call := range batch.calls {
+			methods[i] = call.method
+		}
+		err := batch.client.doPool(ctx, methods, func(ep *endpoint) error {
+			var err error
+			results, err = (&Batch{client: ep.client, calls: batch.calls}).Send(ctx)
+			return err
+		})
+		return results, err
+	}
+	if batch.client.batchDisabled.Load() {
+		return batch.sendOneByOne(ctx)
+	}
+
+	policy := batch.client.retryPolicy
+	results := make([]*BatchResult, len(batch.calls))
+	pending := make([]int, len(batch.calls))
+	for i := range pending {
+		pending[i] = i
+	}
+	for attempt := 1; ; attempt++ {
+		err := batch.sendOnce(ctx, pending, results)
+		var unsupportedErr *batchUnsupportedError
+		if errors.As(err, &unsupportedErr) {
+			sdkLog.Warnf("node does not support batch request (%v), fall back to single requests", unsupportedErr.err)
+			batch.client.batchDisabled.Store(true)
+			return batch.sendOneByOne(ctx)
+		}
+
+		// retry is empty without a policy
+		retry := batch.retryable(pending, results, err)
+		if len(retry) > 0 && attempt < policy.MaxAttempts && ctx.Err() == nil {
+			retryErr := err
+			if retryErr == nil {
+				retryErr = results[retry[0]].Err
+			}
+			delay := policy.backoff(attempt)
+			sdkLog.Warnf("batch request of %d calls failed (attempt %d/%d): %v, retry %d calls in %v", len(pending), attempt, policy.MaxAttempts, retryErr, len(retry), delay)
+			if policy.sleep(ctx, delay) == nil {
+				pending = retry
+				continue
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+}
+
+// retryable returns the calls to send again after sending the calls at pending: all of them if
+// the request failed with err and they are all retryable, and the ones which failed with a
+// retryable error otherwise.
+func (batch *Batch) retryable(pending []int, results []*BatchResult, err error) []int {
+	policy := batch.client.retryPolicy
+	if policy == nil {
+		return nil
+	}
+	if err != nil {
+		for _, i := range pending {
+			if !policy.isRetryable(batch.calls[i].method, err) {
+				return nil
+			}
+		}
+		return pending
+	}
+	var retry []int
+	for _, i := range pending {
+		if results[i].Err != nil && policy.isRetryable(batch.calls[i].method, results[i].Err) {
+			retry = append(retry, i)
+		}
+	}
+	return retry
+}
+
+// sendOnce sends the calls at indices in one request through the middlewares, and sets their results.
+func (batch *Batch) sendOnce(ctx context.Context, indices []int, results []*BatchResult) error {
+	methods := make([]string, len(indices))
+	deadlineMethod := ""
+	for i, index := range indices {
+		methods[i] = batch.calls[index].method
+		// a batch with a broadcast gets the longer deadline of the broadcasts
+		if MethodClassOf(methods[i]) == MethodClassBroadcast {
+			deadlineMethod = methods[i]
+		}
+	}
+	if batch.client.limiter != nil {
+		release, err := batch.client.limiter.acquireBatch(ctx, methods)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	ctx, cancel := batch.client.withDefaultDeadline(ctx, deadlineMethod)
+	defer cancel()
+
+	jsonReqs := make([]interface{}, len(indices))
+	id2Index := make(map[string]int, len(indices))
+	for i, index := range indices {
+		call := batch.calls[index]
+		jsonReq := &JSONRPCRequest{
+			JSONRPC: "1.0",
+			Method:  call.method,
+			Params:  call.params,
+			ID:      batch.client.nextID(),
+		}
+		jsonReqs[i] = jsonReq
+		id2Index[jsonReq.ID] = index
+	}
+	raw, err := batch.client.invoke(ctx, BATCH_METHOD, jsonReqs)
+	if err != nil {
+		return err
+	}
+
+	var respObjs []*JSONRPCResponse
+	err = json.Unmarshal(raw, &respObjs)
+	if err != nil {
+		sdkLog.Errorf("fail to unmarshal json batch response: %v", err)
+		return err
+	}
+	for _, index := range indices {
+		results[index] = nil
+	}
+	for _, respObj := range respObjs {
+		index, ok := id2Index[respObj.ID]
+		if !ok || results[index] != nil {
+			sdkLog.Warnf("unexpected response with id %q in batch response", respObj.ID)
+			continue
+		}
+		results[index] = batch.calls[index].result(respObj)
+	}
+	for id, index := range id2Index {
+		if results[index] == nil {
+			results[index] = &BatchResult{
+				Method: batch.calls[index].method,
+				Err:    fmt.Errorf("no response for request %s in batch response", id),
+			}
+		}
+	}
+	return nil
+}
+
+func (batch *Batch) sendOneByOne(ctx context.Context) ([]*BatchResult, error) {
+	results := make([]*BatchResult, len(batch.calls))
+	for i, call := range batch.calls {
+		var raw json.RawMessage
+		err := batch.client.DoCtx(ctx, call.method, call.params, &raw)
+		if err != nil {
+			var rpcErr *RPCError
+			if !errors.As(err, &rpcErr) {
+				return nil, err
+			}
+			results[i] = &BatchResult{Method: call.method, Err: err}
+			continue
+		}
+		results[i] = call.result(&JSONRPCResponse{Result: raw})
+	}
+	return results, nil
+}
+
+func (call *batchCall) result(respObj *JSONRPCResponse) *BatchResult {
+	res := &BatchResult{Method: call.method}
+	if respObj.Error != nil {
+		res.Err = respObj.Error
+		return res
+	}
+	res.Result, res.Err = call.decode(respObj.Result)
+	return res
+}
+
+// batchUnsupportedError is the error object abec answers a batch request with if it does not accept them.
+type batchUnsupportedError struct {
+	err *RPCError
+}
+
+func (e *batchUnsupportedError) Error() string {
+	return fmt.Sprintf("batch request not supported: %v", e.err)
+}
+
+func (e *batchUnsupportedError) Unwrap() error {
+	return e.err
+}
+
+// roundTripBatch is the innermost Invoker of a batch request, params being its *JSONRPCRequest.
+func (client *Client) roundTripBatch(ctx context.Context, params []interface{}) (json.RawMessage, error) {
+	jsonBody, err := json.Marshal(params)
+	if err != nil {
+		sdkLog.Errorf("fail to marshal json batch request: %v", err)
+		return nil, err
+	}
+	body, err := client.transport.RoundTrip(ctx, jsonBody)
+	if err != nil {
+		return nil, err
+	}
+	var respObjs []json.RawMessage
+	if json.Unmarshal(body, &respObjs) != nil {
+		// abec answers a request it can not parse with a single error object
+		respObj := &JSONRPCResponse{}
+		if json.Unmarshal(body, respObj) == nil && respObj.Error != nil {
+			return nil, &batchUnsupportedError{err: respObj.Error}
+		}
+	}
+	return body, nil
+}
@@ -0,0 +1,259 @@
+package abelian_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+// methodRecorder is a middleware recording the methods it sees.
+type methodRecorder struct {
+	mtx     sync.Mutex
+	methods []string
+}
+
+func (recorder *methodRecorder) middleware(next abelian.Invoker) abelian.Invoker {
+	return func(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+		recorder.mtx.Lock()
+		recorder.methods = append(recorder.methods, method)
+		recorder.mtx.Unlock()
+		return next(ctx, method, params)
+	}
+}
+
+func (recorder *methodRecorder) seen() []string {
+	recorder.mtx.Lock()
+	defer recorder.mtx.Unlock()
+	return append([]string{}, recorder.methods...)
+}
+
+func TestBatchSend(t *testing.T) {
+	for _, batch := range []bool{true, false} {
+		name := "batch"
+		options := []abeliantest.Option{}
+		if batch {
+			options = append(options, abeliantest.WithBatch())
+		} else {
+			name = "fallback to single requests"
+		}
+		t.Run(name, func(t *testing.T) {
+			server := abeliantest.NewServer(options...)
+			defer server.Close()
+			server.AddBlock()
+			client, err := server.NewClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			for i := 0; i < 2; i++ {
+				results, err := client.Batch().GetBlockHash(1).GetBlockHash(5).GetRawMempool().Send(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(results) != 3 {
+					t.Fatalf("%d results, want 3", len(results))
+				}
+				if results[0].Err != nil || results[0].Result != server.BlockByHeight(1).BlockHash {
+					t.Errorf("results[0] = %v, %v", results[0].Result, results[0].Err)
+				}
+				var rpcErr *abelian.RPCError
+				if !errors.As(results[1].Err, &rpcErr) {
+					t.Errorf("results[1].Err = %v, want *RPCError", results[1].Err)
+				}
+				if results[2].Err != nil || results[2].Method != "getrawmempool" {
+					t.Errorf("results[2] = %+v", results[2])
+				}
+			}
+		})
+	}
+}
+
+func TestBatchWrappedRPCError(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	server.AddBlock()
+	// a middleware wrapping the errors of the calls sent one by one
+	client, err := server.NewClient(abelian.WithMiddleware(func(next abelian.Invoker) abelian.Invoker {
+		return func(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+			res, err := next(ctx, method, params)
+			if err != nil && method == "getblockhash" {
+				err = fmt.Errorf("getblockhash: %w", err)
+			}
+			return res, err
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	results, err := client.Batch().GetBlockHash(5).GetBlockHash(1).Send(context.Background())
+	if err != nil {
+		t.Fatalf("Send = %v, want the wrapped RPC error in the result of the call", err)
+	}
+	var rpcErr *abelian.RPCError
+	if !errors.As(results[0].Err, &rpcErr) {
+		t.Errorf("results[0].Err = %v, want *RPCError", results[0].Err)
+	}
+	if results[1].Err != nil || results[1].Result != server.BlockByHeight(1).BlockHash {
+		t.Errorf("results[1] = %v, %v", results[1].Result, results[1].Err)
+	}
+}
+
+func TestBatchDeadline(t *testing.T) {
+	tests := []struct {
+		name    string
+		methods []string
+		wantErr error
+	}{
+		{"reads", []string{"getblockcount", "getbestblockhash"}, context.DeadlineExceeded},
+		{"with a broadcast", []string{"getblockcount", "sendrawtransactionabe"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := abeliantest.NewServer(abeliantest.WithBatch())
+			defer server.Close()
+			client, err := server.NewClient(abelian.WithTimeoutDuration(50*time.Millisecond),
+				abelian.WithBroadcastTimeoutDuration(5*time.Second), abelian.WithVersionCheck(abelian.VersionCheckNone))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+			server.SetLatency("getblockcount", 200*time.Millisecond)
+
+			txHex, _ := testTx(t, 1)
+			batch := client.Batch()
+			for _, method := range tt.methods {
+				var params []interface{}
+				if method == "sendrawtransactionabe" {
+					params = []interface{}{txHex}
+				}
+				batch.Call(method, params)
+			}
+			_, err = batch.Send(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Send of a batch answered in 200ms = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBatchMiddlewareAndMetrics(t *testing.T) {
+	server := abeliantest.NewServer(abeliantest.WithBatch())
+	defer server.Close()
+	server.AddBlock()
+	metrics := abelian.NewMetrics()
+	recorder := &methodRecorder{}
+	// no version check, whose getinfo would be measured too
+	client, err := server.NewClient(abelian.WithMetrics(metrics), abelian.WithMiddleware(recorder.middleware),
+		abelian.WithVersionCheck(abelian.VersionCheckNone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	_, err = client.Batch().GetBlockHash(1).GetBlockHash(5).GetChainInfo().Send(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen := recorder.seen(); !reflect.DeepEqual(seen, []string{abelian.BATCH_METHOD}) {
+		t.Errorf("middleware saw %v, want one batch", seen)
+	}
+	snapshot := metrics.Snapshot()
+	if m := snapshot["getblockhash"]; m == nil || m.Requests != 2 || m.Errors[abelian.ErrorClassRPC] != 1 || m.InFlight != 0 {
+		t.Errorf("getblockhash metrics = %+v, want 2 requests and 1 rpc error", m)
+	}
+	if m := snapshot["getinfo"]; m == nil || m.Requests != 1 || len(m.Errors) != 0 {
+		t.Errorf("getinfo metrics = %+v, want 1 request", m)
+	}
+	if _, ok := snapshot[abelian.BATCH_METHOD]; ok {
+		t.Errorf("batch request measured as a call")
+	}
+}
+
+func TestBatchRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		fault     *abeliantest.Fault
+		wantErr   bool
+		wantCalls map[string]int
+	}{
+		{
+			name:      "failed request",
+			method:    "getblockhash",
+			fault:     &abeliantest.Fault{HTTPStatus: http.StatusServiceUnavailable},
+			wantCalls: map[string]int{"getblockhash": 2, "getrawmempool": 1},
+		},
+		{
+			name:      "failed call",
+			method:    "getrawmempool",
+			fault:     &abeliantest.Fault{RPCError: &abelian.RPCError{Code: abelian.RPC_ERR_IN_WARMUP, Message: "warmup"}},
+			wantCalls: map[string]int{"getblockhash": 1, "getrawmempool": 2},
+		},
+		{
+			name:      "permanent error",
+			method:    "getrawmempool",
+			fault:     &abeliantest.Fault{RPCError: &abelian.RPCError{Code: abelian.RPC_ERR_MISC, Message: "misc"}},
+			wantErr:   true,
+			wantCalls: map[string]int{"getblockhash": 1, "getrawmempool": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := abeliantest.NewServer(abeliantest.WithBatch())
+			defer server.Close()
+			server.AddBlock()
+			client, err := server.NewClient(abelian.WithRetryPolicy(testRetryPolicy()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			server.InjectFault(tt.method, tt.fault, 1)
+			results, err := client.Batch().GetBlockHash(1).GetRawMempool().Send(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if results[0].Err != nil || (results[1].Err != nil) != tt.wantErr {
+				t.Errorf("results = %+v, %+v, want error of the second call %v", results[0], results[1], tt.wantErr)
+			}
+			for method, want := range tt.wantCalls {
+				if calls := server.CallCount(method); calls != want {
+					t.Errorf("%s calls = %d, want %d", method, calls, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchNotRetriedWithBroadcast(t *testing.T) {
+	server := abeliantest.NewServer(abeliantest.WithBatch())
+	defer server.Close()
+	client, err := server.NewClient(abelian.WithRetryPolicy(testRetryPolicy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	txHex, _ := testTx(t, 1)
+	server.InjectFault("getblockcount", &abeliantest.Fault{HTTPStatus: http.StatusServiceUnavailable}, 1)
+	_, err = client.Batch().Call("getblockcount", nil).Call("sendrawtransactionabe", []interface{}{txHex}).Send(context.Background())
+	var httpErr *abelian.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Send error = %v, want *HTTPError", err)
+	}
+	if calls := server.CallCount("getblockcount"); calls != 1 {
+		t.Errorf("getblockcount calls = %d, want 1", calls)
+	}
+}
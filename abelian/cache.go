@@ -0,0 +1,312 @@
+package abelian
+
+import (
+	"container/list"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	DEFAULT_CACHE_MAX_SIZE           = 64 << 20 // bytes
+	DEFAULT_CACHE_CONFIRMATION_DEPTH = 100      // blocks
+)
+
+// CacheConfig configures the cache of immutable responses, i.e. blocks and transactions fetched by hash.
+type CacheConfig struct {
+	MaxSize           uint64 // bytes of responses kept in memory, default DEFAULT_CACHE_MAX_SIZE
+	Dir               string // directory of the on-disk store, empty to keep the cache in memory only
+	ConfirmationDepth int64  // confirmations a block needs before its height-to-hash lookup is cached, 0 to never cache it;
+	// a reorganization deeper than it leaves a stale lookup in the cache
+}
+
+func NewCacheConfig(options ...CacheOption) *CacheConfig {
+	config := &CacheConfig{
+		MaxSize:           DEFAULT_CACHE_MAX_SIZE,
+		ConfirmationDepth: DEFAULT_CACHE_CONFIRMATION_DEPTH,
+	}
+
+	for _, opt := range options {
+		opt(config)
+	}
+
+	return config
+}
+
+// CacheOption change cache config
+type CacheOption func(*CacheConfig)
+
+// WithCacheMaxSize sets the number of bytes of responses kept in memory.
+func WithCacheMaxSize(maxSize uint64) CacheOption {
+	return func(config *CacheConfig) {
+		config.MaxSize = maxSize
+	}
+}
+
+// WithCacheDir enables the on-disk store in dir. The store is not bounded in size.
+func WithCacheDir(dir string) CacheOption {
+	return func(config *CacheConfig) {
+		config.Dir = dir
+	}
+}
+
+// WithCacheConfirmationDepth sets the confirmations a block needs before its height-to-hash lookup is cached,
+// 0 to never cache it.
+func WithCacheConfirmationDepth(depth int64) CacheOption {
+	return func(config *CacheConfig) {
+		config.ConfirmationDepth = depth
+	}
+}
+
+// CacheStats are the counters of a response cache.
+type CacheStats struct {
+	Hits      uint64 // lookups served from memory or disk
+	DiskHits  uint64 // lookups served from disk, included in Hits
+	Misses    uint64 // lookups sent to the node
+	Evictions uint64 // entries dropped from memory to stay within MaxSize
+	Entries   int    // entries in memory
+	Size      uint64 // bytes of the entries in memory
+	MaxSize   uint64
+}
+
+type cacheEntry struct {
+	key   string
+	value json.RawMessage
+}
+
+// responseCache is a LRU cache of raw JSON results, backed by an optional directory.
+type responseCache struct {
+	maxSize uint64
+	dir     string
+	depth   int64
+
+	mtx     sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	size    uint64
+	tip     int64 // highest tip seen in responses, -1 if unknown
+	stats   CacheStats
+}
+
+func newResponseCache(config *CacheConfig) (*responseCache, error) {
+	if config.ConfirmationDepth < 0 {
+		return nil, fmt.Errorf("cache confirmation depth %d is negative", config.ConfirmationDepth)
+	}
+	if config.Dir != "" {
+		err := os.MkdirAll(config.Dir, 0o755)
+		if err != nil {
+			return nil, fmt.Errorf("fail to create cache directory: %v", err)
+		}
+	}
+	return &responseCache{
+		maxSize: config.MaxSize,
+		dir:     config.Dir,
+		depth:   config.ConfirmationDepth,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+		tip:     -1,
+	}, nil
+}
+
+func (cache *responseCache) get(key string) (json.RawMessage, bool) {
+	cache.mtx.Lock()
+	if elem, ok := cache.entries[key]; ok {
+		cache.lru.MoveToFront(elem)
+		cache.stats.Hits++
+		cache.mtx.Unlock()
+		return elem.Value.(*cacheEntry).value, true
+	}
+	cache.mtx.Unlock()
+
+	if cache.dir != "" {
+		value, err := os.ReadFile(cache.path(key))
+		if err == nil {
+			cache.mtx.Lock()
+			cache.stats.Hits++
+			cache.stats.DiskHits++
+			cache.add(key, value)
+			cache.mtx.Unlock()
+			return value, true
+		}
+	}
+
+	cache.mtx.Lock()
+	cache.stats.Misses++
+	cache.mtx.Unlock()
+	return nil, false
+}
+
+func (cache *responseCache) contains(key string) bool {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+	_, ok := cache.entries[key]
+	return ok
+}
+
+func (cache *responseCache) put(key string, value json.RawMessage) {
+	cache.mtx.Lock()
+	cache.add(key, value)
+	cache.mtx.Unlock()
+
+	if cache.dir != "" {
+		err := writeFileAtomic(cache.path(key), value)
+		if err != nil {
+			sdkLog.Warnf("fail to write cache entry %s: %v", key, err)
+		}
+	}
+}
+
+// add inserts the entry into memory, evicting the least recently used ones. mtx must be held.
+func (cache *responseCache) add(key string, value json.RawMessage) {
+	if uint64(len(value)) > cache.maxSize {
+		return
+	}
+	if elem, ok := cache.entries[key]; ok {
+		cache.lru.MoveToFront(elem)
+		return
+	}
+	cache.entries[key] = cache.lru.PushFront(&cacheEntry{key: key, value: value})
+	cache.size += uint64(len(value))
+	for cache.size > cache.maxSize {
+		elem := cache.lru.Back()
+		entry := elem.Value.(*cacheEntry)
+		cache.lru.Remove(elem)
+		delete(cache.entries, entry.key)
+		cache.size -= uint64(len(entry.value))
+		cache.stats.Evictions++
+	}
+}
+
+func (cache *responseCache) path(key string) string {
+	return filepath.Join(cache.dir, strings.ReplaceAll(key, ":", "-"))
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	return os.Rename(tmpFile.Name(), path)
+}
+
+// observeTip records a tip height seen in a response.
+func (cache *responseCache) observeTip(height int64) {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+	if height > cache.tip {
+		cache.tip = height
+	}
+}
+
+// isDeep reports whether a block with the specified confirmations is buried deep enough
+// for its height-to-hash lookup to be cached.
+func (cache *responseCache) isDeep(confirmations int64) bool {
+	return cache.depth > 0 && confirmations >= cache.depth
+}
+
+// isDeepHeight reports whether the block at height is deep enough below the highest tip seen.
+func (cache *responseCache) isDeepHeight(height int64) bool {
+	cache.mtx.Lock()
+	tip := cache.tip
+	cache.mtx.Unlock()
+	return tip >= 0 && cache.isDeep(tip-height+1)
+}
+
+func (cache *responseCache) snapshot() *CacheStats {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+	stats := cache.stats
+	stats.Entries = cache.lru.Len()
+	stats.Size = cache.size
+	stats.MaxSize = cache.maxSize
+	return &stats
+}
+
+// CacheStats returns the counters of the response cache, or nil if the client has no cache.
+func (client *Client) CacheStats() *CacheStats {
+	if client.cache == nil {
+		return nil
+	}
+	return client.cache.snapshot()
+}
+
+// doCached serves the call from the cache under key if possible. Otherwise the call is sent
+// to the node and its result is cached if cacheable (nil for always) accepts it. An empty key
+// is never cached.
+func (client *Client) doCached(ctx context.Context, key string, method string, params []interface{}, result any, cacheable func(raw json.RawMessage) bool) error {
+	if client.cache == nil || key == "" {
+		return client.DoCtx(ctx, method, params, result)
+	}
+	raw, ok := client.cache.get(key)
+	if !ok {
+		err := client.DoCtx(ctx, method, params, &raw)
+		if err != nil {
+			return err
+		}
+		if cacheable == nil || cacheable(raw) {
+			client.cache.put(key, raw)
+		}
+	}
+	err := json.Unmarshal(raw, result)
+	if err != nil {
+		sdkLog.Errorf("fail to unmarshal json result: %v", err)
+		return err
+	}
+	return nil
+}
+
+// observeBlock records the tip implied by block, and caches its height-to-hash lookup if it is deep enough.
+func (cache *responseCache) observeBlock(block *Block) {
+	if block == nil || block.BlockHash == "" {
+		return
+	}
+	cache.observeTip(block.Height + block.Confirmations - 1)
+	if cache.isDeep(block.Confirmations) && !cache.contains(blockHashCacheKey(block.Height)) {
+		value, _ := json.Marshal(block.BlockHash)
+		cache.put(blockHashCacheKey(block.Height), value)
+	}
+}
+
+func blockHashCacheKey(height int64) string {
+	return fmt.Sprintf("blockhash:%d", height)
+}
+
+// hashCacheKey returns the key of a response looked up by hash, or "" if hash is not a block or
+// transaction hash, as the key is also a file name in the on-disk store.
+func hashCacheKey(kind string, hash string) string {
+	if !isHash(hash) {
+		return ""
+	}
+	return kind + ":" + hash
+}
+
+// isHash reports whether s is a hash in hex, as the node prints block and transaction hashes.
+func isHash(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// isDeepTx reports whether the raw transaction result is confirmed deep enough to be cached,
+// as the block of a less confirmed transaction may still change.
+func (cache *responseCache) isDeepTx(raw json.RawMessage) bool {
+	var tx struct {
+		Confirmations int64 `json:"confirmations"`
+	}
+	return json.Unmarshal(raw, &tx) == nil && cache.isDeep(tx.Confirmations)
+}
@@ -0,0 +1,165 @@
+package abelian_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+func newCachedClient(t *testing.T, server *abeliantest.Server, options ...abelian.CacheOption) *abelian.Client {
+	t.Helper()
+	client, err := server.NewClient(abelian.WithCache(abelian.NewCacheConfig(options...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestCacheByHash(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	tx := &abelian.Tx{Hex: "00ff"}
+	block := server.AddBlock(tx)
+	client := newCachedClient(t, server)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetBlock(block.BlockHash); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.GetTxBytes(tx.TxID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := server.CallCount("getblockabe"); calls != 1 {
+		t.Errorf("getblockabe calls = %d, want 1", calls)
+	}
+	if calls := server.CallCount("getrawtransaction"); calls != 1 {
+		t.Errorf("getrawtransaction calls = %d, want 1", calls)
+	}
+	stats := client.CacheStats()
+	if stats.Hits != 2 || stats.Misses != 2 {
+		t.Errorf("stats = %+v, want 2 hits and 2 misses", stats)
+	}
+}
+
+func TestCacheRejectsInvalidHash(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	server.AddBlock()
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "cache")
+	client := newCachedClient(t, server, abelian.WithCacheDir(dir))
+
+	tests := []string{
+		"../outside",
+		"../../" + server.BlockByHeight(1).BlockHash,
+		"zz" + server.BlockByHeight(1).BlockHash[2:],
+		server.BlockByHeight(1).BlockHash + "00",
+	}
+	for _, blockID := range tests {
+		for i := 0; i < 2; i++ {
+			if _, err := client.GetBlock(blockID); err == nil {
+				t.Errorf("GetBlock(%q) succeeded", blockID)
+			}
+		}
+	}
+	if calls := server.CallCount("getblockabe"); calls != 2*len(tests) {
+		t.Errorf("getblockabe calls = %d, want %d", calls, 2*len(tests))
+	}
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("files next to the cache directory = %d, want none", len(entries)-1)
+	}
+	if stats := client.CacheStats(); stats.Entries != 0 {
+		t.Errorf("entries = %d, want 0", stats.Entries)
+	}
+}
+
+func TestCacheBlockHash(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	for i := 0; i < abelian.DEFAULT_CACHE_CONFIRMATION_DEPTH+10; i++ {
+		server.AddBlock()
+	}
+	client := newCachedClient(t, server)
+	if _, err := client.GetBlockCount(); err != nil {
+		t.Fatal(err)
+	}
+
+	tip := server.Height()
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetBlockHash(1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.GetBlockHash(tip - 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// only the lookup of the block below the confirmation depth is cached
+	if calls := server.CallCount("getblockhash"); calls != 3 {
+		t.Errorf("getblockhash calls = %d, want 3", calls)
+	}
+
+	// a reorganization within the confirmation depth is seen by the next lookup
+	server.Reorg(20)
+	for i := 0; i < 20; i++ {
+		server.AddBlock()
+	}
+	blockHash, err := client.GetBlockHash(tip - 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := server.BlockByHeight(tip - 10).BlockHash; blockHash != want {
+		t.Errorf("GetBlockHash after reorganization = %s, want %s", blockHash, want)
+	}
+}
+
+func TestCacheConfirmationDepth(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	for i := 0; i < 20; i++ {
+		server.AddBlock()
+	}
+
+	tests := []struct {
+		depth     int64
+		wantErr   bool
+		wantCalls int // getblockhash calls for two lookups of a block with 11 confirmations
+	}{
+		{-1, true, 0},
+		{0, false, 2},
+		{6, false, 1},
+		{11, false, 1},
+		{12, false, 2},
+		{abelian.DEFAULT_CACHE_CONFIRMATION_DEPTH, false, 2},
+	}
+	for _, tt := range tests {
+		client, err := server.NewClient(abelian.WithCache(abelian.NewCacheConfig(abelian.WithCacheConfirmationDepth(tt.depth))))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("depth %d: error = %v, want error %v", tt.depth, err, tt.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		calls := server.CallCount("getblockhash")
+		if _, err := client.GetBlockCount(); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := client.GetBlockHash(server.Height() - 10); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if calls := server.CallCount("getblockhash") - calls; calls != tt.wantCalls {
+			t.Errorf("depth %d: getblockhash calls = %d, want %d", tt.depth, calls, tt.wantCalls)
+		}
+		client.Close()
+	}
+}
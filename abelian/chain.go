@@ -1,7 +1,9 @@
 package abelian
 
 import (
-	"fmt"
+	"context"
+	"math"
+
 	api "github.com/pqabelian/abec/sdkapi/v2"
 )
 
@@ -36,54 +38,75 @@ var NetName2NetID = map[string]NetworkID{
 	"simnet":        SimNet,
 }
 
+// The helpers below are those of mainnet. Their variants taking params, or the methods of the
+// ChainParams of the network, e.g. returned by Client.ChainParams, serve the other networks.
+
+// GetTxoRingSizeByBlockHeight returns the ring size at height on mainnet.
 func GetTxoRingSizeByBlockHeight(height int64) uint8 {
-	return api.GetTxoRingSizeByBlockHeight(int32(height))
+	return GetTxoRingSizeByBlockHeightWithParams(MainNetParams, height)
 }
 
+// GetTxoRingSizeByBlockHeightWithParams returns the ring size at height on the network of params.
+func GetTxoRingSizeByBlockHeightWithParams(params *ChainParams, height int64) uint8 {
+	return params.TxoRingSizeByBlockHeight(height)
+}
+
+// GetBlockNumPerRingGroupByBlockHeight returns the ring group size at height on mainnet.
 func GetBlockNumPerRingGroupByBlockHeight(height int64) uint8 {
-	return api.GetBlockNumPerRingGroupByBlockHeight(int32(height))
+	return GetBlockNumPerRingGroupByBlockHeightWithParams(MainNetParams, height)
+}
+
+// GetBlockNumPerRingGroupByBlockHeightWithParams returns the ring group size at height on the network of params.
+func GetBlockNumPerRingGroupByBlockHeightWithParams(params *ChainParams, height int64) uint8 {
+	return params.BlockNumPerRingGroupByBlockHeight(height)
 }
 
+// GetCoinbaseMaturity returns the coinbase maturity of mainnet.
 func GetCoinbaseMaturity() int64 {
-	return 200
+	return GetCoinbaseMaturityWithParams(MainNetParams)
+}
+
+// GetCoinbaseMaturityWithParams returns the coinbase maturity of the network of params.
+func GetCoinbaseMaturityWithParams(params *ChainParams) int64 {
+	return params.CoinbaseMaturity
 }
 
+// GetRingBlockHeights returns the heights of the ring group of height on mainnet.
 func GetRingBlockHeights(height int64) []int64 {
-	blockNumPerGroup := GetBlockNumPerRingGroupByBlockHeight(height)
-	firstRingBlockHeight := height - height%int64(blockNumPerGroup)
+	return GetRingBlockHeightsWithParams(MainNetParams, height)
+}
 
-	ringBlockHeights := make([]int64, 0, blockNumPerGroup)
-	for i := int64(0); i < int64(blockNumPerGroup); i++ {
-		ringBlockHeights = append(ringBlockHeights, firstRingBlockHeight+i)
-	}
-	return ringBlockHeights
+// GetRingBlockHeightsWithParams returns the heights of the ring group of height on the network of params.
+func GetRingBlockHeightsWithParams(params *ChainParams, height int64) []int64 {
+	return params.RingBlockHeights(height)
 }
 
 func GetRingBlockGroupByHeight(client *Client, height int64) ([][]byte, error) {
-	blockNumPerGroup := GetBlockNumPerRingGroupByBlockHeight(height)
-	firstRingBlockHeight := height - height%int64(blockNumPerGroup)
-
-	serializedBlockGroups := make([][]byte, 0, blockNumPerGroup)
-	for i := int64(0); i < int64(blockNumPerGroup); i++ {
-		blockBytes, err := client.GetBlockBytesByHeight(firstRingBlockHeight + i)
-		if err != nil {
-			return nil, fmt.Errorf("fail to get block group: %v", err)
-		}
-		serializedBlockGroups = append(serializedBlockGroups, blockBytes)
-	}
-	return serializedBlockGroups, nil
+	return GetRingBlockGroupByHeightCtx(context.Background(), client, height)
 }
 
-func EstimateTxFee(txinDescs interface{}, txOutDescs []*TxOutDesc) int64 {
-	return 1_000_000
+// GetRingBlockGroupByHeightCtx fetches the blocks of the ring group of height in parallel.
+// Use a RingGroupProvider to reuse the groups.
+func GetRingBlockGroupByHeightCtx(ctx context.Context, client *Client, height int64) ([][]byte, error) {
+	params, err := client.ChainParamsCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	group, err := fetchRingGroup(ctx, client, params, height, DEFAULT_RING_GROUP_CONCURRENCY)
+	if err != nil {
+		return nil, err
+	}
+	return group.Blocks, nil
 }
 
+// Deprecated: use Amount.ToAbel, or Amount.String for an exact decimal.
 func NeutrinoToAbel(neutrinoAmount int64) float64 {
-	return float64(neutrinoAmount) / 1e7
+	return Amount(neutrinoAmount).ToAbel()
 }
 
+// Deprecated: use ParseAmount, or NewAmountFromAbel which rounds to the nearest neutrino as this does.
 func AbelToNeutrino(abelAmount float64) int64 {
-	return int64(abelAmount * 1e7)
+	return int64(math.Round(abelAmount * NEUTRINO_PER_ABEL))
 }
 
 // CoinIDRing is the porting of wire.OutPointRing to avoid using specific concepts, but generalize them.
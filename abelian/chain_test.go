@@ -0,0 +1,121 @@
+package abelian_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	api "github.com/pqabelian/abec/sdkapi/v2"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+// testNetworkID is the network of testChainParams, which no node runs.
+const testNetworkID abelian.NetworkID = 42
+
+// testChainParams returns parameters differing from those of mainnet, registered for testNetworkID.
+func testChainParams(t *testing.T) *abelian.ChainParams {
+	t.Helper()
+	params := &abelian.ChainParams{
+		NetworkID:        testNetworkID,
+		Name:             "testchain",
+		CoinbaseMaturity: 10,
+		RingSchedule: []*abelian.RingParams{
+			{Height: 0, RingVersion: 1, TxoRingSize: 3, BlockNumPerRingGroup: 2},
+			{Height: 50, RingVersion: 2, TxoRingSize: 5, BlockNumPerRingGroup: 4},
+		},
+	}
+	if err := abelian.RegisterChainParams(params); err != nil {
+		t.Fatal(err)
+	}
+	return params
+}
+
+func TestChainHelpersWithParams(t *testing.T) {
+	params := testChainParams(t)
+	tests := []struct {
+		params         *abelian.ChainParams
+		height         int64
+		wantRingSize   uint8
+		wantGroupSize  uint8
+		wantMaturity   int64
+		wantRingHeight []int64
+	}{
+		{abelian.MainNetParams, 0, 7, 3, 200, []int64{0, 1, 2}},
+		{abelian.MainNetParams, 10, 7, 3, 200, []int64{9, 10, 11}},
+		{abelian.MainNetParams, 299999, 7, 3, 200, []int64{299997, 299998, 299999}},
+		{abelian.MainNetParams, 300000, 7, 3, 200, []int64{300000, 300001, 300002}},
+		{abelian.MainNetParams, 300001, 7, 3, 200, []int64{300000, 300001, 300002}},
+		{params, 3, 3, 2, 10, []int64{2, 3}},
+		{params, 51, 5, 4, 10, []int64{48, 49, 50, 51}},
+	}
+	for _, tt := range tests {
+		if size := abelian.GetTxoRingSizeByBlockHeightWithParams(tt.params, tt.height); size != tt.wantRingSize {
+			t.Errorf("ring size at %d on %s = %d, want %d", tt.height, tt.params.Name, size, tt.wantRingSize)
+		}
+		if size := abelian.GetBlockNumPerRingGroupByBlockHeightWithParams(tt.params, tt.height); size != tt.wantGroupSize {
+			t.Errorf("ring group size at %d on %s = %d, want %d", tt.height, tt.params.Name, size, tt.wantGroupSize)
+		}
+		if maturity := abelian.GetCoinbaseMaturityWithParams(tt.params); maturity != tt.wantMaturity {
+			t.Errorf("coinbase maturity of %s = %d, want %d", tt.params.Name, maturity, tt.wantMaturity)
+		}
+		if heights := abelian.GetRingBlockHeightsWithParams(tt.params, tt.height); !reflect.DeepEqual(heights, tt.wantRingHeight) {
+			t.Errorf("ring block heights of %d on %s = %v, want %v", tt.height, tt.params.Name, heights, tt.wantRingHeight)
+		}
+	}
+}
+
+func TestMainNetHelpersMatchSDKAPI(t *testing.T) {
+	mlpaut := abelian.MainNetParams.BlockHeightMLPAUT
+	for _, height := range []int64{0, 1, 10, mlpaut - 1, mlpaut, mlpaut + 1, mlpaut + 1000} {
+		if size, want := abelian.GetTxoRingSizeByBlockHeight(height), api.GetTxoRingSizeByBlockHeight(int32(height)); size != want {
+			t.Errorf("ring size at %d = %d, want %d", height, size, want)
+		}
+		if size, want := abelian.GetBlockNumPerRingGroupByBlockHeight(height), api.GetBlockNumPerRingGroupByBlockHeight(int32(height)); size != want {
+			t.Errorf("ring group size at %d = %d, want %d", height, size, want)
+		}
+	}
+}
+
+// tagView is a view account receiving the outputs whose data starts with tag, worth 100 per byte.
+type tagView struct {
+	abelian.ViewAccount
+	tag byte
+}
+
+func (view *tagView) ReceiveCoin(txVersion uint32, txOutData []byte) (bool, uint64, error) {
+	if len(txOutData) > 0 && txOutData[0] == view.tag {
+		return true, uint64(len(txOutData)) * 100, nil
+	}
+	return false, 0, nil
+}
+
+func TestCoinbaseTrackerWithClient(t *testing.T) {
+	params := testChainParams(t)
+	server := abeliantest.NewServer(abeliantest.WithNetID(testNetworkID))
+	defer server.Close()
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	tracker, err := abelian.NewCoinbaseTrackerWithClient(context.Background(), client, &tagView{tag: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.AddBlock(&abelian.Tx{Hex: "cb", Vout: []*abelian.TxVout{{Script: "0701"}, {Script: "0801"}}})
+	outputs, err := tracker.ScanBlock(context.Background(), client, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) != 1 || outputs[0].Coin.Value != 200 || outputs[0].MatureHeight != 1+params.CoinbaseMaturity {
+		t.Fatalf("outputs = %+v, want one output of 200 maturing at %d", outputs, 1+params.CoinbaseMaturity)
+	}
+	if mature := tracker.Mature(params.CoinbaseMaturity - 1); len(mature) != 0 {
+		t.Errorf("%d outputs mature before the coinbase maturity", len(mature))
+	}
+	if mature := tracker.Mature(params.CoinbaseMaturity); len(mature) != 1 {
+		t.Errorf("%d outputs mature at the coinbase maturity, want 1", len(mature))
+	}
+}
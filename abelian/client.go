@@ -1,38 +1,65 @@
 package abelian
 
 import (
-	"bytes"
-	"crypto/tls"
-	"crypto/x509"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
-	DEFAULT_REQUEST_TIMEOUT = 3000
+	DEFAULT_REQUEST_TIMEOUT   = 30 // seconds
+	DEFAULT_BROADCAST_TIMEOUT = 60 // seconds
 )
 
+// DEFAULT_MAX_RESPONSE_SIZE is the default maximum size in bytes of a response, twice the largest blocks in hex.
+const DEFAULT_MAX_RESPONSE_SIZE = 256 << 20
+
 type ClientConfig struct {
-	Endpoint string
+	Endpoint     string
+	NetworkID    *NetworkID   // network the node must be on, checked by NewClient with getinfo and before each broadcast, nil for no check
+	VersionCheck VersionCheck // how to react to a node version not supported by this SDK, default VersionCheckWarn
 
-	EnableTLS bool   // enable tls
-	CaFile    string // cert file for verifying server certificates
+	EnableTLS          bool     // enable tls
+	CaFile             string   // cert file for verifying server certificates, empty for the system roots
+	CertFile           string   // client cert file for mutual tls
+	KeyFile            string   // client key file for mutual tls
+	ServerName         string   // server name for SNI and verification, default the host of Endpoint
+	PinnedSHA256       []string // hex SHA-256 of the server leaf certificate or its public key (SPKI), any of them matches
+	MinTLSVersion      uint16   // minimum tls version, default tls.VersionTLS12
+	InsecureSkipVerify bool     // skip verifying the server certificate chain and name, for development only
 
 	Username string // username for auth
 	Password string // password for auth
 
-	Timeout uint64 // timeout for requesting Abec server, default DEFAULT_TIMEOUT
+	Timeout                  uint64        // timeout in seconds for read requests, default DEFAULT_REQUEST_TIMEOUT
+	BroadcastTimeout         uint64        // timeout in seconds for broadcast requests, default DEFAULT_BROADCAST_TIMEOUT
+	TimeoutDuration          time.Duration // timeout for read requests, overrides Timeout if not 0
+	BroadcastTimeoutDuration time.Duration // timeout for broadcast requests, overrides BroadcastTimeout if not 0
+	MaxResponseSize          int64         // maximum size in bytes of a http response, default DEFAULT_MAX_RESPONSE_SIZE, 0 for no limit
+
+	RetryPolicy *RetryPolicy // policy for retrying failed requests, nil for no retry
+	Cache       *CacheConfig // cache of blocks and transactions fetched by hash, nil for no cache
+
+	Transport    Transport         // transport of the JSON-RPC payloads, default a HTTPTransport built from this config
+	RoundTripper http.RoundTripper // round tripper of the http requests, e.g. a Replayer; the tls settings do not apply to it
+	RecordFile   string            // file to record the calls to, see Recorder
+	Middlewares  []Middleware      // middlewares wrapping the calls, the first one being the outermost
+	Metrics      MetricsRecorder   // recorder of the measurements of the calls, nil for no metrics
+
+	Limits map[MethodClass]*Limit // limits of the calls sent to the node by method class, nil for no limit
 }
 
 func NewClientConfig(endpoint string, options ...ClientOption) *ClientConfig {
 	clientConfig := &ClientConfig{
-		Endpoint: endpoint,
-		Timeout:  DEFAULT_REQUEST_TIMEOUT,
+		Endpoint:         endpoint,
+		Timeout:          DEFAULT_REQUEST_TIMEOUT,
+		BroadcastTimeout: DEFAULT_BROADCAST_TIMEOUT,
+		MaxResponseSize:  DEFAULT_MAX_RESPONSE_SIZE,
 	}
 
 	for _, opt := range options {
@@ -45,13 +72,49 @@ func NewClientConfig(endpoint string, options ...ClientOption) *ClientConfig {
 // ClientOption change client config
 type ClientOption func(*ClientConfig)
 
-// WithTimeout ...
+// WithNetworkID makes NewClient and SendRawTx fail with *NetworkMismatchError if the node is not on the network netID.
+// An endpoint of a multi-endpoint client is checked before its first request, and is unhealthy while it fails.
+func WithNetworkID(netID NetworkID) ClientOption {
+	return func(config *ClientConfig) {
+		config.NetworkID = &netID
+	}
+}
+
+// WithVersionCheck sets how the client reacts to a node version not supported by this SDK.
+func WithVersionCheck(check VersionCheck) ClientOption {
+	return func(config *ClientConfig) {
+		config.VersionCheck = check
+	}
+}
+
+// WithTimeout sets the default deadline in seconds for read requests.
 func WithTimeout(timeout uint64) ClientOption {
 	return func(config *ClientConfig) {
 		config.Timeout = timeout
 	}
 }
 
+// WithBroadcastTimeout sets the default deadline in seconds for broadcast requests.
+func WithBroadcastTimeout(timeout uint64) ClientOption {
+	return func(config *ClientConfig) {
+		config.BroadcastTimeout = timeout
+	}
+}
+
+// WithTimeoutDuration sets the default deadline for read requests, with a precision below the second.
+func WithTimeoutDuration(timeout time.Duration) ClientOption {
+	return func(config *ClientConfig) {
+		config.TimeoutDuration = timeout
+	}
+}
+
+// WithBroadcastTimeoutDuration sets the default deadline for broadcast requests, with a precision below the second.
+func WithBroadcastTimeoutDuration(timeout time.Duration) ClientOption {
+	return func(config *ClientConfig) {
+		config.BroadcastTimeoutDuration = timeout
+	}
+}
+
 // WithAuth ...
 func WithAuth(username string, password string) ClientOption {
 	return func(config *ClientConfig) {
@@ -60,6 +123,76 @@ func WithAuth(username string, password string) ClientOption {
 	}
 }
 
+// WithRetryPolicy enables retrying failed requests according to policy.
+func WithRetryPolicy(policy *RetryPolicy) ClientOption {
+	return func(config *ClientConfig) {
+		config.RetryPolicy = policy
+	}
+}
+
+// WithCache enables caching GetBlock, GetBlockBytes, GetTxBytes and GetRawTx by hash, and GetBlockHash
+// for blocks with at least cache.ConfirmationDepth confirmations. Confirmations and NextBlockHash of a
+// cached result are the ones at the time it was fetched.
+func WithCache(cache *CacheConfig) ClientOption {
+	return func(config *ClientConfig) {
+		config.Cache = cache
+	}
+}
+
+// WithTransport sends the JSON-RPC payloads through transport instead of http. The endpoint, auth,
+// tls, RoundTripper and RecordFile settings do not apply to it.
+func WithTransport(transport Transport) ClientOption {
+	return func(config *ClientConfig) {
+		config.Transport = transport
+	}
+}
+
+// WithMaxResponseSize makes the requests fail with *ResponseTooLargeError if the node answers
+// more than size bytes, 0 for no limit.
+func WithMaxResponseSize(size int64) ClientOption {
+	return func(config *ClientConfig) {
+		config.MaxResponseSize = size
+	}
+}
+
+// WithRoundTripper sends the http requests through roundTripper instead of a transport built from the tls settings.
+func WithRoundTripper(roundTripper http.RoundTripper) ClientOption {
+	return func(config *ClientConfig) {
+		config.RoundTripper = roundTripper
+	}
+}
+
+// WithMetrics measures the calls of the client with recorder, e.g. a Metrics.
+func WithMetrics(recorder MetricsRecorder) ClientOption {
+	return func(config *ClientConfig) {
+		config.Metrics = recorder
+	}
+}
+
+// WithLimit caps the calls of the method class sent to the node, see Limit.
+func WithLimit(class MethodClass, limit *Limit) ClientOption {
+	return func(config *ClientConfig) {
+		if config.Limits == nil {
+			config.Limits = map[MethodClass]*Limit{}
+		}
+		config.Limits[class] = limit
+	}
+}
+
+// WithMiddleware appends middlewares wrapping the calls of the client, see Middleware.
+func WithMiddleware(middlewares ...Middleware) ClientOption {
+	return func(config *ClientConfig) {
+		config.Middlewares = append(config.Middlewares, middlewares...)
+	}
+}
+
+// WithRecorder records the calls of the client to a transcript file at path, see Recorder.
+func WithRecorder(path string) ClientOption {
+	return func(config *ClientConfig) {
+		config.RecordFile = path
+	}
+}
+
 func WithTLS(caFile string) ClientOption {
 	return func(config *ClientConfig) {
 		config.EnableTLS = true
@@ -67,96 +200,302 @@ func WithTLS(caFile string) ClientOption {
 	}
 }
 
+// WithClientCert enables tls and authenticates the client with the certificate and key (mutual tls).
+func WithClientCert(certFile string, keyFile string) ClientOption {
+	return func(config *ClientConfig) {
+		config.EnableTLS = true
+		config.CertFile = certFile
+		config.KeyFile = keyFile
+	}
+}
+
+// WithServerName enables tls and sets the name the server certificate is verified against.
+func WithServerName(serverName string) ClientOption {
+	return func(config *ClientConfig) {
+		config.EnableTLS = true
+		config.ServerName = serverName
+	}
+}
+
+// WithPinnedSHA256 enables tls and accepts only a server whose leaf certificate or public key has one of the hex SHA-256 pins.
+func WithPinnedSHA256(pins ...string) ClientOption {
+	return func(config *ClientConfig) {
+		config.EnableTLS = true
+		config.PinnedSHA256 = append(config.PinnedSHA256, pins...)
+	}
+}
+
+// WithMinTLSVersion enables tls and sets the minimum version, e.g. tls.VersionTLS13.
+func WithMinTLSVersion(version uint16) ClientOption {
+	return func(config *ClientConfig) {
+		config.EnableTLS = true
+		config.MinTLSVersion = version
+	}
+}
+
+// WithInsecureSkipVerify enables tls without verifying the server certificate. Pins are still checked.
+// For development only.
+func WithInsecureSkipVerify() ClientOption {
+	return func(config *ClientConfig) {
+		config.EnableTLS = true
+		config.InsecureSkipVerify = true
+	}
+}
+
+// MethodClass groups RPC methods sharing the same default deadline and limits.
+type MethodClass int
+
+const (
+	MethodClassRead MethodClass = iota
+	MethodClassBroadcast
+	MethodClassHeavyRead // reads returning whole blocks, with the default deadline of reads
+)
+
+func (c MethodClass) String() string {
+	switch c {
+	case MethodClassRead:
+		return "read"
+	case MethodClassBroadcast:
+		return "broadcast"
+	case MethodClassHeavyRead:
+		return "heavy read"
+	default:
+		return "unknown"
+	}
+}
+
+// MethodClassOf returns the class of the specified RPC method.
+func MethodClassOf(method string) MethodClass {
+	switch method {
+	case "sendrawtransaction", "sendrawtransactionabe", "submitblock":
+		return MethodClassBroadcast
+	case "getblock", "getblockabe", "getblocktemplate":
+		return MethodClassHeavyRead
+	default:
+		return MethodClassRead
+	}
+}
+
 type Client struct {
-	*http.Client
 	Endpoint string
 	Username string // username for basic auth
 	Password string // password for basic auth
 
+	readTimeout      time.Duration
+	broadcastTimeout time.Duration
+	retryPolicy      *RetryPolicy
+	cache            *responseCache // nil if caching is disabled
+
+	transport Transport
+	invoke    Invoker  // the transport wrapped by the middlewares
+	limiter   *limiter // nil if the calls are not limited
+
+	pool   *endpointPool   // set for multi-endpoint clients
+	sticky *stickyEndpoint // set for sticky clients of a multi-endpoint client
+
+	requestID     atomic.Uint64
+	batchDisabled atomic.Bool // set once the node rejects batch requests
+
+	versionCheck    VersionCheck
+	expectedNetwork *NetworkID  // nil if the network is not checked
+	nodeChecked     atomic.Bool // set once the node met the expected network and version
+	nodeMtx         sync.Mutex
+	node            *nodeInfo // nil until getinfo is answered
 }
 
 func NewClient(config *ClientConfig) (*Client, error) {
-	httpClient := &http.Client{
-		Timeout: time.Duration(config.Timeout) * time.Second,
+	client, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+	err = client.verifyNode(context.Background())
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// newClient creates a client without checking its node.
+func newClient(config *ClientConfig) (*Client, error) {
+	transport := config.Transport
+	if transport == nil {
+		httpTransport, err := NewHTTPTransport(config)
+		if err != nil {
+			return nil, err
+		}
+		transport = httpTransport
 	}
-	if config.EnableTLS {
-		caCert, err := os.ReadFile(config.CaFile)
+	var cache *responseCache
+	if config.Cache != nil {
+		var err error
+		cache, err = newResponseCache(config.Cache)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read CA certificate: %v", err)
+			transport.Close()
+			return nil, err
 		}
+	}
+	client := &Client{
+		Endpoint:         config.Endpoint,
+		Username:         config.Username,
+		Password:         config.Password,
+		readTimeout:      configTimeout(config.Timeout, config.TimeoutDuration),
+		broadcastTimeout: configTimeout(config.BroadcastTimeout, config.BroadcastTimeoutDuration),
+		retryPolicy:      config.RetryPolicy,
+		cache:            cache,
+		transport:        transport,
+		limiter:          newLimiter(config.Limits),
+		versionCheck:     config.VersionCheck,
+		expectedNetwork:  config.NetworkID,
+	}
+	middlewares := config.Middlewares
+	if config.Metrics != nil {
+		middlewares = append([]Middleware{metricsMiddleware(config.Metrics)}, middlewares...)
+	}
 
-		caCertPool := x509.NewCertPool()
-		if ok := caCertPool.AppendCertsFromPEM(caCert); !ok {
-			return nil, fmt.Errorf("failed to add CA certificate to pool")
-		}
+	client.invoke = chainMiddlewares(client.invokeTransport, middlewares)
+	return client, nil
+}
 
-		httpClient.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs:    caCertPool,
-				ServerName: config.Endpoint,
-			},
-		}
+// configTimeout returns the timeout of seconds, or duration if it is set.
+func configTimeout(seconds uint64, duration time.Duration) time.Duration {
+	if duration != 0 {
+		return duration
 	}
-	return &Client{
-		Client:   httpClient,
-		Endpoint: config.Endpoint,
-		Username: config.Username,
-		Password: config.Password,
-	}, nil
+	return time.Duration(seconds) * time.Second
+}
+
+// withDefaultDeadline bounds ctx by the default deadline of the method class
+// unless the caller has already set a deadline.
+func (client *Client) withDefaultDeadline(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	timeout := client.readTimeout
+	if MethodClassOf(method) == MethodClassBroadcast {
+		timeout = client.broadcastTimeout
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
 }
 
 func (client *Client) Do(method string, params []interface{}, result any) error {
-	jsonReq := &JSONRPCRequest{
-		JSONRPC: "1.0",
-		Method:  method,
-		Params:  params,
-		ID:      strconv.FormatInt(time.Now().UnixMilli(), 10),
+	return client.DoCtx(context.Background(), method, params, result)
+}
+
+// DoCtx is like Do but bounded by ctx, cancelling the in-flight HTTP request
+// when ctx is done. If ctx has no deadline, the default deadline of the method
+// class applies to each attempt.
+func (client *Client) DoCtx(ctx context.Context, method string, params []interface{}, result any) error {
+	if client.pool != nil {
+		return client.doPool(ctx, []string{method}, func(ep *endpoint) error {
+			return ep.client.DoCtx(ctx, method, params, result)
+		})
 	}
-	jsonBody, err := json.Marshal(jsonReq)
-	if err != nil {
-		sdkLog.Errorf("fail to marshal json request: %v", err)
-		return err
+	if client.retryPolicy == nil {
+		return client.doOnce(ctx, method, params, result)
 	}
+	return client.retryPolicy.do(ctx, method, func(ctx context.Context) error {
+		return client.doOnce(ctx, method, params, result)
+	})
+}
 
-	req, err := http.NewRequest(http.MethodPost, client.Endpoint, bytes.NewBuffer(jsonBody))
+func (client *Client) doOnce(ctx context.Context, method string, params []interface{}, result any) error {
+	// the wait for the limits is bounded by ctx only, not by the default deadline
+	if client.limiter != nil {
+		release, err := client.limiter.acquire(ctx, MethodClassOf(method), 1)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	ctx, cancel := client.withDefaultDeadline(ctx, method)
+	defer cancel()
+
+	raw, err := client.invoke(ctx, method, params)
 	if err != nil {
-		sdkLog.Errorf("fail to create request: %v", err)
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.SetBasicAuth(client.Username, client.Password)
 
-	resp, err := client.Client.Do(req)
+	if result == nil {
+		return nil
+	}
+
+	err = json.Unmarshal(raw, &result)
 	if err != nil {
-		sdkLog.Errorf("fail to do request: %v", err)
+		sdkLog.Errorf("fail to unmarshal json result: %v", err)
 		return err
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// invokeTransport is the innermost Invoker, exchanging the call with the node through the transport.
+func (client *Client) invokeTransport(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+	if method == BATCH_METHOD {
+		return client.roundTripBatch(ctx, params)
+	}
+	if stream := hexStreamOf(ctx, method); stream != nil {
+		if streamTransport, ok := client.transport.(StreamTransport); ok {
+			return client.roundTripHexStream(ctx, streamTransport, method, params, stream)
+		}
+	}
+	jsonReq := &JSONRPCRequest{
+		JSONRPC: "1.0",
+		Method:  method,
+		Params:  params,
+		ID:      client.nextID(),
+	}
+	jsonBody, err := json.Marshal(jsonReq)
 	if err != nil {
-		return err
+		sdkLog.Errorf("fail to marshal json request: %v", err)
+		return nil, err
 	}
 
 	respObj := &JSONRPCResponse{}
-	err = json.Unmarshal(body, respObj)
+	if streamTransport, ok := client.transport.(StreamTransport); ok {
+		// decode the response as it is read, without holding the body besides the result
+		var body io.ReadCloser
+		body, err = streamTransport.RoundTripStream(ctx, jsonBody)
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		err = json.NewDecoder(body).Decode(respObj)
+	} else {
+		var body []byte
+		body, err = client.transport.RoundTrip(ctx, jsonBody)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(body, respObj)
+	}
 	if err != nil {
 		sdkLog.Errorf("fail to unmarshal json response: %v", err)
-		return err
+		return nil, err
 	}
 	if respObj.Error != nil {
 		sdkLog.Errorf("request method %s with param %v, response error: %v", method, params, respObj.Error)
-		return respObj.Error
+		return nil, respObj.Error
 	}
+	return respObj.Result, nil
+}
 
-	if result == nil {
-		return nil
+// Close releases the resources of the client, such as idle connections and the health checker of a multi-endpoint client.
+func (client *Client) Close() {
+	if client.pool != nil {
+		if client.sticky == nil {
+			client.pool.close()
+		}
+		return
 	}
-
-	err = json.Unmarshal(respObj.Result, &result)
+	err := client.transport.Close()
 	if err != nil {
-		sdkLog.Errorf("fail to unmarshal json result: %v", err)
-		return err
+		sdkLog.Warnf("fail to close transport: %v", err)
 	}
-	return nil
+}
+
+func (client *Client) nextID() string {
+	return strconv.FormatUint(client.requestID.Add(1), 10)
 }
@@ -27,7 +27,7 @@ type Coin struct {
 	Index        uint8
 	BlockHash    string
 	BlockHeight  int64
-	Value        int64
+	Value        Amount
 	SerialNumber string
 	TxVoutData   []byte
 	RingID       string
@@ -50,7 +50,7 @@ func NewCoin(
 	index uint8,
 	blockHash string,
 	blockHeight int64,
-	value int64,
+	value Amount,
 	serialNumber string,
 	txVoutData []byte,
 ) *Coin {
@@ -0,0 +1,51 @@
+package crypto
+
+import (
+	"bytes"
+
+	"github.com/pqabelian/abec/abecryptox"
+	api "github.com/pqabelian/abec/sdkapi/v2"
+	"github.com/pqabelian/abec/wire"
+)
+
+// TxVersion is the version of the transactions created by this SDK
+var TxVersion = api.TxVersion
+
+// PrecomputeTransferTxContentSize returns the approximate size of a transfer transaction without
+// its witness, for inputs in rings of the given versions and sizes, outputs to the given coin
+// addresses and a memo of memoLen bytes. The fee is counted as 8 bytes.
+func PrecomputeTransferTxContentSize(txVersion uint32, inputRingVersions []uint32, inputRingSizes []uint8,
+	coinAddresses [][]byte, memoLen int) (int, error) {
+	size, err := wire.PrecomputeTrTxConSizeMLP(txVersion, inputRingVersions, inputRingSizes, coinAddresses, uint32(memoLen))
+	if err != nil {
+		log.Errorf("fail to precompute transfer tx content size: %v", err)
+		return 0, err
+	}
+	return int(size), nil
+}
+
+// PrecomputeTransferTxWitnessSize returns the approximate size of the witness of a transfer transaction,
+// with its length prefix,
+// spending inForRing coins in rings of inRingSizes and coins of inForSingleDistinct distinct pseudonym
+// addresses, to outForRing full-privacy outputs. vPublic is the fee plus the pseudonym output values
+// minus the pseudonym input values.
+func PrecomputeTransferTxWitnessSize(txVersion uint32, inForRing uint8, inForSingleDistinct uint8,
+	inRingSizes []uint8, outForRing uint8, vPublic int64) (int, error) {
+	size, err := abecryptox.GetTrTxWitnessSerializeSizeApprox(txVersion, inForRing, inForSingleDistinct, inRingSizes, outForRing, vPublic)
+	if err != nil {
+		log.Errorf("fail to precompute transfer tx witness size: %v", err)
+		return 0, err
+	}
+	return wire.VarIntSerializeSize(uint64(size)) + size, nil
+}
+
+// GetTxSerializeSize returns the size of a serialized transaction without and with its witness.
+func GetTxSerializeSize(serializedTxFull []byte) (contentSize int, fullSize int, err error) {
+	msgTx := &wire.MsgTxAbe{}
+	err = msgTx.DeserializeFull(bytes.NewReader(serializedTxFull))
+	if err != nil {
+		log.Errorf("fail to deserialize transaction: %v", err)
+		return 0, 0, err
+	}
+	return msgTx.SerializeSize(), msgTx.SerializeSizeFull(), nil
+}
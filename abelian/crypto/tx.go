@@ -0,0 +1,20 @@
+package crypto
+
+import (
+	"bytes"
+
+	"github.com/pqabelian/abec/wire"
+)
+
+// GetTxID returns the id of a serialized transaction, with or without its witness.
+func GetTxID(serializedTx []byte) (string, error) {
+	msgTx := &wire.MsgTxAbe{}
+	err := msgTx.DeserializeFull(bytes.NewReader(serializedTx))
+	if err != nil {
+		err = msgTx.Deserialize(bytes.NewReader(serializedTx))
+	}
+	if err != nil {
+		return "", err
+	}
+	return msgTx.TxHash().String(), nil
+}
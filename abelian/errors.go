@@ -0,0 +1,216 @@
+package abelian
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Errors returned by the client, derived from the JSON-RPC error codes and HTTP status of abec.
+// Use errors.Is to test for them, and errors.As with *TxRejectedError for the reason of a rejection.
+var (
+	ErrBlockNotFound          = errors.New("block not found")
+	ErrTxNotFound             = errors.New("transaction not found")
+	ErrTxAlreadyKnown         = errors.New("transaction already known")
+	ErrTxRejected             = errors.New("transaction rejected")
+	ErrDoubleSpend            = errors.New("double spend")
+	ErrInsufficientFee        = errors.New("insufficient fee")
+	ErrUnauthorized           = errors.New("unauthorized")
+	ErrWarmingUp              = errors.New("node is warming up")
+	ErrMethodNotFound         = errors.New("method not found")
+	ErrBlockRejected          = errors.New("block rejected")
+	ErrNetworkMismatch        = errors.New("network mismatch")
+	ErrUnknownNetwork         = errors.New("unknown network")
+	ErrUnsupportedNodeVersion = errors.New("unsupported node version")
+	ErrResponseTooLarge       = errors.New("response too large")
+	ErrReorgTooDeep           = errors.New("reorganization deeper than the finality depth")
+	ErrInvalidAmount          = errors.New("invalid amount")
+	ErrAmountOverflow         = errors.New("amount overflow")
+)
+
+// JSON-RPC error codes of abec.
+const (
+	RPC_ERR_MISC                   = -1
+	RPC_ERR_INVALID_ADDRESS_OR_KEY = -5
+	RPC_ERR_INVALID_PARAMETER      = -8
+	RPC_ERR_DESERIALIZATION        = -22
+	RPC_ERR_TX_ERROR               = -25
+	RPC_ERR_TX_REJECTED            = -26
+	RPC_ERR_TX_ALREADY_IN_CHAIN    = -27
+	RPC_ERR_IN_WARMUP              = -28
+	RPC_ERR_UTXO_RING_NO_INFO      = -103
+	RPC_ERR_METHOD_NOT_FOUND       = -32601
+	RPC_ERR_INVALID_PARAMS         = -32602
+	RPC_ERR_INTERNAL               = -32603
+	RPC_ERR_PARSE                  = -32700
+)
+
+// errMsgTxAlreadyKnown is how the mempool of abec refuses a transaction it already has.
+const errMsgTxAlreadyKnown = "already have transaction"
+
+// BlockRejectedError is a block refused by submitblock. It matches ErrBlockRejected.
+type BlockRejectedError struct {
+	Reason string
+}
+
+func (e *BlockRejectedError) Error() string {
+	return fmt.Sprintf("block rejected: %s", e.Reason)
+}
+
+func (e *BlockRejectedError) Is(target error) bool {
+	return target == ErrBlockRejected
+}
+
+// NetworkMismatchError is a node, address or transaction of another network than the expected one.
+// It matches ErrNetworkMismatch.
+type NetworkMismatchError struct {
+	Expected NetworkID
+	Actual   NetworkID
+}
+
+func (e *NetworkMismatchError) Error() string {
+	return fmt.Sprintf("network mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
+
+func (e *NetworkMismatchError) Is(target error) bool {
+	return target == ErrNetworkMismatch
+}
+
+// ResponseTooLargeError is a response of the node longer than the MaxResponseSize of the client.
+// It matches ErrResponseTooLarge.
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response too large: more than %d bytes", e.Limit)
+}
+
+func (e *ResponseTooLargeError) Is(target error) bool {
+	return target == ErrResponseTooLarge
+}
+
+// UnsupportedNodeVersionError is a node whose version or protocol version is outside of the
+// ranges supported by this SDK. It matches ErrUnsupportedNodeVersion.
+type UnsupportedNodeVersionError struct {
+	Version         NodeVersion
+	ProtocolVersion int64
+}
+
+func (e *UnsupportedNodeVersionError) Error() string {
+	return fmt.Sprintf("unsupported node version %s (protocol %d), %s %s supports abec %s with protocol >= %d",
+		e.Version, e.ProtocolVersion, SDKName, SDKVersion, supportedNodeVersionsString(), MIN_NODE_PROTOCOL_VERSION)
+}
+
+func (e *UnsupportedNodeVersionError) Is(target error) bool {
+	return target == ErrUnsupportedNodeVersion
+}
+
+// TxRejectReason tells why abec refused a transaction.
+type TxRejectReason int
+
+const (
+	TxRejectInvalid TxRejectReason = iota
+	TxRejectDoubleSpend
+	TxRejectInsufficientFee
+	TxRejectOrphan
+)
+
+func (reason TxRejectReason) String() string {
+	switch reason {
+	case TxRejectDoubleSpend:
+		return "double spend"
+	case TxRejectInsufficientFee:
+		return "insufficient fee"
+	case TxRejectOrphan:
+		return "orphan"
+	default:
+		return "invalid"
+	}
+}
+
+// TxRejectedError is a transaction refused by the mempool of abec.
+// It matches ErrTxRejected, and ErrDoubleSpend or ErrInsufficientFee according to its reason.
+type TxRejectedError struct {
+	Code    int
+	Reason  TxRejectReason
+	Message string
+}
+
+func (e *TxRejectedError) Error() string {
+	return fmt.Sprintf("transaction rejected (%s): %s", e.Reason, e.Message)
+}
+
+func (e *TxRejectedError) Is(target error) bool {
+	switch target {
+	case ErrTxRejected:
+		return true
+	case ErrDoubleSpend:
+		return e.Reason == TxRejectDoubleSpend
+	case ErrInsufficientFee:
+		return e.Reason == TxRejectInsufficientFee
+	}
+	return false
+}
+
+// Unwrap returns the error RPCError is classified as, or nil if it matches none of the errors of this package.
+func (e RPCError) Unwrap() error {
+	message := strings.ToLower(e.Message)
+	switch e.Code {
+	case RPC_ERR_INVALID_ADDRESS_OR_KEY:
+		// abec uses the same code for all failed lookups
+		if strings.Contains(message, "transaction") {
+			return ErrTxNotFound
+		}
+		if strings.Contains(message, "block") {
+			return ErrBlockNotFound
+		}
+	case RPC_ERR_MISC:
+		if strings.Contains(message, "block number out of range") {
+			return ErrBlockNotFound
+		}
+	case RPC_ERR_TX_ALREADY_IN_CHAIN:
+		return ErrTxAlreadyKnown
+	case RPC_ERR_TX_ERROR, RPC_ERR_TX_REJECTED:
+		if strings.Contains(message, errMsgTxAlreadyKnown) {
+			return ErrTxAlreadyKnown
+		}
+		return &TxRejectedError{
+			Code:    e.Code,
+			Reason:  txRejectReasonOf(message),
+			Message: strings.TrimPrefix(e.Message, "TX rejected: "),
+		}
+	case RPC_ERR_IN_WARMUP:
+		return ErrWarmingUp
+	case RPC_ERR_METHOD_NOT_FOUND:
+		return ErrMethodNotFound
+	case RPC_ERR_INVALID_PARAMS:
+		// abec refuses the admin methods to its limited user
+		if strings.Contains(message, "not authorized") {
+			return ErrUnauthorized
+		}
+	}
+	return nil
+}
+
+func txRejectReasonOf(message string) TxRejectReason {
+	switch {
+	case strings.Contains(message, "already spent"):
+		return TxRejectDoubleSpend
+	case strings.Contains(message, "fee") || strings.Contains(message, "priority"):
+		return TxRejectInsufficientFee
+	case strings.Contains(message, "orphan transaction"):
+		return TxRejectOrphan
+	default:
+		return TxRejectInvalid
+	}
+}
+
+// Unwrap returns ErrUnauthorized for a 401 status, abec's answer to wrong credentials.
+func (e *HTTPError) Unwrap() error {
+	if e.StatusCode == http.StatusUnauthorized {
+		return ErrUnauthorized
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package abelian_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+)
+
+func TestErrorMapping(t *testing.T) {
+	allErrs := []error{
+		abelian.ErrBlockNotFound, abelian.ErrTxNotFound, abelian.ErrTxAlreadyKnown, abelian.ErrTxRejected,
+		abelian.ErrDoubleSpend, abelian.ErrInsufficientFee, abelian.ErrUnauthorized, abelian.ErrWarmingUp,
+		abelian.ErrMethodNotFound,
+	}
+	rpcError := func(code int, message string) error {
+		return abelian.RPCError{Code: code, Message: message}
+	}
+	tests := []struct {
+		name       string
+		err        error
+		want       []error // the errors err matches, none for an unclassified error
+		wantReason abelian.TxRejectReason
+	}{
+		{"tx lookup", rpcError(abelian.RPC_ERR_INVALID_ADDRESS_OR_KEY, "No information available about transaction"),
+			[]error{abelian.ErrTxNotFound}, 0},
+		{"block lookup", rpcError(abelian.RPC_ERR_INVALID_ADDRESS_OR_KEY, "Block not found"),
+			[]error{abelian.ErrBlockNotFound}, 0},
+		{"other lookup", rpcError(abelian.RPC_ERR_INVALID_ADDRESS_OR_KEY, "Invalid address or key"), nil, 0},
+		{"block height", rpcError(abelian.RPC_ERR_MISC, "Block number out of range"),
+			[]error{abelian.ErrBlockNotFound}, 0},
+		{"other misc", rpcError(abelian.RPC_ERR_MISC, "unexpected"), nil, 0},
+		{"double spend", rpcError(abelian.RPC_ERR_TX_REJECTED, "TX rejected: output 0a:0 already spent by transaction 0b in the memory pool"),
+			[]error{abelian.ErrTxRejected, abelian.ErrDoubleSpend}, abelian.TxRejectDoubleSpend},
+		{"fee", rpcError(abelian.RPC_ERR_TX_REJECTED, "TX rejected: transaction 0a has 10 fees which is under the required amount of 100"),
+			[]error{abelian.ErrTxRejected, abelian.ErrInsufficientFee}, abelian.TxRejectInsufficientFee},
+		{"priority", rpcError(abelian.RPC_ERR_TX_REJECTED, "TX rejected: transaction 0a has insufficient priority"),
+			[]error{abelian.ErrTxRejected, abelian.ErrInsufficientFee}, abelian.TxRejectInsufficientFee},
+		{"orphan", rpcError(abelian.RPC_ERR_TX_REJECTED, "TX rejected: orphan transaction 0a references outputs of unknown or fully-spent transaction 0b"),
+			[]error{abelian.ErrTxRejected}, abelian.TxRejectOrphan},
+		{"invalid tx", rpcError(abelian.RPC_ERR_TX_ERROR, "TX rejected: invalid signature"),
+			[]error{abelian.ErrTxRejected}, abelian.TxRejectInvalid},
+		{"tx in mempool", rpcError(abelian.RPC_ERR_TX_REJECTED, "TX rejected: already have transaction 0a"),
+			[]error{abelian.ErrTxAlreadyKnown}, 0},
+		{"tx in chain", rpcError(abelian.RPC_ERR_TX_ALREADY_IN_CHAIN, "transaction already exists"),
+			[]error{abelian.ErrTxAlreadyKnown}, 0},
+		{"warmup", rpcError(abelian.RPC_ERR_IN_WARMUP, "Loading block index..."),
+			[]error{abelian.ErrWarmingUp}, 0},
+		{"method", rpcError(abelian.RPC_ERR_METHOD_NOT_FOUND, "Method not found"),
+			[]error{abelian.ErrMethodNotFound}, 0},
+		{"limited user", rpcError(abelian.RPC_ERR_INVALID_PARAMS, "limited user not authorized for this method"),
+			[]error{abelian.ErrUnauthorized}, 0},
+		{"invalid params", rpcError(abelian.RPC_ERR_INVALID_PARAMS, "wrong number of params"), nil, 0},
+		{"internal", rpcError(abelian.RPC_ERR_INTERNAL, "Block not found"), nil, 0},
+		{"wrapped", fmt.Errorf("fail to get block: %w", rpcError(abelian.RPC_ERR_INVALID_ADDRESS_OR_KEY, "Block not found")),
+			[]error{abelian.ErrBlockNotFound}, 0},
+		{"http 401", &abelian.HTTPError{StatusCode: http.StatusUnauthorized, Message: "401 Unauthorized"},
+			[]error{abelian.ErrUnauthorized}, 0},
+		{"http 503", &abelian.HTTPError{StatusCode: http.StatusServiceUnavailable, Message: "503 Service Unavailable"}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, target := range allErrs {
+				want := false
+				for _, w := range tt.want {
+					want = want || w == target
+				}
+				if is := errors.Is(tt.err, target); is != want {
+					t.Errorf("errors.Is(%v, %v) = %v, want %v", tt.err, target, is, want)
+				}
+			}
+			var rejected *abelian.TxRejectedError
+			if errors.As(tt.err, &rejected) {
+				if rejected.Reason != tt.wantReason {
+					t.Errorf("reason of %v = %s, want %s", tt.err, rejected.Reason, tt.wantReason)
+				}
+			} else if errors.Is(tt.err, abelian.ErrTxRejected) {
+				t.Errorf("%v is not a *TxRejectedError", tt.err)
+			}
+		})
+	}
+}
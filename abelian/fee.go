@@ -0,0 +1,357 @@
+package abelian
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/crypto"
+)
+
+const (
+	DEFAULT_FEE_RATE       = 10                // neutrino per kB, the default minimum relay fee of abec
+	DEFAULT_FEE_PERCENTILE = 50                // percentile of the mempool fee rates
+	DEFAULT_TX_FEE         = Amount(1_000_000) // the fee of EstimateTxFee when the size can not be estimated
+)
+
+// TxSize is the serialized size of a transaction in bytes.
+type TxSize struct {
+	ContentSize int64 // size without the witness, on which the node charges the fee
+	WitnessSize int64
+}
+
+func (size *TxSize) FullSize() int64 {
+	return size.ContentSize + size.WitnessSize
+}
+
+// FeeEstimate is the fee of a transaction at a fee rate, with its predicted size.
+type FeeEstimate struct {
+	Fee     Amount
+	FeeRate int64 // neutrino per kB of content
+	Size    *TxSize
+}
+
+// TxFeeForSize returns the fee the node requires for a transaction of contentSize bytes
+// without witness at feeRate neutrino per kB.
+func TxFeeForSize(contentSize int64, feeRate int64) Amount {
+	fee := Amount(contentSize * feeRate / 1000)
+	if fee == 0 && feeRate > 0 {
+		fee = Amount(feeRate)
+	}
+	return fee
+}
+
+// txInSize is what the size of a transaction depends on for one of its inputs.
+type txInSize struct {
+	ringVersion uint32
+	ringSize    uint8
+	pseudonym   bool
+	coinAddress string // coin address of a pseudonym input
+	value       Amount
+}
+
+func newTxInSize(params *ChainParams, height int64, txVersion uint32, txOutData []byte, value Amount, ring *CoinRing) (*txInSize, error) {
+	privacyLevel, err := crypto.GetTxoPrivacyLevel(txVersion, txOutData)
+	if err != nil {
+		return nil, err
+	}
+	in := &txInSize{
+		ringVersion: txVersion,
+		ringSize:    params.TxoRingSizeByBlockHeight(height),
+		pseudonym:   privacyLevel == crypto.PrivacyLevelPseudonym,
+		value:       value,
+	}
+	if ring != nil {
+		in.ringVersion = ring.Version
+		in.ringSize = uint8(len(ring.SerializedTxOuts))
+	}
+	if in.pseudonym {
+		// a pseudonym coin is spent alone
+		in.ringSize = 1
+		coinAddress, err := crypto.DecodeCoinAddressFromSerializedTxOutData(txVersion, txOutData)
+		if err != nil {
+			return nil, err
+		}
+		in.coinAddress = string(coinAddress.Data())
+	}
+	return in, nil
+}
+
+// txInSizes accepts []*TxInDesc and []*TxInDescWithRing. Without the ring of a full-privacy
+// input, its ring is assumed to be of the largest size at its height on the network of params.
+func txInSizes(params *ChainParams, txInDescs interface{}) ([]*txInSize, error) {
+	var ins []*txInSize
+	switch descs := txInDescs.(type) {
+	case []*TxInDesc:
+		for _, desc := range descs {
+			in, err := newTxInSize(params, desc.BlockHeight, desc.TxVersion, desc.TxOutData, desc.CoinValue, nil)
+			if err != nil {
+				return nil, err
+			}
+			ins = append(ins, in)
+		}
+	case []*TxInDescWithRing:
+		for _, desc := range descs {
+			in, err := newTxInSize(params, desc.BlockHeight, desc.TxVersion, desc.TxOutData, desc.CoinValue, desc.TxoRing)
+			if err != nil {
+				return nil, err
+			}
+			ins = append(ins, in)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported transaction inputs %T", txInDescs)
+	}
+	if len(ins) == 0 {
+		return nil, fmt.Errorf("no transaction input")
+	}
+	return ins, nil
+}
+
+// EstimateTxSize predicts the size of the transaction spending txInDescs, of type []*TxInDesc or
+// []*TxInDescWithRing, to txOutDescs with txMemo and txFee. The ring of each full-privacy input
+// is taken from TxInDescWithRing, or assumed to be of the largest size at its height on mainnet otherwise.
+func EstimateTxSize(txInDescs interface{}, txOutDescs []*TxOutDesc, txMemo []byte, txFee Amount) (*TxSize, error) {
+	return EstimateTxSizeWithParams(MainNetParams, txInDescs, txOutDescs, txMemo, txFee)
+}
+
+// EstimateTxSizeWithParams is like EstimateTxSize, with the ring sizes of the network of params.
+func EstimateTxSizeWithParams(params *ChainParams, txInDescs interface{}, txOutDescs []*TxOutDesc, txMemo []byte, txFee Amount) (*TxSize, error) {
+	ins, err := txInSizes(params, txInDescs)
+	if err != nil {
+		return nil, err
+	}
+	contentSize, err := estimateTxContentSize(ins, txOutDescs, txMemo)
+	if err != nil {
+		return nil, err
+	}
+	witnessSize, err := estimateTxWitnessSize(ins, txOutDescs, txFee)
+	if err != nil {
+		return nil, err
+	}
+	return &TxSize{ContentSize: contentSize, WitnessSize: witnessSize}, nil
+}
+
+func estimateTxContentSize(ins []*txInSize, txOutDescs []*TxOutDesc, txMemo []byte) (int64, error) {
+	ringVersions := make([]uint32, len(ins))
+	ringSizes := make([]uint8, len(ins))
+	for i, in := range ins {
+		ringVersions[i] = in.ringVersion
+		ringSizes[i] = in.ringSize
+	}
+	coinAddresses := make([][]byte, len(txOutDescs))
+	for i, desc := range txOutDescs {
+		if desc.AbelAddress == nil {
+			return 0, fmt.Errorf("transaction output %d has no address", i)
+		}
+		coinAddresses[i] = desc.AbelAddress.GetCryptoAddress().GetCoinAddress().Data()
+	}
+	size, err := crypto.PrecomputeTransferTxContentSize(crypto.TxVersion, ringVersions, ringSizes, coinAddresses, len(txMemo))
+	return int64(size), err
+}
+
+// estimateTxWitnessSize returns the size of the witness, which depends on the values of the
+// pseudonym inputs and outputs through the balance proof.
+func estimateTxWitnessSize(ins []*txInSize, txOutDescs []*TxOutDesc, txFee Amount) (int64, error) {
+	inRingSizes := make([]uint8, 0, len(ins))
+	singleAddresses := map[string]struct{}{}
+	vPublic := txFee
+	for _, in := range ins {
+		if in.pseudonym {
+			singleAddresses[in.coinAddress] = struct{}{}
+			vPublic -= in.value
+			continue
+		}
+		inRingSizes = append(inRingSizes, in.ringSize)
+	}
+	outForRing := 0
+	for _, desc := range txOutDescs {
+		if desc.AbelAddress.GetCryptoAddress().GetPrivacyLevel() == crypto.PrivacyLevelPseudonym {
+			vPublic += desc.CoinValue
+			continue
+		}
+		outForRing++
+	}
+	if len(inRingSizes) > math.MaxUint8 || len(singleAddresses) > math.MaxUint8 || outForRing > math.MaxUint8 {
+		return 0, fmt.Errorf("too many transaction inputs or outputs")
+	}
+	// vPublic balances the full-privacy values, so its sign is known when a side has none of them,
+	// even if the output values are not final yet
+	switch {
+	case len(inRingSizes) == 0 && outForRing == 0:
+		vPublic = 0
+	case len(inRingSizes) == 0:
+		vPublic = min(vPublic, -Amount(outForRing))
+	case outForRing == 0:
+		vPublic = max(vPublic, 0)
+	}
+	size, err := crypto.PrecomputeTransferTxWitnessSize(crypto.TxVersion, uint8(len(inRingSizes)), uint8(len(singleAddresses)),
+		inRingSizes, uint8(outForRing), int64(vPublic))
+	return int64(size), err
+}
+
+// EstimateTxFeeWithRate predicts the size of the transaction spending txInDescs to txOutDescs with
+// txMemo like EstimateTxSize, and the fee it requires at feeRate neutrino per kB.
+func EstimateTxFeeWithRate(txInDescs interface{}, txOutDescs []*TxOutDesc, txMemo []byte, feeRate int64) (*FeeEstimate, error) {
+	return EstimateTxFeeWithParams(MainNetParams, txInDescs, txOutDescs, txMemo, feeRate)
+}
+
+// EstimateTxFeeWithParams is like EstimateTxFeeWithRate, with the ring sizes of the network of params.
+func EstimateTxFeeWithParams(params *ChainParams, txInDescs interface{}, txOutDescs []*TxOutDesc, txMemo []byte, feeRate int64) (*FeeEstimate, error) {
+	ins, err := txInSizes(params, txInDescs)
+	if err != nil {
+		return nil, err
+	}
+	// the fee is counted with a fixed size in the content, so the content does not depend on it
+	contentSize, err := estimateTxContentSize(ins, txOutDescs, txMemo)
+	if err != nil {
+		return nil, err
+	}
+	fee := TxFeeForSize(contentSize, feeRate)
+	witnessSize, err := estimateTxWitnessSize(ins, txOutDescs, fee)
+	if err != nil {
+		return nil, err
+	}
+	return &FeeEstimate{
+		Fee:     fee,
+		FeeRate: feeRate,
+		Size:    &TxSize{ContentSize: contentSize, WitnessSize: witnessSize},
+	}, nil
+}
+
+// EstimateTxFee returns the fee of the transaction at DEFAULT_FEE_RATE, or DEFAULT_TX_FEE if its size
+// can not be estimated. Use a FeeEstimator for the fee rate of the node.
+func EstimateTxFee(txinDescs interface{}, txOutDescs []*TxOutDesc) Amount {
+	estimate, err := EstimateTxFeeWithRate(txinDescs, txOutDescs, nil, DEFAULT_FEE_RATE)
+	if err != nil {
+		sdkLog.Warnf("fail to estimate transaction fee: %v, use %v", err, DEFAULT_TX_FEE)
+		return DEFAULT_TX_FEE
+	}
+	return estimate.Fee
+}
+
+// GetSignedRawTxSize returns the size of a signed transaction, to compare with its estimate.
+func GetSignedRawTxSize(signedRawTx *SignedRawTx) (*TxSize, error) {
+	contentSize, fullSize, err := crypto.GetTxSerializeSize(signedRawTx.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &TxSize{ContentSize: int64(contentSize), WitnessSize: int64(fullSize - contentSize)}, nil
+}
+
+// FeeRateSource is where a FeeEstimator takes its fee rate from.
+type FeeRateSource int
+
+const (
+	FeeRateSourceRelay   FeeRateSource = iota // the minimum relay fee of the node, as reported by getinfo
+	FeeRateSourceMempool                      // a percentile of the fee rates in the mempool, at least the relay fee
+)
+
+// FeeEstimatorConfig configures a FeeEstimator.
+type FeeEstimatorConfig struct {
+	Source     FeeRateSource
+	Percentile int   // percentile of the mempool fee rates with FeeRateSourceMempool, default DEFAULT_FEE_PERCENTILE
+	MinFeeRate int64 // lowest fee rate in neutrino per kB
+}
+
+func NewFeeEstimatorConfig(options ...FeeEstimatorOption) *FeeEstimatorConfig {
+	config := &FeeEstimatorConfig{
+		Source:     FeeRateSourceRelay,
+		Percentile: DEFAULT_FEE_PERCENTILE,
+	}
+
+	for _, opt := range options {
+		opt(config)
+	}
+
+	return config
+}
+
+// FeeEstimatorOption change fee estimator config
+type FeeEstimatorOption func(*FeeEstimatorConfig)
+
+// WithFeeRateSource sets where the fee rate is taken from.
+func WithFeeRateSource(source FeeRateSource) FeeEstimatorOption {
+	return func(config *FeeEstimatorConfig) {
+		config.Source = source
+	}
+}
+
+// WithFeePercentile sets the percentile of the mempool fee rates, from 0 to 100.
+func WithFeePercentile(percentile int) FeeEstimatorOption {
+	return func(config *FeeEstimatorConfig) {
+		config.Percentile = percentile
+	}
+}
+
+// WithMinFeeRate sets the lowest fee rate in neutrino per kB.
+func WithMinFeeRate(feeRate int64) FeeEstimatorOption {
+	return func(config *FeeEstimatorConfig) {
+		config.MinFeeRate = feeRate
+	}
+}
+
+// FeeEstimator estimates the fee of transactions at a fee rate taken from a node.
+type FeeEstimator struct {
+	client *Client
+	config *FeeEstimatorConfig
+}
+
+func NewFeeEstimator(client *Client, config *FeeEstimatorConfig) *FeeEstimator {
+	if config == nil {
+		config = NewFeeEstimatorConfig()
+	}
+	return &FeeEstimator{
+		client: client,
+		config: config,
+	}
+}
+
+// FeeRate returns the current fee rate in neutrino per kB.
+func (estimator *FeeEstimator) FeeRate() (int64, error) {
+	return estimator.FeeRateCtx(context.Background())
+}
+func (estimator *FeeEstimator) FeeRateCtx(ctx context.Context) (int64, error) {
+	info, err := estimator.client.GetChainInfoCtx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("fail to get relay fee: %w", err)
+	}
+	feeRate := max(info.RelayFee.Neutrino(), estimator.config.MinFeeRate)
+	if estimator.config.Source != FeeRateSourceMempool {
+		return feeRate, nil
+	}
+
+	mempool, err := estimator.client.GetRawMempoolVerboseCtx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("fail to get mempool: %w", err)
+	}
+	rates := make([]int64, 0, len(mempool))
+	for _, tx := range mempool {
+		if tx.Size > 0 {
+			rates = append(rates, tx.Fee.Neutrino()*1000/tx.Size)
+		}
+	}
+	if len(rates) == 0 {
+		return feeRate, nil
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i] < rates[j] })
+	percentile := min(max(estimator.config.Percentile, 0), 100)
+	return max(rates[(len(rates)-1)*percentile/100], feeRate), nil
+}
+
+// EstimateFee predicts the size of the transaction spending txInDescs to txOutDescs with txMemo
+// like EstimateTxSize on the network of the node, and the fee it requires at the current fee rate.
+func (estimator *FeeEstimator) EstimateFee(txInDescs interface{}, txOutDescs []*TxOutDesc, txMemo []byte) (*FeeEstimate, error) {
+	return estimator.EstimateFeeCtx(context.Background(), txInDescs, txOutDescs, txMemo)
+}
+func (estimator *FeeEstimator) EstimateFeeCtx(ctx context.Context, txInDescs interface{}, txOutDescs []*TxOutDesc, txMemo []byte) (*FeeEstimate, error) {
+	params, err := estimator.client.ChainParamsCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	feeRate, err := estimator.FeeRateCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return EstimateTxFeeWithParams(params, txInDescs, txOutDescs, txMemo, feeRate)
+}
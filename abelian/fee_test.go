@@ -0,0 +1,388 @@
+package abelian_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pqabelian/abec/abecryptox"
+	"github.com/pqabelian/abec/abecryptox/abecryptoxkey"
+	"github.com/pqabelian/abec/abecryptox/abecryptoxparam"
+	"github.com/pqabelian/abec/wire"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/crypto"
+)
+
+// sizeFixtureHeight is the first height of the blocks of a sizeFixture, a ring group after MLPAUT.
+const sizeFixtureHeight = 300003
+
+// sizeFixture is a chain of coinbase transactions paying coins to a full-privacy and a pseudonym
+// account, grouped into rings as abec does, for transactions spending them.
+type sizeFixture struct {
+	fullPrivacy abelian.Account
+	pseudonym   abelian.Account
+	coins       []*fixtureCoin
+}
+
+type fixtureCoin struct {
+	desc    *abelian.TxInDescWithRing
+	account abelian.Account
+	spent   bool
+}
+
+func testAbelAddress(t *testing.T, account abelian.Account) *abelian.AbelAddress {
+	t.Helper()
+	data, err := account.(*abelian.RootSeedAccount).GenerateAbelAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err := abelian.NewAbelAddress(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return address
+}
+
+// newSizeFixture builds the blocks of groups of ring groups, each block paying the number of full-privacy
+// coins given for it and one pseudonym coin. It skips the test if the keys can not be generated, e.g. with
+// a liboqs built without Kyber.
+func newSizeFixture(t *testing.T, groups ...[3]int) *sizeFixture {
+	t.Helper()
+	fullPrivacy, err := abelian.NewAccount(abelian.MainNet, abelian.AccountPrivacyLevelFullPrivacy)
+	if err != nil {
+		t.Skipf("fail to generate a full-privacy account: %v", err)
+	}
+	pseudonym, err := abelian.NewAccount(abelian.MainNet, abelian.AccountPrivacyLevelPseudonym)
+	if err != nil {
+		t.Skipf("fail to generate a pseudonym account: %v", err)
+	}
+	if _, err := fullPrivacy.(*abelian.RootSeedAccount).GenerateAbelAddress(); err != nil {
+		t.Skipf("fail to generate a full-privacy address: %v", err)
+	}
+	fixture := &sizeFixture{fullPrivacy: fullPrivacy, pseudonym: pseudonym}
+
+	height := int64(sizeFixtureHeight)
+	for _, group := range groups {
+		var blocks [][]byte
+		var coins []*fixtureCoin
+		for _, fullPrivacyCoins := range group {
+			var outputs []*abecryptox.AbeTxOutputDesc
+			var accounts []abelian.Account
+			total := uint64(0)
+			for i := 0; i <= fullPrivacyCoins; i++ {
+				account := fullPrivacy
+				if i == fullPrivacyCoins {
+					account = pseudonym
+				}
+				value := uint64(1_000_000_000 + i)
+				outputs = append(outputs, abecryptox.NewAbeTxOutDesc(testAbelAddress(t, account).GetCryptoAddress().Data(), value))
+				accounts = append(accounts, account)
+				total += value
+			}
+			template := wire.NewMsgTxAbe(wire.TxVersion)
+			txIn, err := wire.NewStandardCoinbaseTxIn(int32(height), wire.TxVersion)
+			if err != nil {
+				t.Fatal(err)
+			}
+			template.AddTxIn(txIn)
+			template.TxFee = total
+			coinbase, err := abecryptox.CoinbaseTxGen(outputs, template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			block := &wire.MsgBlockAbe{Header: wire.BlockHeader{Version: wire.BlockVersionMLPAUT, Height: int32(height)}}
+			block.AddTransaction(coinbase)
+			var buf bytes.Buffer
+			if err := block.Serialize(&buf); err != nil {
+				t.Fatal(err)
+			}
+			blocks = append(blocks, buf.Bytes())
+
+			for i, txOut := range coinbase.TxOuts {
+				var txOutData bytes.Buffer
+				if err := wire.WriteTxOutAbe(&txOutData, 0, coinbase.Version, txOut); err != nil {
+					t.Fatal(err)
+				}
+				coins = append(coins, &fixtureCoin{
+					desc: &abelian.TxInDescWithRing{
+						BlockHeight: height,
+						TxVersion:   coinbase.Version,
+						TxID:        coinbase.TxHash().String(),
+						TxOutIndex:  uint8(i),
+						TxOutData:   txOutData.Bytes(),
+						CoinValue:   abelian.Amount(outputs[i].Value()),
+					},
+					account: accounts[i],
+				})
+			}
+			height++
+		}
+
+		rings, err := abelian.BuildCoinRings(blocks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, coin := range coins {
+			for _, ring := range rings {
+				for _, coinID := range ring.CoinIDRing.CoinIDs {
+					if coinID.TxID == coin.desc.TxID && coinID.Index == coin.desc.TxOutIndex {
+						coin.desc.TxoRing = ring
+					}
+				}
+			}
+			if coin.desc.TxoRing == nil {
+				t.Fatalf("no ring for coin %s:%d", coin.desc.TxID, coin.desc.TxOutIndex)
+			}
+		}
+		fixture.coins = append(fixture.coins, coins...)
+	}
+	return fixture
+}
+
+// coin returns an unspent coin of account in a ring of ringSize coins.
+func (fixture *sizeFixture) coin(t *testing.T, account abelian.Account, ringSize int) *fixtureCoin {
+	t.Helper()
+	for _, coin := range fixture.coins {
+		if !coin.spent && coin.account == account && len(coin.desc.TxoRing.SerializedTxOuts) == ringSize {
+			coin.spent = true
+			return coin
+		}
+	}
+	t.Fatalf("no unspent coin in a ring of %d", ringSize)
+	return nil
+}
+
+// spend signs a transaction spending coins to an address of each account of outputs with memo, paying
+// the fee estimated at feeRate, and returns the descriptions of its inputs and outputs, its fee and its size.
+func (fixture *sizeFixture) spend(t *testing.T, coins []*fixtureCoin, outputs []abelian.Account, memo []byte, feeRate int64) (
+	[]*abelian.TxInDescWithRing, []*abelian.TxOutDesc, abelian.Amount, *abelian.TxSize) {
+	t.Helper()
+	ins := make([]*abelian.TxInDescWithRing, 0, len(coins))
+	total := abelian.Amount(0)
+	for _, coin := range coins {
+		ins = append(ins, coin.desc)
+		total += coin.desc.CoinValue
+	}
+	if err := abelian.SortTxInDescWithRing(ins); err != nil {
+		t.Fatal(err)
+	}
+	signers := make([]abelian.Account, 0, len(ins))
+	for _, in := range ins {
+		for _, coin := range coins {
+			if coin.desc == in {
+				signers = append(signers, coin.account)
+			}
+		}
+	}
+	outs := make([]*abelian.TxOutDesc, 0, len(outputs))
+	for _, account := range outputs {
+		outs = append(outs, &abelian.TxOutDesc{AbelAddress: testAbelAddress(t, account)})
+	}
+
+	// the content, and so the fee, does not depend on the output values
+	estimate, err := abelian.EstimateTxFeeWithRate(ins, outs, memo, feeRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	change := total - estimate.Fee
+	for _, out := range outs {
+		out.CoinValue = change / abelian.Amount(len(outs))
+	}
+	outs[0].CoinValue += change % abelian.Amount(len(outs))
+	if err := abelian.SortTxOutDesc(outs); err != nil {
+		t.Fatal(err)
+	}
+
+	desc := abelian.NewTxDescWithRing(ins, outs, estimate.Fee)
+	desc.TxMemo = memo
+	unsignedRawTx, err := abelian.GenerateUnsignedRawTxWithRing(desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedRawTx, err := abelian.GenerateSignedRawTx(unsignedRawTx, signers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, err := abelian.GetSignedRawTxSize(signedRawTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ins, outs, estimate.Fee, size
+}
+
+func TestEstimateTxSize(t *testing.T) {
+	if testing.Short() {
+		t.Skip("signs transactions")
+	}
+	// rings of 2, 7 and 4 full-privacy coins, and a pseudonym coin in each block
+	fixture := newSizeFixture(t, [3]int{1, 1, 0}, [3]int{3, 2, 2}, [3]int{4, 0, 0})
+	fullPrivacy, pseudonym := fixture.fullPrivacy, fixture.pseudonym
+	tests := []struct {
+		name       string
+		ringSizes  []int // ring sizes of the full-privacy inputs
+		pseudonyms int   // pseudonym inputs
+		outputs    []abelian.Account
+		memo       []byte
+	}{
+		{"ring of 2 to 2 outputs", []int{2}, 0, []abelian.Account{fullPrivacy, fullPrivacy}, nil},
+		{"ring of 7 to 1 output", []int{7}, 0, []abelian.Account{fullPrivacy}, nil},
+		{"rings of 4 and 7 to 3 outputs", []int{4, 7}, 0, []abelian.Account{fullPrivacy, fullPrivacy, fullPrivacy}, make([]byte, 300)},
+		{"ring of 2 to a pseudonym output", []int{2}, 0, []abelian.Account{fullPrivacy, pseudonym}, nil},
+		{"pseudonym to 1 output", nil, 1, []abelian.Account{fullPrivacy}, nil},
+		{"pseudonym to pseudonym", nil, 1, []abelian.Account{pseudonym}, nil},
+		{"2 pseudonyms to 2 outputs", nil, 2, []abelian.Account{fullPrivacy, fullPrivacy}, nil},
+		{"ring of 7 and pseudonym with memo", []int{7}, 1, []abelian.Account{fullPrivacy, pseudonym}, []byte("memo")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var coins []*fixtureCoin
+			for _, ringSize := range tt.ringSizes {
+				coins = append(coins, fixture.coin(t, fullPrivacy, ringSize))
+			}
+			for i := 0; i < tt.pseudonyms; i++ {
+				coins = append(coins, fixture.coin(t, pseudonym, 1))
+			}
+			ins, outs, fee, actual := fixture.spend(t, coins, tt.outputs, tt.memo, 1000)
+
+			estimate, err := abelian.EstimateTxSize(ins, outs, tt.memo, fee)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// abec counts the fee on 8 bytes, which takes a varint in the transaction
+			if estimate.ContentSize < actual.ContentSize || estimate.ContentSize-actual.ContentSize > 8 {
+				t.Errorf("content size = %d, want %d up to 8 bytes more", estimate.ContentSize, actual.ContentSize)
+			}
+			// the witness size depends on random values, the estimate is at most 5% above it
+			if estimate.WitnessSize < actual.WitnessSize || estimate.WitnessSize-actual.WitnessSize > actual.WitnessSize/20 {
+				t.Errorf("witness size = %d, want %d up to 5%% more", estimate.WitnessSize, actual.WitnessSize)
+			}
+
+			// without the rings, the rings are assumed to be of the largest size
+			withoutRings := make([]*abelian.TxInDesc, 0, len(ins))
+			for _, in := range ins {
+				withoutRings = append(withoutRings, &abelian.TxInDesc{
+					BlockHeight: in.BlockHeight,
+					TxVersion:   in.TxVersion,
+					TxID:        in.TxID,
+					TxOutIndex:  in.TxOutIndex,
+					TxOutData:   in.TxOutData,
+					CoinValue:   in.CoinValue,
+				})
+			}
+			upper, err := abelian.EstimateTxSize(withoutRings, outs, tt.memo, fee)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if upper.ContentSize < actual.ContentSize || upper.FullSize() < actual.FullSize() {
+				t.Errorf("size without rings = %+v, want at least %+v", upper, actual)
+			}
+			if required := abelian.TxFeeForSize(actual.ContentSize, 1000); fee < required {
+				t.Errorf("fee = %v, want at least %v", fee, required)
+			}
+		})
+	}
+}
+
+// zeroKeyCoin returns an address of the privacy level on mainnet and the data of an output paying to it,
+// made of zero keys but for tag, which distinguishes the coin addresses. Their sizes are those of real keys,
+// so the estimations need no key generation. A full-privacy address needs the sizes of Kyber from liboqs.
+func zeroKeyCoin(t *testing.T, pseudonym bool, tag byte) (*abelian.AbelAddress, []byte) {
+	t.Helper()
+	privacyLevel, addressLen, coinAddressLen := byte(abecryptoxkey.PrivacyLevelRINGCT), abelian.ABEL_ADDRESS_LENGTH_RINGCT, crypto.COIN_ADDRESS_LENGTH_FULL_PRIVACY_RAND
+	if pseudonym {
+		privacyLevel, addressLen, coinAddressLen = byte(abecryptoxkey.PrivacyLevelPSEUDONYM), abelian.ABEL_ADDRESS_LENGTH_PSEUDONYM, crypto.COIN_ADDRESS_LENGTH_PSEUDONYM
+	}
+	// net ID, crypto scheme, privacy level, coin address starting with its type, value public key, checksum
+	data := make([]byte, addressLen)
+	data[0] = byte(abelian.MainNet)
+	copy(data[1:], abecryptoxparam.SerializeCryptoScheme(abecryptoxparam.CryptoSchemePQRingCTX))
+	data[5] = privacyLevel
+	coinAddress := data[6 : 6+coinAddressLen]
+	coinAddress[0] = privacyLevel
+	coinAddress[1] = tag
+	address, err := abelian.NewAbelAddress(data)
+	if err != nil {
+		t.Skipf("fail to decode a zero-key address: %v", err)
+	}
+
+	txoSize, err := abecryptox.GetTxoSerializeSizeApprox(wire.TxVersion, address.GetCryptoAddress().Data())
+	if err != nil {
+		t.Fatal(err)
+	}
+	txo := &wire.TxOutAbe{Version: wire.TxVersion, TxoScript: make([]byte, txoSize)}
+	copy(txo.TxoScript, coinAddress)
+	var txOutData bytes.Buffer
+	if err := wire.WriteTxOutAbe(&txOutData, 0, wire.TxVersion, txo); err != nil {
+		t.Fatal(err)
+	}
+	return address, txOutData.Bytes()
+}
+
+func TestEstimateTxSizeOfZeroKeys(t *testing.T) {
+	tests := []struct {
+		name        string
+		ringSizes   []int // ring sizes of the full-privacy inputs, 0 for the largest at their height
+		pseudonyms  []byte
+		outputs     []bool // pseudonym outputs
+		memo        []byte
+		wantContent int64
+		wantWitness int64
+	}{
+		{"pseudonym to pseudonym", nil, []byte{1}, []bool{true}, nil, 421, 18772},
+		{"pseudonyms of 2 addresses to pseudonym", nil, []byte{1, 2}, []bool{true}, nil, 621, 37525},
+		{"pseudonyms of 1 address with memo", nil, []byte{1, 1}, []bool{true, true}, make([]byte, 300), 1129, 18772},
+		{"largest ring to 2 outputs", []int{0}, nil, []bool{false, false}, nil, 41609, 1168025},
+		{"ring of 2 to a pseudonym output", []int{2}, nil, []bool{false, true}, nil, 21052, 463464},
+		{"rings of 4 and 7 and pseudonym with memo", []int{4, 7}, []byte{1}, []bool{false, false, false}, []byte("memo"), 62710, 1836328},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ins []*abelian.TxInDescWithRing
+			for _, ringSize := range tt.ringSizes {
+				_, txOutData := zeroKeyCoin(t, false, 0)
+				in := &abelian.TxInDescWithRing{BlockHeight: sizeFixtureHeight, TxVersion: wire.TxVersion, TxOutData: txOutData, CoinValue: 1000}
+				if ringSize > 0 {
+					in.TxoRing = &abelian.CoinRing{Version: wire.TxVersion, SerializedTxOuts: make([][]byte, ringSize)}
+				}
+				ins = append(ins, in)
+			}
+			for _, tag := range tt.pseudonyms {
+				_, txOutData := zeroKeyCoin(t, true, tag)
+				ins = append(ins, &abelian.TxInDescWithRing{BlockHeight: sizeFixtureHeight, TxVersion: wire.TxVersion, TxOutData: txOutData, CoinValue: 1000})
+			}
+			var outs []*abelian.TxOutDesc
+			for i, pseudonym := range tt.outputs {
+				address, _ := zeroKeyCoin(t, pseudonym, byte(10+i))
+				outs = append(outs, &abelian.TxOutDesc{AbelAddress: address, CoinValue: 500})
+			}
+
+			size, err := abelian.EstimateTxSize(ins, outs, tt.memo, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size.ContentSize != tt.wantContent || size.WitnessSize != tt.wantWitness {
+				t.Errorf("size = %d + %d of witness, want %d + %d", size.ContentSize, size.WitnessSize, tt.wantContent, tt.wantWitness)
+			}
+		})
+	}
+}
+
+func TestTxFeeForSize(t *testing.T) {
+	tests := []struct {
+		contentSize int64
+		feeRate     int64
+		want        abelian.Amount
+	}{
+		{1000, 10, 10},
+		{2500, 1000, 2500},
+		{1999, 10, 19}, // rounded down
+		{50, 10, 10},   // at least the fee rate
+		{0, 10, 10},
+		{1000, 0, 0},
+	}
+	for _, tt := range tests {
+		if fee := abelian.TxFeeForSize(tt.contentSize, tt.feeRate); fee != tt.want {
+			t.Errorf("TxFeeForSize(%d, %d) = %d, want %d", tt.contentSize, tt.feeRate, fee, tt.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package abelian
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+const (
+	DEFAULT_FETCH_CONCURRENCY = 4
+	DEFAULT_FETCH_PREFETCH    = 16 // blocks
+)
+
+// FetchOptions configures FetchBlocks.
+type FetchOptions struct {
+	Concurrency int // blocks fetched at the same time, default DEFAULT_FETCH_CONCURRENCY
+	Prefetch    int // blocks fetched ahead of the consumer, default DEFAULT_FETCH_PREFETCH, at least Concurrency
+}
+
+func NewFetchOptions(options ...FetchOption) *FetchOptions {
+	opts := &FetchOptions{
+		Concurrency: DEFAULT_FETCH_CONCURRENCY,
+		Prefetch:    DEFAULT_FETCH_PREFETCH,
+	}
+
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	return opts
+}
+
+// FetchOption change fetch options
+type FetchOption func(*FetchOptions)
+
+func WithFetchConcurrency(concurrency int) FetchOption {
+	return func(opts *FetchOptions) {
+		opts.Concurrency = concurrency
+	}
+}
+
+func WithFetchPrefetch(prefetch int) FetchOption {
+	return func(opts *FetchOptions) {
+		opts.Prefetch = prefetch
+	}
+}
+
+// BlockStream delivers the blocks of FetchBlocks in height order.
+//
+//	stream := client.FetchBlocks(ctx, from, to, nil)
+//	defer stream.Close()
+//	for stream.Next() {
+//		block := stream.Block()
+//		...
+//	}
+//	if err := stream.Err(); err != nil {
+//		...
+//	}
+type BlockStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	slots  chan *fetchSlot
+
+	block *Block
+	err   error
+}
+
+// fetchSlot is the pending block at one height, filled by a worker.
+type fetchSlot struct {
+	height int64
+	block  *Block
+	err    error
+	done   chan struct{}
+}
+
+// FetchBlocks fetches the blocks at heights from (inclusive) to to (exclusive) with their
+// transactions in Block.RawTxs, using a pool of workers, and delivers them in height order.
+// options may be nil for the defaults. The first failure stops the stream and is returned by Err.
+func (client *Client) FetchBlocks(ctx context.Context, from int64, to int64, options *FetchOptions) *BlockStream {
+	if options == nil {
+		options = NewFetchOptions()
+	}
+	concurrency := max(options.Concurrency, 1)
+	prefetch := max(options.Prefetch, concurrency)
+
+	stream := &BlockStream{slots: make(chan *fetchSlot, prefetch)}
+	stream.ctx, stream.cancel = context.WithCancel(ctx)
+
+	jobs := make(chan *fetchSlot)
+	stream.wg.Add(1 + concurrency)
+	go func() {
+		defer stream.wg.Done()
+		defer close(stream.slots)
+		defer close(jobs)
+		for height := from; height < to; height++ {
+			slot := &fetchSlot{height: height, done: make(chan struct{})}
+			// the slot is queued for the consumer first, which bounds the blocks fetched ahead of it
+			select {
+			case stream.slots <- slot:
+			case <-stream.ctx.Done():
+				return
+			}
+			select {
+			case jobs <- slot:
+			case <-stream.ctx.Done():
+				return
+			}
+		}
+	}()
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer stream.wg.Done()
+			for slot := range jobs {
+				slot.block, slot.err = client.getBlockWithTxsByHeight(stream.ctx, slot.height)
+				close(slot.done)
+			}
+		}()
+	}
+
+	return stream
+}
+
+// Next waits for the next block, returning false at the end of the range or on failure.
+func (stream *BlockStream) Next() bool {
+	if stream.err != nil {
+		return false
+	}
+	var slot *fetchSlot
+	select {
+	case slot = <-stream.slots:
+	case <-stream.ctx.Done():
+		stream.err = stream.ctx.Err()
+		return false
+	}
+	if slot == nil {
+		stream.block = nil
+		return false
+	}
+	select {
+	case <-slot.done:
+	case <-stream.ctx.Done():
+		stream.err = stream.ctx.Err()
+		return false
+	}
+	if slot.err != nil {
+		stream.err = fmt.Errorf("fail to fetch block at height %d: %w", slot.height, slot.err)
+		stream.cancel()
+		return false
+	}
+	stream.block = slot.block
+	return true
+}
+
+// Block returns the block delivered by the last call to Next.
+func (stream *BlockStream) Block() *Block {
+	return stream.block
+}
+
+// Err returns the failure which stopped the stream, or nil if the whole range was delivered.
+func (stream *BlockStream) Err() error {
+	return stream.err
+}
+
+// Close stops the workers and waits for them to exit. It may be called before the end of the range.
+func (stream *BlockStream) Close() {
+	stream.cancel()
+	stream.wg.Wait()
+}
+
+func (client *Client) getBlockWithTxsByHeight(ctx context.Context, height int64) (*Block, error) {
+	blockID, err := client.GetBlockHashCtx(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetBlockWithTxsCtx(ctx, blockID)
+}
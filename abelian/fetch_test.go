@@ -0,0 +1,145 @@
+package abelian_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+// addTestBlocks adds n blocks of two transactions to server.
+func addTestBlocks(server *abeliantest.Server, n int) {
+	for i := 0; i < n; i++ {
+		server.AddBlock(&abelian.Tx{Hex: "00" + string(rune('a'+i%26))}, &abelian.Tx{Hex: "01" + string(rune('a'+i%26))})
+	}
+}
+
+func checkBlockTxs(t *testing.T, block *abelian.Block, want *abelian.Block) {
+	t.Helper()
+	if block.BlockHash != want.BlockHash {
+		t.Fatalf("block %s, want %s", block.BlockHash, want.BlockHash)
+	}
+	if !reflect.DeepEqual(block.TxHashes, want.TxHashes) {
+		t.Errorf("TxHashes of %s = %v, want %v", block.BlockHash, block.TxHashes, want.TxHashes)
+	}
+	if len(block.RawTxs) != len(want.TxHashes) {
+		t.Fatalf("%d RawTxs in %s, want %d", len(block.RawTxs), block.BlockHash, len(want.TxHashes))
+	}
+	for i, tx := range block.RawTxs {
+		if tx.TxID != want.TxHashes[i] {
+			t.Errorf("RawTxs[%d] of %s = %s, want %s", i, block.BlockHash, tx.TxID, want.TxHashes[i])
+		}
+	}
+}
+
+func TestGetBlockWithTxs(t *testing.T) {
+	tests := []struct {
+		name          string
+		hashesOnly    bool // the node answers getblockabe at verbosity 2 without rawTx
+		wantTxFetches int
+	}{
+		{"transactions in the block", false, 0},
+		{"transactions fetched in a batch", true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := abeliantest.NewServer(abeliantest.WithBatch())
+			defer server.Close()
+			addTestBlocks(server, 1)
+			want := server.BlockByHeight(1)
+			if tt.hashesOnly {
+				server.Handle("getblockabe", func(params []json.RawMessage) (any, *abelian.RPCError) {
+					block := *want
+					block.RawTxs = nil
+					return &block, nil
+				})
+			}
+			client, err := server.NewClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			block, err := client.GetBlockWithTxs(want.BlockHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkBlockTxs(t, block, want)
+			if calls := server.CallCount("getrawtransaction"); calls != tt.wantTxFetches {
+				t.Errorf("getrawtransaction calls = %d, want %d", calls, tt.wantTxFetches)
+			}
+		})
+	}
+}
+
+func TestGetBlockWithTxsCached(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	addTestBlocks(server, 1)
+	client := newCachedClient(t, server)
+
+	want := server.BlockByHeight(1)
+	for i := 0; i < 2; i++ {
+		block, err := client.GetBlockWithTxs(want.BlockHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkBlockTxs(t, block, want)
+	}
+	if calls := server.CallCount("getblockabe"); calls != 1 {
+		t.Errorf("getblockabe calls = %d, want 1", calls)
+	}
+}
+
+func TestFetchBlocks(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	addTestBlocks(server, 20)
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	stream := client.FetchBlocks(context.Background(), 1, 21, abelian.NewFetchOptions(abelian.WithFetchConcurrency(3)))
+	defer stream.Close()
+	height := int64(1)
+	for stream.Next() {
+		checkBlockTxs(t, stream.Block(), server.BlockByHeight(height))
+		height++
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if height != 21 {
+		t.Errorf("stream ended at height %d, want 21", height)
+	}
+	if calls := server.CallCount("getrawtransaction"); calls != 0 {
+		t.Errorf("getrawtransaction calls = %d, want 0", calls)
+	}
+}
+
+func TestFetchBlocksFailure(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	addTestBlocks(server, 5)
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// heights beyond the tip are not found
+	stream := client.FetchBlocks(context.Background(), 1, 10, nil)
+	defer stream.Close()
+	n := 0
+	for stream.Next() {
+		n++
+	}
+	if n != 5 || stream.Err() == nil {
+		t.Errorf("delivered %d blocks with error %v, want 5 blocks and an error", n, stream.Err())
+	}
+}
@@ -0,0 +1,412 @@
+package abelian
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pqabelian/abec/wire"
+)
+
+const (
+	DEFAULT_FOLLOWER_FINALITY_DEPTH = 100    // blocks
+	DEFAULT_FOLLOWER_POLL_INTERVAL  = 10_000 // milliseconds
+)
+
+// FollowerConfig configures a ChainFollower.
+type FollowerConfig struct {
+	StartHeight   int64         // height of the first block connected when the store has no cursor
+	FinalityDepth int64         // confirmations after which a block can no longer be disconnected, default DEFAULT_FOLLOWER_FINALITY_DEPTH
+	PollInterval  uint64        // interval in milliseconds between two checks of the tip, default DEFAULT_FOLLOWER_POLL_INTERVAL
+	Fetch         *FetchOptions // options of the blocks fetched while catching up, nil for the defaults
+	Store         CursorStore   // store of the cursor, nil to start from StartHeight every time
+}
+
+func NewFollowerConfig(options ...FollowerOption) *FollowerConfig {
+	config := &FollowerConfig{
+		FinalityDepth: DEFAULT_FOLLOWER_FINALITY_DEPTH,
+		PollInterval:  DEFAULT_FOLLOWER_POLL_INTERVAL,
+	}
+
+	for _, opt := range options {
+		opt(config)
+	}
+
+	return config
+}
+
+// FollowerOption change follower config
+type FollowerOption func(*FollowerConfig)
+
+// WithFollowerStartHeight sets the height of the first block connected when the store has no cursor.
+func WithFollowerStartHeight(height int64) FollowerOption {
+	return func(config *FollowerConfig) {
+		config.StartHeight = height
+	}
+}
+
+// WithFollowerFinalityDepth sets the confirmations after which a block can no longer be disconnected.
+func WithFollowerFinalityDepth(depth int64) FollowerOption {
+	return func(config *FollowerConfig) {
+		config.FinalityDepth = depth
+	}
+}
+
+// WithFollowerPollInterval sets the interval in milliseconds between two checks of the tip.
+func WithFollowerPollInterval(interval uint64) FollowerOption {
+	return func(config *FollowerConfig) {
+		config.PollInterval = interval
+	}
+}
+
+// WithFollowerFetchOptions sets the options of the blocks fetched while catching up.
+func WithFollowerFetchOptions(options *FetchOptions) FollowerOption {
+	return func(config *FollowerConfig) {
+		config.Fetch = options
+	}
+}
+
+// WithFollowerStore persists the cursor in store, so that the follower resumes where it stopped.
+func WithFollowerStore(store CursorStore) FollowerOption {
+	return func(config *FollowerConfig) {
+		config.Store = store
+	}
+}
+
+// ChainHandler receives the blocks connected to and disconnected from the main chain, in order.
+// The blocks come with their transactions in RawTxs. An error stops the follower, and the same
+// block is delivered again once it runs again.
+//
+// The node describes only the blocks of the main chain, so disconnected blocks are decoded from
+// their serialized form: they have no confirmations, difficulty or next block, and their
+// transactions have no witness.
+type ChainHandler interface {
+	Connected(block *Block) error
+	Disconnected(block *Block) error
+}
+
+// ChainHandlerFuncs adapts functions to ChainHandler. A nil function ignores its blocks.
+type ChainHandlerFuncs struct {
+	OnConnected    func(block *Block) error
+	OnDisconnected func(block *Block) error
+}
+
+func (funcs *ChainHandlerFuncs) Connected(block *Block) error {
+	if funcs.OnConnected == nil {
+		return nil
+	}
+	return funcs.OnConnected(block)
+}
+
+func (funcs *ChainHandlerFuncs) Disconnected(block *Block) error {
+	if funcs.OnDisconnected == nil {
+		return nil
+	}
+	return funcs.OnDisconnected(block)
+}
+
+// ChainCursor is a block connected by a follower.
+type ChainCursor struct {
+	Height    int64  `json:"height"`
+	BlockHash string `json:"hash"`
+}
+
+// CursorStore persists the last blocks connected by a follower, lowest first. Load returns
+// no cursor if nothing was saved yet.
+type CursorStore interface {
+	Load() ([]*ChainCursor, error)
+	Save(cursors []*ChainCursor) error
+}
+
+// FileCursorStore is a CursorStore keeping the cursor in a JSON file.
+type FileCursorStore struct {
+	Path string
+}
+
+func NewFileCursorStore(path string) *FileCursorStore {
+	return &FileCursorStore{Path: path}
+}
+
+func (store *FileCursorStore) Load() ([]*ChainCursor, error) {
+	data, err := os.ReadFile(store.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cursors []*ChainCursor
+	err = json.Unmarshal(data, &cursors)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse cursor file %s: %v", store.Path, err)
+	}
+	return cursors, nil
+}
+
+func (store *FileCursorStore) Save(cursors []*ChainCursor) error {
+	data, err := json.Marshal(cursors)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(store.Path, data)
+}
+
+// ChainFollower follows the main chain of a node, and reports its blocks to a ChainHandler:
+// Connected for each block in height order and, when the node switches to another branch,
+// Disconnected for each orphaned block from the tip down before the blocks of the new branch.
+//
+// The follower remembers the last FinalityDepth blocks it connected and checks that each new block
+// follows the previous one by PrevBlockHash. A reorganization deeper than that fails with ErrReorgTooDeep,
+// before any block is disconnected.
+type ChainFollower struct {
+	client  *Client
+	handler ChainHandler
+	config  *FollowerConfig
+
+	// recent are the last blocks connected, lowest first, at most FinalityDepth of them
+	recent []*ChainCursor
+}
+
+func NewChainFollower(client *Client, handler ChainHandler, config *FollowerConfig) (*ChainFollower, error) {
+	if config == nil {
+		config = NewFollowerConfig()
+	}
+	if config.FinalityDepth < 1 {
+		return nil, fmt.Errorf("finality depth must be at least 1")
+	}
+	follower := &ChainFollower{
+		client:  client,
+		handler: handler,
+		config:  config,
+	}
+	if config.Store != nil {
+		cursors, err := config.Store.Load()
+		if err != nil {
+			return nil, fmt.Errorf("fail to load cursor: %v", err)
+		}
+		follower.recent = cursors
+	}
+	return follower, nil
+}
+
+// Cursor returns the last block connected, or nil if none.
+func (follower *ChainFollower) Cursor() *ChainCursor {
+	if len(follower.recent) == 0 {
+		return nil
+	}
+	cursor := *follower.recent[len(follower.recent)-1]
+	return &cursor
+}
+
+// Run follows the chain until ctx is done or the handler fails, checking the tip every PollInterval.
+// A failure to reach the node is logged and retried at the next check.
+func (follower *ChainFollower) Run(ctx context.Context) error {
+	ticker := time.NewTicker(time.Duration(max(follower.config.PollInterval, 1)) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		err := follower.Sync(ctx)
+		if err != nil && ctx.Err() == nil {
+			var handlerErr *chainHandlerError
+			if errors.As(err, &handlerErr) || errors.Is(err, ErrReorgTooDeep) {
+				return err
+			}
+			sdkLog.Warnf("fail to follow chain: %v, retry in %v", err, time.Duration(follower.config.PollInterval)*time.Millisecond)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// chainHandlerError is an error returned by the handler of a follower.
+type chainHandlerError struct {
+	err error
+}
+
+func (e *chainHandlerError) Error() string {
+	return e.err.Error()
+}
+
+func (e *chainHandlerError) Unwrap() error {
+	return e.err
+}
+
+// Sync reports the blocks up to the current tip of the node, and returns once it is reached.
+func (follower *ChainFollower) Sync(ctx context.Context) error {
+	for {
+		err := follower.rewind(ctx)
+		if err != nil {
+			return err
+		}
+		done, err := follower.connect(ctx)
+		if err != nil || done {
+			return err
+		}
+		// the chain changed while connecting its blocks
+	}
+}
+
+// rewind disconnects the blocks which are no longer on the main chain. It first looks for the last
+// block still on the main chain, so that nothing is disconnected if the fork point is out of reach.
+func (follower *ChainFollower) rewind(ctx context.Context) error {
+	fork := len(follower.recent) // number of recent blocks still on the main chain
+	for fork > 0 {
+		cursor := follower.recent[fork-1]
+		blockHash, err := follower.client.GetBlockHashCtx(ctx, cursor.Height)
+		if err != nil && !errors.Is(err, ErrBlockNotFound) {
+			return err
+		}
+		if err == nil && blockHash == cursor.BlockHash {
+			break
+		}
+		fork--
+	}
+	if fork == len(follower.recent) {
+		return nil
+	}
+	// with no block left, the fork point is unknown unless nothing was connected below
+	if fork == 0 && follower.recent[0].Height > follower.config.StartHeight {
+		return fmt.Errorf("%w: none of the last %d blocks connected from height %d is on the main chain",
+			ErrReorgTooDeep, len(follower.recent), follower.recent[0].Height)
+	}
+
+	for len(follower.recent) > fork {
+		cursor := follower.recent[len(follower.recent)-1]
+		block, err := follower.disconnectedBlock(ctx, cursor)
+		if err != nil {
+			return fmt.Errorf("fail to get disconnected block %s: %w", cursor.BlockHash, err)
+		}
+		sdkLog.Infof("block %s at height %d is disconnected from the main chain", cursor.BlockHash, cursor.Height)
+		err = follower.handler.Disconnected(block)
+		if err != nil {
+			return &chainHandlerError{err: err}
+		}
+		follower.recent = follower.recent[:len(follower.recent)-1]
+		err = follower.save()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// disconnectedBlock returns the block of cursor, which is no longer on the main chain. abec fails to describe
+// such a block, but still returns it serialized.
+func (follower *ChainFollower) disconnectedBlock(ctx context.Context, cursor *ChainCursor) (*Block, error) {
+	blockBytes, err := follower.client.GetBlockBytesCtx(ctx, cursor.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBlock(cursor.BlockHash, cursor.Height, blockBytes)
+}
+
+// decodeBlock returns the block serialized without witness in blockBytes, as returned by getblockabe
+// with verbosity 0. blockHash and height are those of the block, which the headers before EthashPoW lack.
+func decodeBlock(blockHash string, height int64, blockBytes []byte) (*Block, error) {
+	msgBlock := &wire.MsgBlockAbe{}
+	err := msgBlock.DeserializeNoWitness(bytes.NewReader(blockBytes))
+	if err != nil {
+		return nil, fmt.Errorf("fail to decode block %s: %v", blockHash, err)
+	}
+	header := &msgBlock.Header
+	block := &Block{
+		Height:        height,
+		Version:       int64(header.Version),
+		VersionHex:    fmt.Sprintf("%08x", header.Version),
+		Time:          header.Timestamp.Unix(),
+		Nonce:         uint64(header.Nonce),
+		Size:          int64(len(blockBytes)),
+		BlockHash:     blockHash,
+		PrevBlockHash: header.PrevBlock.String(),
+		MerkleRoot:    header.MerkleRoot.String(),
+		Bits:          fmt.Sprintf("%08x", header.Bits),
+		TxHashes:      make([]string, 0, len(msgBlock.Transactions)),
+		RawTxs:        make([]*Tx, 0, len(msgBlock.Transactions)),
+	}
+	if header.Version >= int32(wire.BlockVersionEthashPow) {
+		block.Nonce = header.NonceExt
+		block.Mixdigest = header.MixDigest.String()
+	}
+	for _, msgTx := range msgBlock.Transactions {
+		var buf bytes.Buffer
+		err = msgTx.Serialize(&buf)
+		if err != nil {
+			return nil, fmt.Errorf("fail to encode transaction of block %s: %v", blockHash, err)
+		}
+		txID := msgTx.TxHash().String()
+		block.TxHashes = append(block.TxHashes, txID)
+		block.RawTxs = append(block.RawTxs, &Tx{
+			Hex:       hex.EncodeToString(buf.Bytes()),
+			TxID:      txID,
+			TxHash:    txID,
+			Time:      block.Time,
+			BlockHash: blockHash,
+			BlockTime: block.Time,
+			Version:   msgTx.Version,
+			Size:      int64(buf.Len()),
+		})
+	}
+	return block, nil
+}
+
+// connect connects the blocks after the cursor up to the tip. It returns false if a block
+// does not follow the previous one, i.e. the chain changed in the meantime.
+func (follower *ChainFollower) connect(ctx context.Context) (bool, error) {
+	tip, err := follower.client.GetBlockCountCtx(ctx)
+	if err != nil {
+		return false, err
+	}
+	from := follower.config.StartHeight
+	if cursor := follower.Cursor(); cursor != nil {
+		from = cursor.Height + 1
+	}
+	if from > tip {
+		return true, nil
+	}
+
+	stream := follower.client.FetchBlocks(ctx, from, tip+1, follower.config.Fetch)
+	defer stream.Close()
+	for stream.Next() {
+		block := stream.Block()
+		if cursor := follower.Cursor(); cursor != nil && block.PrevBlockHash != cursor.BlockHash {
+			sdkLog.Infof("block %s at height %d does not follow block %s, the chain changed", block.BlockHash, block.Height, cursor.BlockHash)
+			return false, nil
+		}
+		err = follower.handler.Connected(block)
+		if err != nil {
+			return false, &chainHandlerError{err: err}
+		}
+		follower.recent = append(follower.recent, &ChainCursor{Height: block.Height, BlockHash: block.BlockHash})
+		if int64(len(follower.recent)) > follower.config.FinalityDepth {
+			follower.recent = follower.recent[1:]
+		}
+		err = follower.save()
+		if err != nil {
+			return false, err
+		}
+	}
+	err = stream.Err()
+	if errors.Is(err, ErrBlockNotFound) {
+		// the chain got shorter in the meantime
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (follower *ChainFollower) save() error {
+	if follower.config.Store == nil {
+		return nil
+	}
+	err := follower.config.Store.Save(follower.recent)
+	if err != nil {
+		return fmt.Errorf("fail to save cursor: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package abelian_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+// chainRecorder is a ChainHandler recording the blocks it receives, as "+height" or "-height".
+type chainRecorder struct {
+	events       []string
+	disconnected []*abelian.Block
+}
+
+func (recorder *chainRecorder) handler() *abelian.ChainHandlerFuncs {
+	return &abelian.ChainHandlerFuncs{
+		OnConnected: func(block *abelian.Block) error {
+			recorder.events = append(recorder.events, fmt.Sprintf("+%d", block.Height))
+			return nil
+		},
+		OnDisconnected: func(block *abelian.Block) error {
+			recorder.events = append(recorder.events, fmt.Sprintf("-%d", block.Height))
+			recorder.disconnected = append(recorder.disconnected, block)
+			return nil
+		},
+	}
+}
+
+// addCoinbaseBlocks adds n blocks with a serialized coinbase transaction to server, seeded by seed.
+func addCoinbaseBlocks(t *testing.T, server *abeliantest.Server, n int, seed int32) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		txHex, _ := testTx(t, seed+int32(i))
+		server.AddBlock(&abelian.Tx{Hex: txHex})
+	}
+}
+
+func newFollower(t *testing.T, client *abelian.Client, handler abelian.ChainHandler, options ...abelian.FollowerOption) *abelian.ChainFollower {
+	t.Helper()
+	follower, err := abelian.NewChainFollower(client, handler, abelian.NewFollowerConfig(options...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return follower
+}
+
+func TestFollowerReorg(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	addCoinbaseBlocks(t, server, 5, 1)
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	recorder := &chainRecorder{}
+	follower := newFollower(t, client, recorder.handler(), abelian.WithFollowerStartHeight(2))
+	if err := follower.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"+2", "+3", "+4", "+5"}; !reflect.DeepEqual(recorder.events, want) {
+		t.Fatalf("events = %v, want %v", recorder.events, want)
+	}
+
+	recorder.events = nil
+	orphaned := server.Reorg(2)
+	addCoinbaseBlocks(t, server, 3, 100)
+	if err := follower.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"-5", "-4", "+4", "+5", "+6"}; !reflect.DeepEqual(recorder.events, want) {
+		t.Fatalf("events = %v, want %v", recorder.events, want)
+	}
+	// the orphaned blocks are decoded from their serialized form
+	for i, block := range recorder.disconnected {
+		want := orphaned[len(orphaned)-1-i]
+		checkBlockTxs(t, block, want)
+		if block.Height != want.Height || block.PrevBlockHash != want.PrevBlockHash || block.Time != want.Time {
+			t.Errorf("disconnected block = %+v, want %+v", block, want)
+		}
+	}
+	if cursor := follower.Cursor(); cursor.Height != 6 || cursor.BlockHash != server.BlockByHeight(6).BlockHash {
+		t.Errorf("cursor = %+v, want the tip", cursor)
+	}
+}
+
+func TestFollowerRunAfterReorg(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	addCoinbaseBlocks(t, server, 3, 1)
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	stop := errors.New("stop")
+	disconnected := 0
+	handler := &abelian.ChainHandlerFuncs{
+		OnConnected: func(block *abelian.Block) error {
+			if block.Height == 3 && disconnected == 0 {
+				server.Reorg(1)
+				addCoinbaseBlocks(t, server, 2, 100)
+			}
+			if block.Height == 4 {
+				return stop
+			}
+			return nil
+		},
+		OnDisconnected: func(block *abelian.Block) error {
+			disconnected++
+			return nil
+		},
+	}
+	follower := newFollower(t, client, handler, abelian.WithFollowerStartHeight(1), abelian.WithFollowerPollInterval(10))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = follower.Run(ctx)
+	if !errors.Is(err, stop) {
+		t.Fatalf("Run error = %v, want the error of the handler", err)
+	}
+	if disconnected != 1 || follower.Cursor().Height != 3 {
+		t.Errorf("disconnected %d blocks, cursor at %d, want 1 block and height 3", disconnected, follower.Cursor().Height)
+	}
+}
+
+func TestFollowerResume(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	addCoinbaseBlocks(t, server, 3, 1)
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	store := abelian.NewFileCursorStore(filepath.Join(t.TempDir(), "cursor.json"))
+	first := &chainRecorder{}
+	if err := newFollower(t, client, first.handler(), abelian.WithFollowerStartHeight(1), abelian.WithFollowerStore(store)).Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// blocks connected before the restart are disconnected after it
+	server.Reorg(1)
+	addCoinbaseBlocks(t, server, 2, 100)
+	second := &chainRecorder{}
+	follower := newFollower(t, client, second.handler(), abelian.WithFollowerStartHeight(1), abelian.WithFollowerStore(store))
+	if cursor := follower.Cursor(); cursor == nil || cursor.Height != 3 {
+		t.Fatalf("loaded cursor = %+v, want height 3", cursor)
+	}
+	if err := follower.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"-3", "+3", "+4"}; !reflect.DeepEqual(second.events, want) {
+		t.Errorf("events = %v, want %v", second.events, want)
+	}
+}
+
+func TestFollowerReorgTooDeep(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	addCoinbaseBlocks(t, server, 4, 1)
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	recorder := &chainRecorder{}
+	store := abelian.NewFileCursorStore(filepath.Join(t.TempDir(), "cursor.json"))
+	follower := newFollower(t, client, recorder.handler(), abelian.WithFollowerStartHeight(1),
+		abelian.WithFollowerFinalityDepth(2), abelian.WithFollowerStore(store))
+	if err := follower.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := follower.Cursor()
+
+	server.Reorg(3)
+	addCoinbaseBlocks(t, server, 4, 100)
+	err = follower.Sync(context.Background())
+	if !errors.Is(err, abelian.ErrReorgTooDeep) {
+		t.Fatalf("Sync error = %v, want ErrReorgTooDeep", err)
+	}
+	// nothing is disconnected without a fork point
+	if want := []string{"+1", "+2", "+3", "+4"}; !reflect.DeepEqual(recorder.events, want) {
+		t.Errorf("events = %v, want %v", recorder.events, want)
+	}
+	if after := follower.Cursor(); !reflect.DeepEqual(after, cursor) {
+		t.Errorf("cursor = %+v after the error, want %+v", after, cursor)
+	}
+	if after, err := store.Load(); err != nil || !reflect.DeepEqual(after, saved) {
+		t.Errorf("saved cursor = %v, %v after the error, want %v", after, err, saved)
+	}
+}
+
+func TestFollowerDisconnectedBlockError(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	addCoinbaseBlocks(t, server, 3, 1)
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	recorder := &chainRecorder{}
+	follower := newFollower(t, client, recorder.handler(), abelian.WithFollowerStartHeight(1))
+	if err := follower.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	server.Reorg(1)
+	addCoinbaseBlocks(t, server, 1, 100)
+	server.InjectError("getblockabe", &abelian.RPCError{Code: abelian.RPC_ERR_INVALID_ADDRESS_OR_KEY, Message: "Block not found"}, 1)
+	if err := follower.Sync(context.Background()); !errors.Is(err, abelian.ErrBlockNotFound) {
+		t.Fatalf("Sync error = %v, want ErrBlockNotFound", err)
+	}
+	if err := follower.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"+1", "+2", "+3", "-3", "+3"}; !reflect.DeepEqual(recorder.events, want) {
+		t.Errorf("events = %v, want %v", recorder.events, want)
+	}
+}
@@ -0,0 +1,161 @@
+package abelian
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Limit caps the calls of one method class sent by a client to its node. A call over the limit
+// waits for its turn, or until its context is done, instead of failing.
+type Limit struct {
+	Rate        float64 // calls per second, 0 for no rate limit
+	Burst       int     // calls which may be sent at once after an idle period, at least 1
+	MaxInFlight int     // calls waiting for their response at the same time, 0 for no cap
+}
+
+// tokenBucket is a token bucket refilled at rate tokens per second up to burst tokens.
+type tokenBucket struct {
+	rate  float64
+	burst float64
+
+	mtx    sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	b := math.Max(float64(burst), 1)
+	return &tokenBucket{rate: rate, burst: b, tokens: b, last: time.Now()}
+}
+
+// reserve takes n tokens, possibly in advance, and returns how long to wait before using them.
+func (bucket *tokenBucket) reserve(n int) time.Duration {
+	bucket.mtx.Lock()
+	defer bucket.mtx.Unlock()
+	now := time.Now()
+	bucket.tokens = math.Min(bucket.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*bucket.rate)
+	bucket.last = now
+	bucket.tokens -= float64(n)
+	if bucket.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-bucket.tokens / bucket.rate * float64(time.Second))
+}
+
+// unreserve gives back n tokens reserved but not used.
+func (bucket *tokenBucket) unreserve(n int) {
+	bucket.mtx.Lock()
+	defer bucket.mtx.Unlock()
+	bucket.tokens = math.Min(bucket.burst, bucket.tokens+float64(n))
+}
+
+// wait takes n tokens, waiting for them until ctx is done.
+func (bucket *tokenBucket) wait(ctx context.Context, n int) error {
+	delay := bucket.reserve(n)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		bucket.unreserve(n)
+		return ctx.Err()
+	}
+}
+
+type classLimiter struct {
+	bucket *tokenBucket  // nil for no rate limit
+	slots  chan struct{} // nil for no in-flight cap
+}
+
+// limiter applies the limits of a client by method class.
+type limiter struct {
+	classes map[MethodClass]*classLimiter
+}
+
+func newLimiter(limits map[MethodClass]*Limit) *limiter {
+	classes := map[MethodClass]*classLimiter{}
+	for class, limit := range limits {
+		if limit == nil || (limit.Rate <= 0 && limit.MaxInFlight <= 0) {
+			continue
+		}
+		classLimiter := &classLimiter{}
+		if limit.Rate > 0 {
+			classLimiter.bucket = newTokenBucket(limit.Rate, limit.Burst)
+		}
+		if limit.MaxInFlight > 0 {
+			classLimiter.slots = make(chan struct{}, limit.MaxInFlight)
+		}
+		classes[class] = classLimiter
+	}
+	if len(classes) == 0 {
+		return nil
+	}
+	return &limiter{classes: classes}
+}
+
+// acquire waits for an in-flight slot of class, then for n calls of its rate, and returns the
+// function releasing the slot.
+func (limiter *limiter) acquire(ctx context.Context, class MethodClass, n int) (func(), error) {
+	classLimiter, ok := limiter.classes[class]
+	if !ok {
+		return func() {}, nil
+	}
+	release := func() {}
+	if classLimiter.slots != nil {
+		select {
+		case classLimiter.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		release = func() { <-classLimiter.slots }
+	}
+	if classLimiter.bucket != nil {
+		err := classLimiter.bucket.wait(ctx, n)
+		if err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// acquireBatch acquires one in-flight slot per class of the calls, and as many calls of their
+// rates as there are calls of the class. The returned function may be called several times.
+func (limiter *limiter) acquireBatch(ctx context.Context, methods []string) (func(), error) {
+	counts := map[MethodClass]int{}
+	for _, method := range methods {
+		counts[MethodClassOf(method)]++
+	}
+	// acquire in class order, so that concurrent batches can not hold each other's slots
+	classes := make([]MethodClass, 0, len(counts))
+	for class := range counts {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i] < classes[j] })
+
+	releases := make([]func(), 0, len(classes))
+	once := sync.Once{}
+	releaseAll := func() {
+		once.Do(func() {
+			for _, release := range releases {
+				release()
+			}
+		})
+	}
+	for _, class := range classes {
+		release, err := limiter.acquire(ctx, class, counts[class])
+		if err != nil {
+			releaseAll()
+			return nil, err
+		}
+		releases = append(releases, release)
+	}
+	return releaseAll, nil
+}
@@ -0,0 +1,129 @@
+package abelian_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+func TestLimitRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		class   abelian.MethodClass
+		minTime time.Duration
+		maxTime time.Duration
+	}{
+		{"limited class", abelian.MethodClassRead, 130 * time.Millisecond, time.Second},
+		{"other class", abelian.MethodClassBroadcast, 0, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := abeliantest.NewServer()
+			defer server.Close()
+			client, err := server.NewClient(abelian.WithLimit(tt.class, &abelian.Limit{Rate: 20, Burst: 1}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			// the first call takes the burst, the next ones wait 50ms each
+			start := time.Now()
+			for i := 0; i < 4; i++ {
+				if _, err := client.GetBlockCount(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if elapsed := time.Since(start); elapsed < tt.minTime || elapsed > tt.maxTime {
+				t.Errorf("4 calls took %v, want between %v and %v", elapsed, tt.minTime, tt.maxTime)
+			}
+		})
+	}
+}
+
+func TestLimitMaxInFlight(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	var inFlight, maxInFlight atomic.Int32
+	server.Handle("getblockcount", func(params []json.RawMessage) (any, *abelian.RPCError) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			max := maxInFlight.Load()
+			if n <= max || maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return 0, nil
+	})
+	client, err := server.NewClient(abelian.WithLimit(abelian.MethodClassRead, &abelian.Limit{MaxInFlight: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetBlockCount(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if max := maxInFlight.Load(); max != 2 {
+		t.Errorf("at most %d calls in flight, want 2", max)
+	}
+}
+
+func TestLimitWaitCanceled(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	client, err := server.NewClient(abelian.WithLimit(abelian.MethodClassRead, &abelian.Limit{Rate: 0.1, Burst: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if _, err := client.GetBlockCount(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.GetBlockCountCtx(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetBlockCount error = %v, want context.DeadlineExceeded", err)
+	}
+	if calls := server.CallCount("getblockcount"); calls != 1 {
+		t.Errorf("getblockcount calls = %d, want 1", calls)
+	}
+}
+
+func TestLimitBatch(t *testing.T) {
+	server := abeliantest.NewServer(abeliantest.WithBatch())
+	defer server.Close()
+	client, err := server.NewClient(abelian.WithLimit(abelian.MethodClassRead, &abelian.Limit{Rate: 20, Burst: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// a batch takes one token per call
+	start := time.Now()
+	_, err = client.Batch().GetBlockHash(0).GetBlockHash(0).GetBlockHash(0).GetBlockHash(0).Send(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 130*time.Millisecond {
+		t.Errorf("batch of 4 calls took %v, want at least 130ms", elapsed)
+	}
+}
@@ -1,27 +1,127 @@
 package abelian
 
-import "encoding/hex"
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/crypto"
+)
 
 func (client *Client) GetChainInfo() (res *ChainInfo, err error) {
-	err = client.Do("getinfo", nil, &res)
+	return client.GetChainInfoCtx(context.Background())
+}
+func (client *Client) GetChainInfoCtx(ctx context.Context) (res *ChainInfo, err error) {
+	err = client.DoCtx(ctx, "getinfo", nil, &res)
+	if err == nil && res != nil {
+		client.observeNode(res)
+		if client.cache != nil {
+			client.cache.observeTip(res.NumBlocks)
+		}
+	}
 	return res, err
 }
 
 func (client *Client) GetRawMempool() (res []string, err error) {
-	err = client.Do("getrawmempool", []interface{}{false}, &res)
+	return client.GetRawMempoolCtx(context.Background())
+}
+func (client *Client) GetRawMempoolCtx(ctx context.Context) (res []string, err error) {
+	err = client.DoCtx(ctx, "getrawmempool", []interface{}{false}, &res)
 	return res, err
 }
+
 func (client *Client) GetBlockHash(height int64) (res string, err error) {
-	err = client.Do("getblockhash", []interface{}{height}, &res)
+	return client.GetBlockHashCtx(context.Background(), height)
+}
+func (client *Client) GetBlockHashCtx(ctx context.Context, height int64) (res string, err error) {
+	err = client.doCached(ctx, blockHashCacheKey(height), "getblockhash", []interface{}{height}, &res, func(raw json.RawMessage) bool {
+		return client.cache.isDeepHeight(height)
+	})
 	return res, err
 }
+
 func (client *Client) GetBlock(blockID string) (res *Block, err error) {
-	err = client.Do("getblockabe", []interface{}{blockID, 1}, &res)
+	return client.GetBlockCtx(context.Background(), blockID)
+}
+func (client *Client) GetBlockCtx(ctx context.Context, blockID string) (res *Block, err error) {
+	err = client.doCached(ctx, hashCacheKey("block", blockID), "getblockabe", []interface{}{blockID, 1}, &res, nil)
+	if err == nil && client.cache != nil {
+		client.cache.observeBlock(res)
+	}
 	return res, err
 }
+
+// GetBlockWithTxs returns the block with its transactions in RawTxs. They come with the block at
+// verbosity 2, and are fetched in a batch if the node answers with their hashes only.
+func (client *Client) GetBlockWithTxs(blockID string) (res *Block, err error) {
+	return client.GetBlockWithTxsCtx(context.Background(), blockID)
+}
+func (client *Client) GetBlockWithTxsCtx(ctx context.Context, blockID string) (res *Block, err error) {
+	err = client.doCached(ctx, hashCacheKey("blocktxs", blockID), "getblockabe", []interface{}{blockID, 2}, &res, func(raw json.RawMessage) bool {
+		var block struct {
+			TxHashes []string          `json:"tx"`
+			RawTxs   []json.RawMessage `json:"rawTx"`
+		}
+		return json.Unmarshal(raw, &block) == nil && (len(block.RawTxs) > 0 || len(block.TxHashes) == 0)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if client.cache != nil {
+		client.cache.observeBlock(res)
+	}
+	if res == nil {
+		return res, nil
+	}
+	// abec sends the transactions at verbosity 2 without the list of their hashes, which a node
+	// without rawTx sends alone as at verbosity 1
+	if len(res.RawTxs) > 0 || len(res.TxHashes) == 0 {
+		if len(res.TxHashes) == 0 {
+			res.TxHashes = make([]string, 0, len(res.RawTxs))
+			for _, tx := range res.RawTxs {
+				res.TxHashes = append(res.TxHashes, tx.TxID)
+			}
+		}
+		return res, nil
+	}
+	err = client.fetchBlockTxs(ctx, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// fetchBlockTxs sets the RawTxs of block, fetched in a batch.
+func (client *Client) fetchBlockTxs(ctx context.Context, block *Block) error {
+	batch := client.Batch()
+	for _, txHash := range block.TxHashes {
+		batch.GetRawTx(txHash)
+	}
+	results, err := batch.Send(ctx)
+	if err != nil {
+		return err
+	}
+	rawTxs := make([]*Tx, 0, len(results))
+	for _, result := range results {
+		if result.Err != nil {
+			return result.Err
+		}
+		rawTxs = append(rawTxs, result.Result.(*Tx))
+	}
+	block.RawTxs = rawTxs
+	return nil
+}
+
 func (client *Client) GetBlockBytes(blockID string) (res []byte, err error) {
+	return client.GetBlockBytesCtx(context.Background(), blockID)
+}
+func (client *Client) GetBlockBytesCtx(ctx context.Context, blockID string) (res []byte, err error) {
+	if client.cache == nil {
+		return client.streamHexBytes(ctx, "getblockabe", []interface{}{blockID, 0})
+	}
 	var blockHex string
-	err = client.Do("getblockabe", []interface{}{blockID, 0}, &blockHex)
+	err = client.doCached(ctx, hashCacheKey("blockbytes", blockID), "getblockabe", []interface{}{blockID, 0}, &blockHex, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -29,8 +129,14 @@ func (client *Client) GetBlockBytes(blockID string) (res []byte, err error) {
 }
 
 func (client *Client) GetTxBytes(txID string) (res []byte, err error) {
+	return client.GetTxBytesCtx(context.Background(), txID)
+}
+func (client *Client) GetTxBytesCtx(ctx context.Context, txID string) (res []byte, err error) {
+	if client.cache == nil {
+		return client.streamHexBytes(ctx, "getrawtransaction", []interface{}{txID, false})
+	}
 	var txHex string
-	err = client.Do("getrawtransaction", []interface{}{txID, false}, &txHex)
+	err = client.doCached(ctx, hashCacheKey("txbytes", txID), "getrawtransaction", []interface{}{txID, false}, &txHex, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -38,29 +144,158 @@ func (client *Client) GetTxBytes(txID string) (res []byte, err error) {
 }
 
 func (client *Client) GetRawTx(txID string) (res *Tx, err error) {
-	err = client.Do("getrawtransaction", []interface{}{txID, true}, &res)
+	return client.GetRawTxCtx(context.Background(), txID)
+}
+func (client *Client) GetRawTxCtx(ctx context.Context, txID string) (res *Tx, err error) {
+	err = client.doCached(ctx, hashCacheKey("tx", txID), "getrawtransaction", []interface{}{txID, true}, &res, func(raw json.RawMessage) bool {
+		return client.cache.isDeepTx(raw)
+	})
 	return res, err
 }
 
 func (client *Client) GetBlockByHeight(height int64) (res *Block, err error) {
-	blockID, err := client.GetBlockHash(height)
+	return client.GetBlockByHeightCtx(context.Background(), height)
+}
+func (client *Client) GetBlockByHeightCtx(ctx context.Context, height int64) (res *Block, err error) {
+	blockID, err := client.GetBlockHashCtx(ctx, height)
 	if err != nil {
 		return nil, err
 	}
 
-	return client.GetBlock(blockID)
+	return client.GetBlockCtx(ctx, blockID)
 }
 
 func (client *Client) GetBlockBytesByHeight(height int64) (res []byte, err error) {
-	blockID, err := client.GetBlockHash(height)
+	return client.GetBlockBytesByHeightCtx(context.Background(), height)
+}
+func (client *Client) GetBlockBytesByHeightCtx(ctx context.Context, height int64) (res []byte, err error) {
+	blockID, err := client.GetBlockHashCtx(ctx, height)
 	if err != nil {
 		return nil, err
 	}
 
-	return client.GetBlockBytes(blockID)
+	return client.GetBlockBytesCtx(ctx, blockID)
 }
 
+// SendRawTx broadcasts the transaction in hex and returns its id. A transaction the node already has,
+// e.g. sent by an earlier attempt whose answer was lost, is a success: its id is computed locally.
+// Broadcasts are not retried by the RetryPolicy. If the client has an expected network, the node is
+// checked to be on it first, and *NetworkMismatchError is returned otherwise.
 func (client *Client) SendRawTx(rawTx string) (res string, err error) {
-	err = client.Do("sendrawtransactionabe", []interface{}{rawTx}, &res)
+	return client.SendRawTxCtx(context.Background(), rawTx)
+}
+func (client *Client) SendRawTxCtx(ctx context.Context, rawTx string) (res string, err error) {
+	if client.pool != nil {
+		// the network is checked on the endpoint which broadcasts
+		err = client.doPool(ctx, []string{"sendrawtransactionabe"}, func(ep *endpoint) error {
+			var err error
+			res, err = ep.client.SendRawTxCtx(ctx, rawTx)
+			return err
+		})
+		return res, err
+	}
+	err = client.checkNetwork(ctx)
+	if err != nil {
+		return "", err
+	}
+	err = client.DoCtx(ctx, "sendrawtransactionabe", []interface{}{rawTx}, &res)
+	if errors.Is(err, ErrTxAlreadyKnown) {
+		txID, idErr := txIDOfHex(rawTx)
+		if idErr != nil {
+			sdkLog.Warnf("fail to compute id of transaction already known by the node: %v", idErr)
+			return "", err
+		}
+		sdkLog.Infof("transaction %s is already known by the node", txID)
+		return txID, nil
+	}
+	return res, err
+}
+
+func txIDOfHex(rawTx string) (string, error) {
+	txBytes, err := hex.DecodeString(rawTx)
+	if err != nil {
+		return "", err
+	}
+	return crypto.GetTxID(txBytes)
+}
+
+func (client *Client) GetBestBlockHash() (res string, err error) {
+	return client.GetBestBlockHashCtx(context.Background())
+}
+func (client *Client) GetBestBlockHashCtx(ctx context.Context) (res string, err error) {
+	err = client.DoCtx(ctx, "getbestblockhash", nil, &res)
+	return res, err
+}
+
+func (client *Client) GetBlockCount() (res int64, err error) {
+	return client.GetBlockCountCtx(context.Background())
+}
+func (client *Client) GetBlockCountCtx(ctx context.Context) (res int64, err error) {
+	err = client.DoCtx(ctx, "getblockcount", nil, &res)
+	if err == nil && client.cache != nil {
+		client.cache.observeTip(res)
+	}
+	return res, err
+}
+
+func (client *Client) GetBlockHeader(blockID string) (res *BlockHeader, err error) {
+	return client.GetBlockHeaderCtx(context.Background(), blockID)
+}
+func (client *Client) GetBlockHeaderCtx(ctx context.Context, blockID string) (res *BlockHeader, err error) {
+	err = client.DoCtx(ctx, "getblockheader", []interface{}{blockID, true}, &res)
+	return res, err
+}
+
+func (client *Client) GetBlockHeaderBytes(blockID string) (res []byte, err error) {
+	return client.GetBlockHeaderBytesCtx(context.Background(), blockID)
+}
+func (client *Client) GetBlockHeaderBytesCtx(ctx context.Context, blockID string) (res []byte, err error) {
+	var headerHex string
+	err = client.DoCtx(ctx, "getblockheader", []interface{}{blockID, false}, &headerHex)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(headerHex)
+}
+
+func (client *Client) GetBlockChainInfo() (res *BlockChainInfo, err error) {
+	return client.GetBlockChainInfoCtx(context.Background())
+}
+func (client *Client) GetBlockChainInfoCtx(ctx context.Context) (res *BlockChainInfo, err error) {
+	err = client.DoCtx(ctx, "getblockchaininfo", nil, &res)
+	return res, err
+}
+
+func (client *Client) GetMempoolInfo() (res *MempoolInfo, err error) {
+	return client.GetMempoolInfoCtx(context.Background())
+}
+func (client *Client) GetMempoolInfoCtx(ctx context.Context) (res *MempoolInfo, err error) {
+	err = client.DoCtx(ctx, "getmempoolinfo", nil, &res)
+	return res, err
+}
+
+// GetRawMempoolVerbose returns the transactions of the mempool by id.
+func (client *Client) GetRawMempoolVerbose() (res map[string]*MempoolTx, err error) {
+	return client.GetRawMempoolVerboseCtx(context.Background())
+}
+func (client *Client) GetRawMempoolVerboseCtx(ctx context.Context) (res map[string]*MempoolTx, err error) {
+	err = client.DoCtx(ctx, "getrawmempool", []interface{}{true}, &res)
+	return res, err
+}
+
+func (client *Client) GetDifficulty() (res float64, err error) {
+	return client.GetDifficultyCtx(context.Background())
+}
+func (client *Client) GetDifficultyCtx(ctx context.Context) (res float64, err error) {
+	err = client.DoCtx(ctx, "getdifficulty", nil, &res)
+	return res, err
+}
+
+// GetUtxoRing returns the outputs of the TXO ring and which of them are consumed.
+func (client *Client) GetUtxoRing(ring *TXORing) (res *UtxoRing, err error) {
+	return client.GetUtxoRingCtx(context.Background(), ring)
+}
+func (client *Client) GetUtxoRingCtx(ctx context.Context, ring *TXORing) (res *UtxoRing, err error) {
+	err = client.DoCtx(ctx, "getutxoring", []interface{}{ring}, &res)
 	return res, err
 }
@@ -0,0 +1,158 @@
+package abelian_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pqabelian/abec/wire"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+func TestChainQueries(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	for _, txHex := range []string{"cb01", "cb02", "cb03"} {
+		server.AddBlock(&abelian.Tx{Hex: txHex})
+	}
+	first := &abelian.Tx{Hex: "aa01", Size: 100, FullSize: 300, Fee: 1000, Time: 1700000000}
+	second := &abelian.Tx{Hex: "aa02", Size: 200, FullSize: 500, Fee: 3000, Time: 1700000100}
+	server.AddMempoolTx(first)
+	server.AddMempoolTx(second)
+	ring := &abelian.UtxoRing{
+		RingBlockHeight: 3,
+		TXORing: abelian.TXORing{
+			Version:     1,
+			BlockHashes: []string{server.BlockByHeight(1).BlockHash, server.BlockByHeight(2).BlockHash, server.BlockByHeight(3).BlockHash},
+			OutPoints:   []abelian.OutPoint{{TxHash: server.BlockByHeight(1).TxHashes[0], Index: 0}},
+		},
+		TxOuts:     []*abelian.TxVout{{N: 0, Script: "00ff"}},
+		IsCoinbase: true,
+	}
+	server.AddUtxoRing(ring)
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	block, tip := server.BlockByHeight(2), server.BlockByHeight(3)
+	tests := []struct {
+		name     string
+		call     func() (any, error)
+		want     any
+		wantErr  error
+		wantCode int
+	}{
+		{
+			name: "GetBestBlockHash",
+			call: func() (any, error) { return client.GetBestBlockHash() },
+			want: tip.BlockHash,
+		},
+		{
+			name: "GetBlockCount",
+			call: func() (any, error) { return client.GetBlockCount() },
+			want: int64(3),
+		},
+		{
+			name: "GetBlockHeader",
+			call: func() (any, error) { return client.GetBlockHeader(block.BlockHash) },
+			want: &abelian.BlockHeader{
+				BlockHash:     block.BlockHash,
+				Confirmations: 2,
+				Height:        2,
+				Version:       block.Version,
+				VersionHex:    block.VersionHex,
+				Time:          block.Time,
+				Bits:          block.Bits,
+				Difficulty:    block.Difficulty,
+				PrevBlockHash: block.PrevBlockHash,
+				NextBlockHash: tip.BlockHash,
+			},
+		},
+		{
+			name:    "GetBlockHeader of an unknown block",
+			call:    func() (any, error) { return client.GetBlockHeader(strings.Repeat("00", 32)) },
+			wantErr: abelian.ErrBlockNotFound,
+		},
+		{
+			name: "GetBlockHeaderBytes",
+			call: func() (any, error) {
+				headerBytes, err := client.GetBlockHeaderBytes(block.BlockHash)
+				if err != nil {
+					return nil, err
+				}
+				header := &wire.BlockHeader{}
+				if err := header.Deserialize(bytes.NewReader(headerBytes)); err != nil {
+					return nil, err
+				}
+				return []any{header.PrevBlock.String(), header.Timestamp.Unix()}, nil
+			},
+			want: []any{block.PrevBlockHash, block.Time},
+		},
+		{
+			name: "GetBlockChainInfo",
+			call: func() (any, error) { return client.GetBlockChainInfo() },
+			want: &abelian.BlockChainInfo{
+				Chain:         "mainnet",
+				Blocks:        3,
+				Headers:       3,
+				BestBlockHash: tip.BlockHash,
+				Difficulty:    tip.Difficulty,
+				MedianTime:    tip.Time,
+			},
+		},
+		{
+			name: "GetMempoolInfo",
+			call: func() (any, error) { return client.GetMempoolInfo() },
+			want: &abelian.MempoolInfo{Size: 2, Bytes: 300},
+		},
+		{
+			name: "GetRawMempoolVerbose",
+			call: func() (any, error) { return client.GetRawMempoolVerbose() },
+			want: map[string]*abelian.MempoolTx{
+				first.TxID:  {Size: 100, FullSize: 300, Fee: 1000, Time: 1700000000, Height: 3},
+				second.TxID: {Size: 200, FullSize: 500, Fee: 3000, Time: 1700000100, Height: 3},
+			},
+		},
+		{
+			name: "GetDifficulty",
+			call: func() (any, error) { return client.GetDifficulty() },
+			want: tip.Difficulty,
+		},
+		{
+			name: "GetUtxoRing",
+			call: func() (any, error) { return client.GetUtxoRing(&ring.TXORing) },
+			want: ring,
+		},
+		{
+			name:     "GetUtxoRing of an unknown ring",
+			call:     func() (any, error) { return client.GetUtxoRing(&abelian.TXORing{Version: 1}) },
+			wantCode: abelian.RPC_ERR_UTXO_RING_NO_INFO,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := tt.call()
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantCode != 0:
+				var rpcErr *abelian.RPCError
+				if !errors.As(err, &rpcErr) || rpcErr.Code != tt.wantCode {
+					t.Errorf("error = %v, want RPC error %d", err, tt.wantCode)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case !reflect.DeepEqual(res, tt.want):
+				t.Errorf("result = %+v, want %+v", res, tt.want)
+			}
+		})
+	}
+}
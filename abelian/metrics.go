@@ -0,0 +1,349 @@
+package abelian
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrorClass groups the errors of the calls for the metrics.
+type ErrorClass int
+
+const (
+	ErrorClassRPC      ErrorClass = iota // JSON-RPC error answered by the node
+	ErrorClassHTTP                       // non-200 http status
+	ErrorClassTimeout                    // deadline exceeded
+	ErrorClassCanceled                   // context canceled by the caller
+	ErrorClassNetwork                    // connection failure
+	ErrorClassOther                      // e.g. malformed response
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassRPC:
+		return "rpc"
+	case ErrorClassHTTP:
+		return "http"
+	case ErrorClassTimeout:
+		return "timeout"
+	case ErrorClassCanceled:
+		return "canceled"
+	case ErrorClassNetwork:
+		return "network"
+	default:
+		return "other"
+	}
+}
+
+// ErrorClassOf returns the class of a non-nil error returned by a call.
+func ErrorClassOf(err error) ErrorClass {
+	var rpcErr *RPCError
+	var httpErr *HTTPError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &rpcErr):
+		return ErrorClassRPC
+	case errors.As(err, &httpErr):
+		return ErrorClassHTTP
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.As(err, &netErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorClassNetwork
+	default:
+		return ErrorClassOther
+	}
+}
+
+// MetricsRecorder receives the measurements of the calls of a client, see WithMetrics.
+// Each attempt of a retried call is measured on its own, and so is each call of a batch request,
+// with the duration of the whole request.
+type MetricsRecorder interface {
+	CallStarted(method string)
+	// CallFinished is called once per CallStarted, with the size in bytes of the result, 0 on error.
+	CallFinished(method string, duration time.Duration, resultSize int, err error)
+}
+
+// metricsMiddleware reports the calls to recorder.
+func metricsMiddleware(recorder MetricsRecorder) Middleware {
+	return func(next Invoker) Invoker {
+		return func(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+			if method == BATCH_METHOD {
+				return recordBatch(ctx, recorder, next, params)
+			}
+			recorder.CallStarted(method)
+			start := time.Now()
+			result, err := next(ctx, method, params)
+			size := len(result)
+			if stream := hexStreamOf(ctx, method); stream != nil && stream.streamed {
+				size = int(stream.written)
+			}
+			recorder.CallFinished(method, time.Since(start), size, err)
+			return result, err
+		}
+	}
+}
+
+// recordBatch reports each call of a batch request to recorder.
+func recordBatch(ctx context.Context, recorder MetricsRecorder, next Invoker, params []interface{}) (json.RawMessage, error) {
+	jsonReqs := make([]*JSONRPCRequest, 0, len(params))
+	for _, param := range params {
+		if jsonReq, ok := param.(*JSONRPCRequest); ok {
+			jsonReqs = append(jsonReqs, jsonReq)
+			recorder.CallStarted(jsonReq.Method)
+		}
+	}
+	start := time.Now()
+	result, err := next(ctx, BATCH_METHOD, params)
+	duration := time.Since(start)
+
+	callErr := err
+	var respObjs []*JSONRPCResponse
+	if callErr == nil {
+		callErr = json.Unmarshal(result, &respObjs)
+	}
+	id2Resp := make(map[string]*JSONRPCResponse, len(respObjs))
+	for _, respObj := range respObjs {
+		id2Resp[respObj.ID] = respObj
+	}
+	for _, jsonReq := range jsonReqs {
+		respObj := id2Resp[jsonReq.ID]
+		switch {
+		case callErr != nil:
+			recorder.CallFinished(jsonReq.Method, duration, 0, callErr)
+		case respObj == nil:
+			recorder.CallFinished(jsonReq.Method, duration, 0, fmt.Errorf("no response for request %s in batch response", jsonReq.ID))
+		case respObj.Error != nil:
+			recorder.CallFinished(jsonReq.Method, duration, 0, respObj.Error)
+		default:
+			recorder.CallFinished(jsonReq.Method, duration, len(respObj.Result), nil)
+		}
+	}
+	return result, err
+}
+
+var (
+	// seconds
+	defaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+	// bytes, up to the size of the largest blocks
+	defaultSizeBuckets = []float64{1 << 8, 1 << 10, 1 << 12, 1 << 14, 1 << 16, 1 << 18, 1 << 20, 1 << 22, 1 << 24, 1 << 26}
+)
+
+// Metrics is a MetricsRecorder keeping per-method counters and histograms in memory.
+// It serves them in the Prometheus text exposition format as a http.Handler:
+//
+//	metrics := abelian.NewMetrics()
+//	client, _ := abelian.NewClient(abelian.NewClientConfig(endpoint, abelian.WithMetrics(metrics)))
+//	http.Handle("/metrics", metrics)
+//
+// The same Metrics may be shared by several clients, e.g. the endpoints of a multi-endpoint client.
+type Metrics struct {
+	latencyBuckets []float64
+	sizeBuckets    []float64
+
+	mtx     sync.Mutex
+	methods map[string]*methodMetrics
+}
+
+type methodMetrics struct {
+	requests uint64
+	errors   map[ErrorClass]uint64
+	inFlight int64
+	latency  *histogram
+	size     *histogram
+}
+
+type histogram struct {
+	bounds []float64
+	counts []uint64 // per bucket, the last one for values above all bounds
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(value float64) {
+	h.counts[sort.SearchFloat64s(h.bounds, value)]++
+	h.sum += value
+	h.count++
+}
+
+func (h *histogram) snapshot() *HistogramSnapshot {
+	snapshot := &HistogramSnapshot{
+		Bounds: h.bounds,
+		Counts: make([]uint64, len(h.bounds)),
+		Sum:    h.sum,
+		Count:  h.count,
+	}
+	cumulative := uint64(0)
+	for i := range h.bounds {
+		cumulative += h.counts[i]
+		snapshot.Counts[i] = cumulative
+	}
+	return snapshot
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		latencyBuckets: defaultLatencyBuckets,
+		sizeBuckets:    defaultSizeBuckets,
+		methods:        map[string]*methodMetrics{},
+	}
+}
+
+// method returns the metrics of method, creating them if needed. mtx must be held.
+func (metrics *Metrics) method(method string) *methodMetrics {
+	m, ok := metrics.methods[method]
+	if !ok {
+		m = &methodMetrics{
+			errors:  map[ErrorClass]uint64{},
+			latency: newHistogram(metrics.latencyBuckets),
+			size:    newHistogram(metrics.sizeBuckets),
+		}
+		metrics.methods[method] = m
+	}
+	return m
+}
+
+func (metrics *Metrics) CallStarted(method string) {
+	metrics.mtx.Lock()
+	defer metrics.mtx.Unlock()
+	metrics.method(method).inFlight++
+}
+
+func (metrics *Metrics) CallFinished(method string, duration time.Duration, resultSize int, err error) {
+	metrics.mtx.Lock()
+	defer metrics.mtx.Unlock()
+	m := metrics.method(method)
+	m.inFlight--
+	m.requests++
+	m.latency.observe(duration.Seconds())
+	if err != nil {
+		m.errors[ErrorClassOf(err)]++
+		return
+	}
+	m.size.observe(float64(resultSize))
+}
+
+// HistogramSnapshot is a histogram with cumulative counts, Counts[i] being the number of values <= Bounds[i].
+type HistogramSnapshot struct {
+	Bounds []float64
+	Counts []uint64
+	Sum    float64
+	Count  uint64
+}
+
+// MethodMetrics are the measurements of one method.
+type MethodMetrics struct {
+	Requests   uint64
+	Errors     map[ErrorClass]uint64
+	InFlight   int64
+	Latency    *HistogramSnapshot // seconds
+	ResultSize *HistogramSnapshot // bytes, successful calls only
+}
+
+// Snapshot returns the current measurements by method.
+func (metrics *Metrics) Snapshot() map[string]*MethodMetrics {
+	metrics.mtx.Lock()
+	defer metrics.mtx.Unlock()
+	snapshot := make(map[string]*MethodMetrics, len(metrics.methods))
+	for method, m := range metrics.methods {
+		errs := make(map[ErrorClass]uint64, len(m.errors))
+		for class, count := range m.errors {
+			errs[class] = count
+		}
+		snapshot[method] = &MethodMetrics{
+			Requests:   m.requests,
+			Errors:     errs,
+			InFlight:   m.inFlight,
+			Latency:    m.latency.snapshot(),
+			ResultSize: m.size.snapshot(),
+		}
+	}
+	return snapshot
+}
+
+// ServeHTTP writes the measurements in the Prometheus text exposition format.
+func (metrics *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, err := io.WriteString(w, metrics.exposition())
+	if err != nil {
+		sdkLog.Warnf("fail to write metrics: %v", err)
+	}
+}
+
+func (metrics *Metrics) exposition() string {
+	snapshot := metrics.Snapshot()
+	methods := make([]string, 0, len(snapshot))
+	for method := range snapshot {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	sb := &strings.Builder{}
+	sb.WriteString("# HELP abelian_rpc_requests_total Requests sent to abec.\n")
+	sb.WriteString("# TYPE abelian_rpc_requests_total counter\n")
+	for _, method := range methods {
+		fmt.Fprintf(sb, "abelian_rpc_requests_total{method=%s} %d\n", quoteLabel(method), snapshot[method].Requests)
+	}
+
+	sb.WriteString("# HELP abelian_rpc_errors_total Failed requests by error class.\n")
+	sb.WriteString("# TYPE abelian_rpc_errors_total counter\n")
+	for _, method := range methods {
+		errs := snapshot[method].Errors
+		for class := ErrorClassRPC; class <= ErrorClassOther; class++ {
+			if count, ok := errs[class]; ok {
+				fmt.Fprintf(sb, "abelian_rpc_errors_total{method=%s,class=%q} %d\n", quoteLabel(method), class.String(), count)
+			}
+		}
+	}
+
+	sb.WriteString("# HELP abelian_rpc_in_flight_requests Requests waiting for their response.\n")
+	sb.WriteString("# TYPE abelian_rpc_in_flight_requests gauge\n")
+	for _, method := range methods {
+		fmt.Fprintf(sb, "abelian_rpc_in_flight_requests{method=%s} %d\n", quoteLabel(method), snapshot[method].InFlight)
+	}
+
+	sb.WriteString("# HELP abelian_rpc_request_duration_seconds Latency of the requests.\n")
+	sb.WriteString("# TYPE abelian_rpc_request_duration_seconds histogram\n")
+	for _, method := range methods {
+		writeHistogram(sb, "abelian_rpc_request_duration_seconds", method, snapshot[method].Latency)
+	}
+
+	sb.WriteString("# HELP abelian_rpc_result_size_bytes Size of the results of the successful requests.\n")
+	sb.WriteString("# TYPE abelian_rpc_result_size_bytes histogram\n")
+	for _, method := range methods {
+		writeHistogram(sb, "abelian_rpc_result_size_bytes", method, snapshot[method].ResultSize)
+	}
+	return sb.String()
+}
+
+func writeHistogram(sb *strings.Builder, name string, method string, h *HistogramSnapshot) {
+	label := quoteLabel(method)
+	for i, bound := range h.Bounds {
+		fmt.Fprintf(sb, "%s_bucket{method=%s,le=%q} %d\n", name, label, strconv.FormatFloat(bound, 'f', -1, 64), h.Counts[i])
+	}
+	fmt.Fprintf(sb, "%s_bucket{method=%s,le=\"+Inf\"} %d\n", name, label, h.Count)
+	fmt.Fprintf(sb, "%s_sum{method=%s} %s\n", name, label, strconv.FormatFloat(h.Sum, 'g', -1, 64))
+	fmt.Fprintf(sb, "%s_count{method=%s} %d\n", name, label, h.Count)
+}
+
+// quoteLabel quotes a label value with the escaping of the Prometheus text format.
+func quoteLabel(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return `"` + value + `"`
+}
@@ -0,0 +1,109 @@
+package abelian_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+)
+
+const wantExposition = `# HELP abelian_rpc_requests_total Requests sent to abec.
+# TYPE abelian_rpc_requests_total counter
+abelian_rpc_requests_total{method="get\"odd\\method\n"} 1
+abelian_rpc_requests_total{method="getblockcount"} 2
+# HELP abelian_rpc_errors_total Failed requests by error class.
+# TYPE abelian_rpc_errors_total counter
+abelian_rpc_errors_total{method="getblockcount",class="rpc"} 1
+# HELP abelian_rpc_in_flight_requests Requests waiting for their response.
+# TYPE abelian_rpc_in_flight_requests gauge
+abelian_rpc_in_flight_requests{method="get\"odd\\method\n"} 1
+abelian_rpc_in_flight_requests{method="getblockcount"} 0
+# HELP abelian_rpc_request_duration_seconds Latency of the requests.
+# TYPE abelian_rpc_request_duration_seconds histogram
+abelian_rpc_request_duration_seconds_bucket{method="get\"odd\\method\n",le="0.005"} 0
+abelian_rpc_request_duration_seconds_bucket{method="get\"odd\\method\n",le="0.01"} 0
+abelian_rpc_request_duration_seconds_bucket{method="get\"odd\\method\n",le="0.025"} 0
+abelian_rpc_request_duration_seconds_bucket{method="get\"odd\\method\n",le="0.05"} 0
+abelian_rpc_request_duration_seconds_bucket{method="get\"odd\\method\n",le="0.1"} 0
+abelian_rpc_request_duration_seconds_bucket{method="get\"odd\\method\n",le="0.25"} 0
+abelian_rpc_request_duration_seconds_bucket{method="get\"odd\\method\n",le="0.5"} 0
+abelian_rpc_request_duration_seconds_bucket{method="get\"odd\\method\n",le="1"} 0
+abelian_rpc_request_duration_seconds_bucket{method="get\"odd\\method\n",le="2.5"} 1
+abelian_rpc_request_duration_seconds_bucket{method="get\"odd\\method\n",le="5"} 1
+abelian_rpc_request_duration_seconds_bucket{method="get\"odd\\method\n",le="10"} 1
+abelian_rpc_request_duration_seconds_bucket{method="get\"odd\\method\n",le="30"} 1
+abelian_rpc_request_duration_seconds_bucket{method="get\"odd\\method\n",le="60"} 1
+abelian_rpc_request_duration_seconds_bucket{method="get\"odd\\method\n",le="+Inf"} 1
+abelian_rpc_request_duration_seconds_sum{method="get\"odd\\method\n"} 2
+abelian_rpc_request_duration_seconds_count{method="get\"odd\\method\n"} 1
+abelian_rpc_request_duration_seconds_bucket{method="getblockcount",le="0.005"} 0
+abelian_rpc_request_duration_seconds_bucket{method="getblockcount",le="0.01"} 1
+abelian_rpc_request_duration_seconds_bucket{method="getblockcount",le="0.025"} 1
+abelian_rpc_request_duration_seconds_bucket{method="getblockcount",le="0.05"} 1
+abelian_rpc_request_duration_seconds_bucket{method="getblockcount",le="0.1"} 1
+abelian_rpc_request_duration_seconds_bucket{method="getblockcount",le="0.25"} 2
+abelian_rpc_request_duration_seconds_bucket{method="getblockcount",le="0.5"} 2
+abelian_rpc_request_duration_seconds_bucket{method="getblockcount",le="1"} 2
+abelian_rpc_request_duration_seconds_bucket{method="getblockcount",le="2.5"} 2
+abelian_rpc_request_duration_seconds_bucket{method="getblockcount",le="5"} 2
+abelian_rpc_request_duration_seconds_bucket{method="getblockcount",le="10"} 2
+abelian_rpc_request_duration_seconds_bucket{method="getblockcount",le="30"} 2
+abelian_rpc_request_duration_seconds_bucket{method="getblockcount",le="60"} 2
+abelian_rpc_request_duration_seconds_bucket{method="getblockcount",le="+Inf"} 2
+abelian_rpc_request_duration_seconds_sum{method="getblockcount"} 0.2578125
+abelian_rpc_request_duration_seconds_count{method="getblockcount"} 2
+# HELP abelian_rpc_result_size_bytes Size of the results of the successful requests.
+# TYPE abelian_rpc_result_size_bytes histogram
+abelian_rpc_result_size_bytes_bucket{method="get\"odd\\method\n",le="256"} 0
+abelian_rpc_result_size_bytes_bucket{method="get\"odd\\method\n",le="1024"} 0
+abelian_rpc_result_size_bytes_bucket{method="get\"odd\\method\n",le="4096"} 1
+abelian_rpc_result_size_bytes_bucket{method="get\"odd\\method\n",le="16384"} 1
+abelian_rpc_result_size_bytes_bucket{method="get\"odd\\method\n",le="65536"} 1
+abelian_rpc_result_size_bytes_bucket{method="get\"odd\\method\n",le="262144"} 1
+abelian_rpc_result_size_bytes_bucket{method="get\"odd\\method\n",le="1048576"} 1
+abelian_rpc_result_size_bytes_bucket{method="get\"odd\\method\n",le="4194304"} 1
+abelian_rpc_result_size_bytes_bucket{method="get\"odd\\method\n",le="16777216"} 1
+abelian_rpc_result_size_bytes_bucket{method="get\"odd\\method\n",le="67108864"} 1
+abelian_rpc_result_size_bytes_bucket{method="get\"odd\\method\n",le="+Inf"} 1
+abelian_rpc_result_size_bytes_sum{method="get\"odd\\method\n"} 2000
+abelian_rpc_result_size_bytes_count{method="get\"odd\\method\n"} 1
+abelian_rpc_result_size_bytes_bucket{method="getblockcount",le="256"} 1
+abelian_rpc_result_size_bytes_bucket{method="getblockcount",le="1024"} 1
+abelian_rpc_result_size_bytes_bucket{method="getblockcount",le="4096"} 1
+abelian_rpc_result_size_bytes_bucket{method="getblockcount",le="16384"} 1
+abelian_rpc_result_size_bytes_bucket{method="getblockcount",le="65536"} 1
+abelian_rpc_result_size_bytes_bucket{method="getblockcount",le="262144"} 1
+abelian_rpc_result_size_bytes_bucket{method="getblockcount",le="1048576"} 1
+abelian_rpc_result_size_bytes_bucket{method="getblockcount",le="4194304"} 1
+abelian_rpc_result_size_bytes_bucket{method="getblockcount",le="16777216"} 1
+abelian_rpc_result_size_bytes_bucket{method="getblockcount",le="67108864"} 1
+abelian_rpc_result_size_bytes_bucket{method="getblockcount",le="+Inf"} 1
+abelian_rpc_result_size_bytes_sum{method="getblockcount"} 4
+abelian_rpc_result_size_bytes_count{method="getblockcount"} 1
+`
+
+func TestMetricsExposition(t *testing.T) {
+	metrics := abelian.NewMetrics()
+	// a method name needing each escape of the label values
+	oddMethod := "get\"odd\\method\n"
+	metrics.CallStarted(oddMethod)
+	metrics.CallFinished(oddMethod, 2*time.Second, 2000, nil)
+	metrics.CallStarted(oddMethod)
+	metrics.CallStarted("getblockcount")
+	metrics.CallFinished("getblockcount", 7812500*time.Nanosecond, 4, nil)
+	metrics.CallStarted("getblockcount")
+	// on the upper bound of its bucket
+	metrics.CallFinished("getblockcount", 250*time.Millisecond, 0, &abelian.RPCError{Code: -1, Message: "failure"})
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want the Prometheus text format", contentType)
+	}
+	if got := recorder.Body.String(); got != wantExposition {
+		t.Errorf("exposition =\n%s\nwant\n%s", got, wantExposition)
+	}
+}
@@ -0,0 +1,244 @@
+package abelian
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BlockTemplateRequest is the optional request of getblocktemplate.
+type BlockTemplateRequest struct {
+	Mode         string   `json:"mode,omitempty"` // "template" (default) or "proposal"
+	Capabilities []string `json:"capabilities,omitempty"`
+	LongPollID   string   `json:"longpollid,omitempty"`
+	Data         string   `json:"data,omitempty"` // hex of the proposed block in proposal mode
+	WorkID       string   `json:"workid,omitempty"`
+}
+
+// BlockTemplateTx is a transaction of a block template.
+type BlockTemplateTx struct {
+	Data        string `json:"data"`
+	TxHash      string `json:"txhash"`
+	WitnessHash string `json:"witnesshash"`
+	Fee         uint64 `json:"fee"`
+	Size        int64  `json:"size"`
+}
+
+// BlockTemplateCoinbase is the coinbase transaction proposed by the node, with the place of its extra nonce.
+type BlockTemplateCoinbase struct {
+	Data             string `json:"data"`
+	TxHash           string `json:"txhash"`
+	WitnessHash      string `json:"witnesshash"`
+	Fee              uint64 `json:"fee"`
+	Size             int64  `json:"size"`
+	ExtraNonceOffset int64  `json:"extranonceoffset"`
+	ExtraNonceLen    int64  `json:"extranoncelen"`
+	WitnessOffset    int64  `json:"witness_offset"`
+}
+
+type BlockTemplate struct {
+	Bits          string                 `json:"bits"`
+	CurTime       int64                  `json:"curtime"`
+	Height        int64                  `json:"height"`
+	PrevBlockHash string                 `json:"previousblockhash"`
+	SizeLimit     int64                  `json:"sizelimit"`
+	Transactions  []*BlockTemplateTx     `json:"transactions"`
+	Version       int64                  `json:"version"`
+	CoinbaseTx    *BlockTemplateCoinbase `json:"coinbasetxn"`
+	WorkID        string                 `json:"workid"`
+	LongPollID    string                 `json:"longpollid"`
+	LongPollURI   string                 `json:"longpolluri"`
+	SubmitOld     *bool                  `json:"submitold"`
+	Target        string                 `json:"target"`
+	Expires       int64                  `json:"expires"`
+	MaxTime       int64                  `json:"maxtime"`
+	MinTime       int64                  `json:"mintime"`
+	Mutable       []string               `json:"mutable"`
+	NonceRange    string                 `json:"noncerange"`
+	Capabilities  []string               `json:"capabilities"`
+	RejectReason  string                 `json:"reject-reason"`
+}
+
+type MiningInfo struct {
+	Blocks           int64   `json:"blocks"`
+	CurrentBlockSize uint64  `json:"currentblocksize"`
+	CurrentBlockTx   uint64  `json:"currentblocktx"`
+	Difficulty       float64 `json:"difficulty"`
+	Errors           string  `json:"errors"`
+	Generate         bool    `json:"generate"`
+	GenProcLimit     int32   `json:"genproclimit"`
+	HashesPerSec     int64   `json:"hashespersec"`
+	NetworkHashPS    int64   `json:"networkhashps"`
+	PooledTx         uint64  `json:"pooledtx"`
+	TestNet          bool    `json:"testnet"`
+}
+
+// GetBlockTemplate returns a template of the next block to mine. request may be nil.
+func (client *Client) GetBlockTemplate(request *BlockTemplateRequest) (res *BlockTemplate, err error) {
+	return client.GetBlockTemplateCtx(context.Background(), request)
+}
+func (client *Client) GetBlockTemplateCtx(ctx context.Context, request *BlockTemplateRequest) (res *BlockTemplate, err error) {
+	var params []interface{}
+	if request != nil {
+		params = []interface{}{request}
+	}
+	err = client.DoCtx(ctx, "getblocktemplate", params, &res)
+	return res, err
+}
+
+// SubmitBlock submits a mined block. A block refused by the node is returned as *BlockRejectedError.
+func (client *Client) SubmitBlock(blockBytes []byte, workID string) (err error) {
+	return client.SubmitBlockCtx(context.Background(), blockBytes, workID)
+}
+func (client *Client) SubmitBlockCtx(ctx context.Context, blockBytes []byte, workID string) (err error) {
+	params := []interface{}{hex.EncodeToString(blockBytes)}
+	if workID != "" {
+		params = append(params, map[string]string{"workid": workID})
+	}
+	var res *string
+	err = client.DoCtx(ctx, "submitblock", params, &res)
+	if err != nil {
+		return err
+	}
+	if res != nil {
+		return &BlockRejectedError{Reason: strings.TrimPrefix(*res, "rejected: ")}
+	}
+	return nil
+}
+
+func (client *Client) GetMiningInfo() (res *MiningInfo, err error) {
+	return client.GetMiningInfoCtx(context.Background())
+}
+func (client *Client) GetMiningInfoCtx(ctx context.Context) (res *MiningInfo, err error) {
+	err = client.DoCtx(ctx, "getmininginfo", nil, &res)
+	return res, err
+}
+
+// CoinbaseOutput is a coinbase output of a tracked view account.
+type CoinbaseOutput struct {
+	Coin         *Coin
+	Account      ViewAccount
+	MatureHeight int64 // height from which a block may include a transaction spending the output
+}
+
+// IsMature reports whether the output can be spent by a transaction in the block following tipHeight.
+func (output *CoinbaseOutput) IsMature(tipHeight int64) bool {
+	return tipHeight+1 >= output.MatureHeight
+}
+
+// CoinbaseTracker finds the coinbase outputs received by a set of view accounts, e.g. the payout
+// accounts of a mining pool, and tracks them until they pass the coinbase maturity of the network.
+type CoinbaseTracker struct {
+	accounts []ViewAccount
+	params   *ChainParams
+
+	mtx      sync.Mutex
+	immature []*CoinbaseOutput // sorted by MatureHeight
+}
+
+// NewCoinbaseTracker tracks the coinbase outputs on mainnet, see NewCoinbaseTrackerWithClient for the network of a node.
+func NewCoinbaseTracker(accounts ...ViewAccount) *CoinbaseTracker {
+	return NewCoinbaseTrackerWithParams(MainNetParams, accounts...)
+}
+
+// NewCoinbaseTrackerWithParams tracks the coinbase outputs on the network of params,
+// e.g. those returned by Client.ChainParams.
+func NewCoinbaseTrackerWithParams(params *ChainParams, accounts ...ViewAccount) *CoinbaseTracker {
+	return &CoinbaseTracker{accounts: accounts, params: params}
+}
+
+// NewCoinbaseTrackerWithClient tracks the coinbase outputs on the network of the node of client.
+func NewCoinbaseTrackerWithClient(ctx context.Context, client *Client, accounts ...ViewAccount) (*CoinbaseTracker, error) {
+	params, err := client.ChainParamsCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewCoinbaseTrackerWithParams(params, accounts...), nil
+}
+
+// ScanCoinbaseTx tracks the outputs of the coinbase transaction of the block at blockHeight
+// received by the accounts, and returns them.
+func (tracker *CoinbaseTracker) ScanCoinbaseTx(tx *Tx, blockHeight int64) ([]*CoinbaseOutput, error) {
+	var outputs []*CoinbaseOutput
+	for index, vout := range tx.Vout {
+		txOutData, err := hex.DecodeString(vout.Script)
+		if err != nil {
+			return nil, fmt.Errorf("fail to decode output %d of coinbase transaction %s: %v", index, tx.TxID, err)
+		}
+		for _, account := range tracker.accounts {
+			success, value, err := account.ReceiveCoin(tx.Version, txOutData)
+			if err != nil {
+				return nil, fmt.Errorf("fail to receive output %d of coinbase transaction %s: %v", index, tx.TxID, err)
+			}
+			if !success {
+				continue
+			}
+			outputs = append(outputs, &CoinbaseOutput{
+				Coin:         NewCoin(tx.Version, tx.TxID, uint8(index), tx.BlockHash, blockHeight, Amount(value), "", txOutData),
+				Account:      account,
+				MatureHeight: blockHeight + tracker.params.CoinbaseMaturity,
+			})
+			break
+		}
+	}
+
+	tracker.mtx.Lock()
+	defer tracker.mtx.Unlock()
+	tracker.immature = append(tracker.immature, outputs...)
+	sort.SliceStable(tracker.immature, func(i, j int) bool {
+		return tracker.immature[i].MatureHeight < tracker.immature[j].MatureHeight
+	})
+	return outputs, nil
+}
+
+// ScanBlock fetches the coinbase transaction of the block at height and scans it with ScanCoinbaseTx.
+func (tracker *CoinbaseTracker) ScanBlock(ctx context.Context, client *Client, height int64) ([]*CoinbaseOutput, error) {
+	block, err := client.GetBlockByHeightCtx(ctx, height)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get block at height %d: %v", height, err)
+	}
+	if len(block.TxHashes) == 0 {
+		return nil, fmt.Errorf("block %s has no coinbase transaction", block.BlockHash)
+	}
+	tx, err := client.GetRawTxCtx(ctx, block.TxHashes[0])
+	if err != nil {
+		return nil, fmt.Errorf("fail to get coinbase transaction of block %s: %v", block.BlockHash, err)
+	}
+	return tracker.ScanCoinbaseTx(tx, block.Height)
+}
+
+// Mature stops tracking the outputs which are mature at tipHeight and returns them.
+func (tracker *CoinbaseTracker) Mature(tipHeight int64) []*CoinbaseOutput {
+	tracker.mtx.Lock()
+	defer tracker.mtx.Unlock()
+	n := 0
+	for n < len(tracker.immature) && tracker.immature[n].IsMature(tipHeight) {
+		n++
+	}
+	mature := tracker.immature[:n:n]
+	tracker.immature = tracker.immature[n:]
+	return mature
+}
+
+// Immature returns the tracked outputs which are not mature yet.
+func (tracker *CoinbaseTracker) Immature() []*CoinbaseOutput {
+	tracker.mtx.Lock()
+	defer tracker.mtx.Unlock()
+	return append([]*CoinbaseOutput{}, tracker.immature...)
+}
+
+// Disconnect stops tracking the outputs of blocks at height and above, e.g. after a reorg.
+func (tracker *CoinbaseTracker) Disconnect(height int64) {
+	tracker.mtx.Lock()
+	defer tracker.mtx.Unlock()
+	kept := tracker.immature[:0]
+	for _, output := range tracker.immature {
+		if output.Coin.BlockHeight < height {
+			kept = append(kept, output)
+		}
+	}
+	tracker.immature = kept
+}
@@ -0,0 +1,200 @@
+package abelian_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+func TestBlockTemplate(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	addTestBlocks(server, 3)
+	server.AddMempoolTx(&abelian.Tx{Hex: "aa01"})
+	server.AddMempoolTx(&abelian.Tx{Hex: "aa02"})
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	template, err := client.GetBlockTemplate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tip := server.BlockByHeight(3)
+	if template.Height != 4 || template.PrevBlockHash != tip.BlockHash || template.WorkID == "" {
+		t.Errorf("template = height %d on %s with work %q, want height 4 on %s", template.Height, template.PrevBlockHash, template.WorkID, tip.BlockHash)
+	}
+	var txIDs []string
+	for _, tx := range template.Transactions {
+		txIDs = append(txIDs, tx.TxHash)
+	}
+	if want := server.Mempool(); !reflect.DeepEqual(txIDs, want) {
+		t.Errorf("transactions of the template = %v, want %v", txIDs, want)
+	}
+
+	_, err = client.GetBlockTemplate(&abelian.BlockTemplateRequest{Mode: "proposal", Data: "00"})
+	var rpcErr *abelian.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != abelian.RPC_ERR_INVALID_PARAMETER {
+		t.Errorf("GetBlockTemplate in an unsupported mode = %v, want RPC error %d", err, abelian.RPC_ERR_INVALID_PARAMETER)
+	}
+
+	info, err := client.GetMiningInfo()
+	if err != nil || info.Blocks != 3 || info.PooledTx != 2 {
+		t.Errorf("GetMiningInfo = %+v, %v, want 3 blocks and 2 pooled transactions", info, err)
+	}
+}
+
+func TestSubmitBlock(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	steps := []struct {
+		block      []byte
+		workID     string
+		wantErr    error
+		wantReason string
+	}{
+		{[]byte{1, 2, 3}, "", nil, ""},
+		{[]byte{4, 5, 6}, "0123456789abcdef", nil, ""},
+		{[]byte{1, 2, 3}, "", abelian.ErrBlockRejected, "duplicate block"},
+	}
+	for _, step := range steps {
+		err := client.SubmitBlock(step.block, step.workID)
+		if !errors.Is(err, step.wantErr) {
+			t.Errorf("SubmitBlock(%x) = %v, want %v", step.block, err, step.wantErr)
+		}
+		var rejected *abelian.BlockRejectedError
+		if errors.As(err, &rejected) && rejected.Reason != step.wantReason {
+			t.Errorf("SubmitBlock(%x) rejected for %q, want %q", step.block, rejected.Reason, step.wantReason)
+		}
+	}
+	if submitted := server.SubmittedBlocks(); !reflect.DeepEqual(submitted, [][]byte{{1, 2, 3}, {4, 5, 6}}) {
+		t.Errorf("submitted blocks = %x", submitted)
+	}
+
+	// a block the node cannot decode is an RPC error, not a rejection
+	err = client.SubmitBlock(nil, "")
+	var rpcErr *abelian.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != abelian.RPC_ERR_DESERIALIZATION || errors.Is(err, abelian.ErrBlockRejected) {
+		t.Errorf("SubmitBlock of no block = %v, want RPC error %d", err, abelian.RPC_ERR_DESERIALIZATION)
+	}
+}
+
+func TestMiningLimitedUser(t *testing.T) {
+	server := abeliantest.NewServer(abeliantest.WithAuth("admin", "adminpass"), abeliantest.WithLimitedAuth("user", "userpass"))
+	defer server.Close()
+	client, err := abelian.NewClient(abelian.NewClientConfig(server.URL, abelian.WithAuth("user", "userpass")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.GetBlockTemplate(nil); !errors.Is(err, abelian.ErrUnauthorized) {
+		t.Errorf("GetBlockTemplate of the limited user = %v, want ErrUnauthorized", err)
+	}
+	if err := client.SubmitBlock([]byte{1}, ""); err != nil {
+		t.Errorf("SubmitBlock of the limited user = %v", err)
+	}
+}
+
+func TestCoinbaseTracker(t *testing.T) {
+	params := testChainParams(t)
+	server := abeliantest.NewServer(abeliantest.WithNetID(testNetworkID))
+	defer server.Close()
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	pool, other := &tagView{tag: 7}, &tagView{tag: 8}
+	tracker, err := abelian.NewCoinbaseTrackerWithClient(context.Background(), client, pool, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// blocks 1 to 5 pay both accounts, block 3 pays none of them
+	for height := 1; height <= 5; height++ {
+		vout := []*abelian.TxVout{{Script: "0701"}, {Script: "080102"}}
+		if height == 3 {
+			vout = []*abelian.TxVout{{Script: "0901"}}
+		}
+		server.AddBlock(&abelian.Tx{Hex: "cb0" + string(rune('0'+height)), Vout: vout})
+	}
+	for height := int64(1); height <= 5; height++ {
+		outputs, err := tracker.ScanBlock(context.Background(), client, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantOutputs := 2
+		if height == 3 {
+			wantOutputs = 0
+		}
+		if len(outputs) != wantOutputs {
+			t.Fatalf("%d outputs in block %d, want %d", len(outputs), height, wantOutputs)
+		}
+		for _, output := range outputs {
+			wantValue := abelian.Amount(200)
+			if output.Account == other {
+				wantValue = 300
+			}
+			if output.Coin.Value != wantValue || output.Coin.BlockHeight != height || output.MatureHeight != height+params.CoinbaseMaturity {
+				t.Errorf("output of block %d = %d at %d maturing at %d, want %d maturing at %d",
+					height, output.Coin.Value, output.Coin.BlockHeight, output.MatureHeight, wantValue, height+params.CoinbaseMaturity)
+			}
+			if output.Coin.TxID != server.BlockByHeight(height).TxHashes[0] {
+				t.Errorf("output of block %d in %s, want the coinbase transaction", height, output.Coin.TxID)
+			}
+		}
+	}
+	if immature := tracker.Immature(); len(immature) != 8 {
+		t.Fatalf("%d immature outputs, want 8", len(immature))
+	}
+
+	// a reorganization drops the outputs of the blocks from height 5
+	tracker.Disconnect(5)
+	if immature := tracker.Immature(); len(immature) != 6 {
+		t.Errorf("%d immature outputs after disconnecting block 5, want 6", len(immature))
+	}
+
+	steps := []struct {
+		tip        int64
+		wantMature int
+		wantLeft   int
+	}{
+		{params.CoinbaseMaturity - 1, 0, 6},
+		{params.CoinbaseMaturity, 2, 4}, // spendable in the block following the tip
+		{params.CoinbaseMaturity, 0, 4},
+		{params.CoinbaseMaturity + 1, 2, 2},
+		{params.CoinbaseMaturity + 10, 2, 0},
+	}
+	for _, step := range steps {
+		mature := tracker.Mature(step.tip)
+		if len(mature) != step.wantMature {
+			t.Errorf("%d outputs mature at tip %d, want %d", len(mature), step.tip, step.wantMature)
+		}
+		for _, output := range mature {
+			if !output.IsMature(step.tip) || output.IsMature(output.MatureHeight-2) {
+				t.Errorf("output maturing at %d is not mature at tip %d only from %d", output.MatureHeight, step.tip, output.MatureHeight-1)
+			}
+		}
+		if left := tracker.Immature(); len(left) != step.wantLeft {
+			t.Errorf("%d outputs immature at tip %d, want %d", len(left), step.tip, step.wantLeft)
+		}
+	}
+
+	// a block without transactions has no coinbase to scan
+	server.AddBlock()
+	if _, err := tracker.ScanBlock(context.Background(), client, server.Height()); err == nil {
+		t.Errorf("ScanBlock of a block without transactions succeeded")
+	}
+}
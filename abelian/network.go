@@ -0,0 +1,65 @@
+package abelian
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+)
+
+// NetworkID returns the network of the node, queried with getinfo the first time.
+func (client *Client) NetworkID() (NetworkID, error) {
+	return client.NetworkIDCtx(context.Background())
+}
+func (client *Client) NetworkIDCtx(ctx context.Context) (NetworkID, error) {
+	node, err := client.nodeInfoCtx(ctx)
+	return node.networkID, err
+}
+
+// DecodeAbelAddress decodes an address like NewAbelAddress, and returns *NetworkMismatchError
+// if the address is not on the network of the node.
+func (client *Client) DecodeAbelAddress(data []byte) (*AbelAddress, error) {
+	return client.DecodeAbelAddressCtx(context.Background(), data)
+}
+func (client *Client) DecodeAbelAddressCtx(ctx context.Context, data []byte) (*AbelAddress, error) {
+	netID, err := client.NetworkIDCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get network of the node: %w", err)
+	}
+	return NewAbelAddressForNetwork(data, netID)
+}
+
+// checkNetwork returns *NetworkMismatchError if the node is not on the expected network of the client.
+// It asks the node with getinfo, as the endpoint may now reach another node than when it was checked.
+func (client *Client) checkNetwork(ctx context.Context) error {
+	if client.expectedNetwork == nil {
+		return nil
+	}
+	info, err := client.GetChainInfoCtx(ctx)
+	if err != nil {
+		return fmt.Errorf("fail to get network of the node: %w", err)
+	}
+	if netID := NetworkID(info.NetID); netID != *client.expectedNetwork {
+		return &NetworkMismatchError{Expected: *client.expectedNetwork, Actual: netID}
+	}
+	return nil
+}
+
+// SendSignedRawTx broadcasts the transaction like SendRawTx, after checking that its outputs are on
+// the network of the node. A serialized transaction does not tell its network, so only SignedRawTx
+// with a NetworkID can be checked, and SendRawTx only checks the node against the expected network
+// of the client.
+func (client *Client) SendSignedRawTx(tx *SignedRawTx) (res string, err error) {
+	return client.SendSignedRawTxCtx(context.Background(), tx)
+}
+func (client *Client) SendSignedRawTxCtx(ctx context.Context, tx *SignedRawTx) (res string, err error) {
+	if tx.NetworkID != nil {
+		netID, err := client.NetworkIDCtx(ctx)
+		if err != nil {
+			return "", fmt.Errorf("fail to get network of the node: %w", err)
+		}
+		if *tx.NetworkID != netID {
+			return "", &NetworkMismatchError{Expected: netID, Actual: *tx.NetworkID}
+		}
+	}
+	return client.SendRawTxCtx(ctx, hex.EncodeToString(tx.Data))
+}
@@ -0,0 +1,483 @@
+package abelian
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	DEFAULT_NOTIFICATION_BUFFER = 100
+
+	notificationPingInterval      = 30 * time.Second
+	notificationMinReconnectDelay = time.Second
+	notificationMaxReconnectDelay = time.Minute
+)
+
+var errNotificationNotConnected = errors.New("notification client is not connected")
+
+// BlockConnected is delivered when a block is connected to the main chain.
+type BlockConnected struct {
+	BlockHash string
+	Height    int64
+	Time      int64
+}
+
+// BlockDisconnected is delivered when a block is disconnected from the main chain due to a reorganization.
+type BlockDisconnected struct {
+	BlockHash string
+	Height    int64
+	Time      int64
+}
+
+// TxAccepted is delivered when a new transaction is accepted into the mempool.
+type TxAccepted struct {
+	TxID   string
+	Height int64
+}
+
+// NotificationClient receives notifications pushed by abec over its websocket endpoint.
+// Events are delivered on channels which must be drained by the caller, as a full channel
+// blocks the reading of further notifications.
+// The connection is re-established automatically and the subscriptions are registered again,
+// after which a value is sent on Reconnected so that callers can catch up on missed events.
+// Close closes all the channels, Reconnected included.
+type NotificationClient struct {
+	wsURL  string
+	header http.Header
+	dialer *websocket.Dialer
+
+	blockConnected    chan *BlockConnected
+	blockDisconnected chan *BlockDisconnected
+	txAccepted        chan *TxAccepted
+	reconnected       chan struct{}
+
+	mtx           sync.Mutex
+	conn          *websocket.Conn
+	subscriptions map[string][]interface{}
+	pending       map[string]chan *JSONRPCResponse
+
+	writeMtx  sync.Mutex // websocket connections support one concurrent writer
+	requestID atomic.Uint64
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewNotificationClient connects to the websocket endpoint of the abec node described by config.
+// The websocket URL is derived from config.Endpoint, using path /ws.
+func NewNotificationClient(config *ClientConfig) (*NotificationClient, error) {
+	wsURL, err := notificationURL(config.Endpoint, config.EnableTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 45 * time.Second,
+	}
+	if config.EnableTLS {
+		dialer.TLSClientConfig, err = newTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	header := http.Header{}
+	if config.Username != "" || config.Password != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(config.Username + ":" + config.Password))
+		header.Set("Authorization", "Basic "+auth)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	nc := &NotificationClient{
+		wsURL:             wsURL,
+		header:            header,
+		dialer:            dialer,
+		blockConnected:    make(chan *BlockConnected, DEFAULT_NOTIFICATION_BUFFER),
+		blockDisconnected: make(chan *BlockDisconnected, DEFAULT_NOTIFICATION_BUFFER),
+		txAccepted:        make(chan *TxAccepted, DEFAULT_NOTIFICATION_BUFFER),
+		reconnected:       make(chan struct{}, 1),
+		subscriptions:     map[string][]interface{}{},
+		pending:           map[string]chan *JSONRPCResponse{},
+		ctx:               ctx,
+		cancel:            cancel,
+	}
+
+	nc.wg.Add(1)
+	go nc.connectHandler()
+
+	return nc, nil
+}
+
+func notificationURL(endpoint string, enableTLS bool) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %s: %v", endpoint, err)
+	}
+	switch u.Scheme {
+	case "https", "wss":
+		u.Scheme = "wss"
+	case "http", "ws":
+		u.Scheme = "ws"
+		if enableTLS {
+			u.Scheme = "wss"
+		}
+	default:
+		return "", fmt.Errorf("invalid endpoint %s: unsupported scheme %q", endpoint, u.Scheme)
+	}
+	u.Path = "/ws"
+	return u.String(), nil
+}
+
+func (nc *NotificationClient) BlockConnected() <-chan *BlockConnected {
+	return nc.blockConnected
+}
+
+func (nc *NotificationClient) BlockDisconnected() <-chan *BlockDisconnected {
+	return nc.blockDisconnected
+}
+
+func (nc *NotificationClient) TxAccepted() <-chan *TxAccepted {
+	return nc.txAccepted
+}
+
+// Reconnected signals that the connection was re-established after it had been lost.
+func (nc *NotificationClient) Reconnected() <-chan struct{} {
+	return nc.reconnected
+}
+
+// NotifyBlocks subscribes to BlockConnected and BlockDisconnected events.
+func (nc *NotificationClient) NotifyBlocks(ctx context.Context) error {
+	return nc.subscribe(ctx, "notifyblocks", nil)
+}
+
+func (nc *NotificationClient) StopNotifyBlocks(ctx context.Context) error {
+	return nc.unsubscribe(ctx, "notifyblocks", "stopnotifyblocks")
+}
+
+// NotifyNewTransactions subscribes to TxAccepted events.
+func (nc *NotificationClient) NotifyNewTransactions(ctx context.Context) error {
+	return nc.subscribe(ctx, "notifynewtransactions", []interface{}{false})
+}
+
+func (nc *NotificationClient) StopNotifyNewTransactions(ctx context.Context) error {
+	return nc.unsubscribe(ctx, "notifynewtransactions", "stopnotifynewtransactions")
+}
+
+// Close shuts down the connection and closes all event channels.
+func (nc *NotificationClient) Close() error {
+	nc.closeOnce.Do(func() {
+		nc.cancel()
+		nc.mtx.Lock()
+		if nc.conn != nil {
+			nc.conn.Close()
+		}
+		nc.mtx.Unlock()
+		nc.wg.Wait()
+
+		close(nc.blockConnected)
+		close(nc.blockDisconnected)
+		close(nc.txAccepted)
+		close(nc.reconnected)
+	})
+	return nil
+}
+
+func (nc *NotificationClient) subscribe(ctx context.Context, method string, params []interface{}) error {
+	nc.mtx.Lock()
+	nc.subscriptions[method] = params
+	nc.mtx.Unlock()
+
+	err := nc.call(ctx, method, params)
+	if errors.Is(err, errNotificationNotConnected) {
+		// registered once the connection is established
+		return nil
+	}
+	return err
+}
+
+func (nc *NotificationClient) unsubscribe(ctx context.Context, subscribeMethod string, method string) error {
+	nc.mtx.Lock()
+	delete(nc.subscriptions, subscribeMethod)
+	nc.mtx.Unlock()
+
+	err := nc.call(ctx, method, nil)
+	if errors.Is(err, errNotificationNotConnected) {
+		return nil
+	}
+	return err
+}
+
+func (nc *NotificationClient) call(ctx context.Context, method string, params []interface{}) error {
+	nc.mtx.Lock()
+	conn := nc.conn
+	if conn == nil {
+		nc.mtx.Unlock()
+		return errNotificationNotConnected
+	}
+	id := strconv.FormatUint(nc.requestID.Add(1), 10)
+	respChan := make(chan *JSONRPCResponse, 1)
+	nc.pending[id] = respChan
+	nc.mtx.Unlock()
+
+	defer func() {
+		nc.mtx.Lock()
+		delete(nc.pending, id)
+		nc.mtx.Unlock()
+	}()
+
+	jsonReq := &JSONRPCRequest{
+		JSONRPC: "1.0",
+		Method:  method,
+		Params:  params,
+		ID:      id,
+	}
+	nc.writeMtx.Lock()
+	err := conn.WriteJSON(jsonReq)
+	nc.writeMtx.Unlock()
+	if err != nil {
+		sdkLog.Errorf("fail to send websocket request %s: %v", method, err)
+		return err
+	}
+
+	select {
+	case resp, ok := <-respChan:
+		if !ok {
+			return errNotificationNotConnected
+		}
+		if resp.Error != nil {
+			sdkLog.Errorf("websocket request method %s, response error: %v", method, resp.Error)
+			return resp.Error
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-nc.ctx.Done():
+		return nc.ctx.Err()
+	}
+}
+
+// connectHandler keeps the websocket connection alive until the client is closed.
+func (nc *NotificationClient) connectHandler() {
+	defer nc.wg.Done()
+
+	delay := notificationMinReconnectDelay
+	connected := false
+	for nc.ctx.Err() == nil {
+		conn, _, err := nc.dialer.DialContext(nc.ctx, nc.wsURL, nc.header)
+		if err != nil {
+			if nc.ctx.Err() != nil {
+				return
+			}
+			sdkLog.Warnf("fail to connect websocket %s: %v, retry in %v", nc.wsURL, err, delay)
+			select {
+			case <-time.After(delay):
+			case <-nc.ctx.Done():
+				return
+			}
+			delay *= 2
+			if delay > notificationMaxReconnectDelay {
+				delay = notificationMaxReconnectDelay
+			}
+			continue
+		}
+		delay = notificationMinReconnectDelay
+
+		nc.mtx.Lock()
+		nc.conn = conn
+		subscriptions := make(map[string][]interface{}, len(nc.subscriptions))
+		for method, params := range nc.subscriptions {
+			subscriptions[method] = params
+		}
+		nc.mtx.Unlock()
+
+		if nc.ctx.Err() != nil {
+			conn.Close()
+			break
+		}
+		reconnect := connected
+		connected = true
+
+		// subscriptions are registered again while the responses are read, and callers are told
+		// of the reconnection once they are back
+		nc.wg.Add(1)
+		go func() {
+			defer nc.wg.Done()
+			for method, params := range subscriptions {
+				err := nc.call(nc.ctx, method, params)
+				if err != nil {
+					sdkLog.Errorf("fail to register %s on websocket: %v", method, err)
+					return
+				}
+			}
+			if reconnect {
+				sdkLog.Infof("websocket %s reconnected", nc.wsURL)
+				select {
+				case nc.reconnected <- struct{}{}:
+				default:
+				}
+			}
+		}()
+
+		nc.readHandler(conn)
+
+		nc.mtx.Lock()
+		nc.conn = nil
+		for id, respChan := range nc.pending {
+			close(respChan)
+			delete(nc.pending, id)
+		}
+		nc.mtx.Unlock()
+		conn.Close()
+	}
+}
+
+type wsMessage struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	Result json.RawMessage   `json:"result"`
+	Error  *RPCError         `json:"error"`
+	ID     *string           `json:"id"`
+}
+
+// readHandler reads messages until the connection fails.
+func (nc *NotificationClient) readHandler(conn *websocket.Conn) {
+	conn.SetReadDeadline(time.Now().Add(2 * notificationPingInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * notificationPingInterval))
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(notificationPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				nc.writeMtx.Lock()
+				err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(notificationPingInterval))
+				nc.writeMtx.Unlock()
+				if err != nil {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if nc.ctx.Err() == nil {
+				sdkLog.Warnf("websocket %s disconnected: %v", nc.wsURL, err)
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * notificationPingInterval))
+
+		msg := &wsMessage{}
+		err = json.Unmarshal(data, msg)
+		if err != nil {
+			sdkLog.Warnf("fail to unmarshal websocket message: %v", err)
+			continue
+		}
+
+		if msg.Method == "" {
+			if msg.ID == nil {
+				continue
+			}
+			nc.mtx.Lock()
+			respChan, ok := nc.pending[*msg.ID]
+			nc.mtx.Unlock()
+			if ok {
+				select {
+				case respChan <- &JSONRPCResponse{Result: msg.Result, Error: msg.Error, ID: *msg.ID}:
+				default:
+				}
+			}
+			continue
+		}
+
+		err = nc.dispatch(msg)
+		if err != nil {
+			sdkLog.Warnf("fail to handle notification %s: %v", msg.Method, err)
+		}
+	}
+}
+
+func (nc *NotificationClient) dispatch(msg *wsMessage) error {
+	switch msg.Method {
+	case "blockconnected", "blockabeconnected":
+		hash, height, blockTime, err := parseBlockNotificationParams(msg.Params)
+		if err != nil {
+			return err
+		}
+		select {
+		case nc.blockConnected <- &BlockConnected{BlockHash: hash, Height: height, Time: blockTime}:
+		case <-nc.ctx.Done():
+		}
+	case "blockdisconnected", "blockabedisconnected":
+		hash, height, blockTime, err := parseBlockNotificationParams(msg.Params)
+		if err != nil {
+			return err
+		}
+		select {
+		case nc.blockDisconnected <- &BlockDisconnected{BlockHash: hash, Height: height, Time: blockTime}:
+		case <-nc.ctx.Done():
+		}
+	case "txaccepted":
+		if len(msg.Params) < 1 {
+			return fmt.Errorf("expected at least 1 param, got %d", len(msg.Params))
+		}
+		event := &TxAccepted{}
+		err := json.Unmarshal(msg.Params[0], &event.TxID)
+		if err != nil {
+			return err
+		}
+		if len(msg.Params) > 1 {
+			err = json.Unmarshal(msg.Params[1], &event.Height)
+			if err != nil {
+				return err
+			}
+		}
+		select {
+		case nc.txAccepted <- event:
+		case <-nc.ctx.Done():
+		}
+	default:
+		sdkLog.Debugf("ignore unknown notification %s", msg.Method)
+	}
+	return nil
+}
+
+// parseBlockNotificationParams parses params in the form [hash, height, time].
+func parseBlockNotificationParams(params []json.RawMessage) (hash string, height int64, blockTime int64, err error) {
+	if len(params) != 3 {
+		return "", 0, 0, fmt.Errorf("expected 3 params, got %d", len(params))
+	}
+	if err = json.Unmarshal(params[0], &hash); err != nil {
+		return "", 0, 0, err
+	}
+	if err = json.Unmarshal(params[1], &height); err != nil {
+		return "", 0, 0, err
+	}
+	if err = json.Unmarshal(params[2], &blockTime); err != nil {
+		return "", 0, 0, err
+	}
+	return hash, height, blockTime, nil
+}
@@ -0,0 +1,132 @@
+package abelian_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+const notificationWait = 5 * time.Second
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed")
+		}
+		return v
+	case <-time.After(notificationWait):
+		t.Fatal("no notification received")
+	}
+	var zero T
+	return zero
+}
+
+func waitSubscribers(t *testing.T, server *abeliantest.Server, blocks int, txs int) {
+	t.Helper()
+	deadline := time.Now().Add(notificationWait)
+	for time.Now().Before(deadline) {
+		b, x := server.WebsocketSubscribers()
+		if b == blocks && x == txs {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	b, x := server.WebsocketSubscribers()
+	t.Fatalf("subscribers = (%d, %d), want (%d, %d)", b, x, blocks, txs)
+}
+
+func newNotificationClient(t *testing.T, server *abeliantest.Server) *abelian.NotificationClient {
+	t.Helper()
+	nc, err := abelian.NewNotificationClient(server.ClientConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nc.Close() })
+
+	ctx := context.Background()
+	if err := nc.NotifyBlocks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := nc.NotifyNewTransactions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	waitSubscribers(t, server, 1, 1)
+	return nc
+}
+
+func TestNotificationClientEvents(t *testing.T) {
+	server := abeliantest.NewServer(abeliantest.WithAuth("user", "pass"))
+	defer server.Close()
+	nc := newNotificationClient(t, server)
+
+	block := server.AddBlock()
+	connected := receive(t, nc.BlockConnected())
+	if connected.BlockHash != block.BlockHash || connected.Height != block.Height || connected.Time != block.Time {
+		t.Errorf("BlockConnected = %+v, want block %s at %d", connected, block.BlockHash, block.Height)
+	}
+
+	tx := &abelian.Tx{Hex: "00ff"}
+	server.AddMempoolTx(tx)
+	accepted := receive(t, nc.TxAccepted())
+	if accepted.TxID != tx.TxID || accepted.Height != block.Height {
+		t.Errorf("TxAccepted = %+v, want %s at %d", accepted, tx.TxID, block.Height)
+	}
+
+	server.Reorg(1)
+	disconnected := receive(t, nc.BlockDisconnected())
+	if disconnected.BlockHash != block.BlockHash || disconnected.Height != block.Height {
+		t.Errorf("BlockDisconnected = %+v, want block %s at %d", disconnected, block.BlockHash, block.Height)
+	}
+
+	if err := nc.StopNotifyNewTransactions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	waitSubscribers(t, server, 1, 0)
+}
+
+func TestNotificationClientReconnect(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	nc := newNotificationClient(t, server)
+
+	server.DropWebsockets()
+	receive(t, nc.Reconnected())
+	// the subscriptions are restored before the reconnection is signaled
+	blocks, txs := server.WebsocketSubscribers()
+	if blocks != 1 || txs != 1 {
+		t.Fatalf("subscribers after reconnection = (%d, %d), want (1, 1)", blocks, txs)
+	}
+
+	block := server.AddBlock()
+	connected := receive(t, nc.BlockConnected())
+	if connected.BlockHash != block.BlockHash {
+		t.Errorf("BlockConnected after reconnection = %s, want %s", connected.BlockHash, block.BlockHash)
+	}
+}
+
+func TestNotificationClientClose(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	nc := newNotificationClient(t, server)
+
+	nc.Close()
+	select {
+	case _, ok := <-nc.Reconnected():
+		if ok {
+			t.Error("Reconnected delivered a value after Close")
+		}
+	case <-time.After(notificationWait):
+		t.Error("Reconnected not closed by Close")
+	}
+	if _, ok := <-nc.BlockConnected(); ok {
+		t.Error("BlockConnected not closed by Close")
+	}
+	if _, ok := <-nc.TxAccepted(); ok {
+		t.Error("TxAccepted not closed by Close")
+	}
+}
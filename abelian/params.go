@@ -0,0 +1,188 @@
+package abelian
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pqabelian/abec/chaincfg"
+	"github.com/pqabelian/abec/wire"
+)
+
+// RingParams are the ring rules from a height on, until the next entry of a ring schedule.
+type RingParams struct {
+	Height               int64  // first height of the rules
+	RingVersion          uint32 // version of the rings, which is the version of their transactions
+	TxoRingSize          uint8  // largest number of coins in a ring
+	BlockNumPerRingGroup uint8  // number of blocks whose coins are grouped into rings
+}
+
+// ChainParams are the consensus and network parameters of a network.
+type ChainParams struct {
+	NetworkID        NetworkID
+	Name             string
+	GenesisHash      string
+	CoinbaseMaturity int64 // blocks after which a coinbase output can be spent
+	AbelAddressNetID byte  // first byte of the abel addresses on the network
+
+	DefaultPort           string // peer-to-peer port of abec
+	DefaultRPCPort        string
+	DefaultRPCPortGetWork string
+
+	RingSchedule []*RingParams // sorted by Height, the first one at height 0
+
+	BlockHeightEthashPoW    int64
+	BlockHeightDSA          int64
+	BlockHeightMLPAUT       int64
+	BlockHeightMLPAUTCommit int64
+}
+
+// RingParams returns the ring rules at height.
+func (params *ChainParams) RingParams(height int64) *RingParams {
+	i := sort.Search(len(params.RingSchedule), func(i int) bool {
+		return params.RingSchedule[i].Height > height
+	})
+	return params.RingSchedule[max(i-1, 0)]
+}
+
+func (params *ChainParams) TxoRingSizeByBlockHeight(height int64) uint8 {
+	return params.RingParams(height).TxoRingSize
+}
+
+func (params *ChainParams) BlockNumPerRingGroupByBlockHeight(height int64) uint8 {
+	return params.RingParams(height).BlockNumPerRingGroup
+}
+
+// RingGroupFirstHeight returns the height of the first block of the ring group of height.
+// Ring groups are aligned on heights, as abec builds the rings at each multiple of the group size.
+func (params *ChainParams) RingGroupFirstHeight(height int64) int64 {
+	return height - height%int64(params.BlockNumPerRingGroupByBlockHeight(height))
+}
+
+func (params *ChainParams) RingBlockHeights(height int64) []int64 {
+	blockNumPerGroup := params.BlockNumPerRingGroupByBlockHeight(height)
+	firstRingBlockHeight := params.RingGroupFirstHeight(height)
+
+	ringBlockHeights := make([]int64, 0, blockNumPerGroup)
+	for i := int64(0); i < int64(blockNumPerGroup); i++ {
+		ringBlockHeights = append(ringBlockHeights, firstRingBlockHeight+i)
+	}
+	return ringBlockHeights
+}
+
+// TxVersionByBlockHeight returns the version of the transactions of the block at height.
+func (params *ChainParams) TxVersionByBlockHeight(height int64) uint32 {
+	return params.RingParams(height).RingVersion
+}
+
+// ringSchedule derives the ring rules of a network from the ring versions of abec.
+func ringSchedule(blockHeightMLPAUT int64) []*RingParams {
+	schedule := make([]*RingParams, 0, 2)
+	for _, entry := range []struct {
+		height  int64
+		version uint32
+	}{
+		{0, wire.TxVersion_Height_0},
+		{blockHeightMLPAUT, wire.TxVersion_Height_MLPAUT_300000},
+	} {
+		ringSize, err := wire.GetTxoRingSizeByRingVersion(entry.version)
+		if err != nil {
+			panic(err)
+		}
+		blockNum, err := wire.GetBlockNumPerRingGroupByRingVersion(entry.version)
+		if err != nil {
+			panic(err)
+		}
+		schedule = append(schedule, &RingParams{
+			Height:               entry.height,
+			RingVersion:          entry.version,
+			TxoRingSize:          ringSize,
+			BlockNumPerRingGroup: blockNum,
+		})
+	}
+	return schedule
+}
+
+// newChainParams takes the parameters of abec for netID. The RPC ports are those of the abec daemon,
+// which are not part of its chain parameters.
+func newChainParams(netID NetworkID, params *chaincfg.Params, rpcPort string, rpcPortGetWork string) *ChainParams {
+	return &ChainParams{
+		NetworkID:               netID,
+		Name:                    netID.String(),
+		GenesisHash:             params.GenesisHash.String(),
+		CoinbaseMaturity:        int64(params.CoinbaseMaturity),
+		AbelAddressNetID:        params.AbelAddressNetId,
+		DefaultPort:             params.DefaultPort,
+		DefaultRPCPort:          rpcPort,
+		DefaultRPCPortGetWork:   rpcPortGetWork,
+		RingSchedule:            ringSchedule(int64(params.BlockHeightMLPAUT)),
+		BlockHeightEthashPoW:    int64(params.BlockHeightEthashPoW),
+		BlockHeightDSA:          int64(params.BlockHeightDSA),
+		BlockHeightMLPAUT:       int64(params.BlockHeightMLPAUT),
+		BlockHeightMLPAUTCommit: int64(params.BlockHeightMLPAUTCOMMIT),
+	}
+}
+
+var (
+	MainNetParams       = newChainParams(MainNet, &chaincfg.MainNetParams, "8667", "8668")
+	RegressionNetParams = newChainParams(RegressionNet, &chaincfg.RegressionNetParams, "18667", "18668")
+	TestNetParams       = newChainParams(TestNet, &chaincfg.TestNet3Params, "18667", "18668")
+	SimNetParams        = newChainParams(SimNet, &chaincfg.SimNetParams, "18889", "18890")
+)
+
+var (
+	chainParamsMtx      sync.RWMutex
+	chainParamsRegistry = map[NetworkID]*ChainParams{
+		MainNet:       MainNetParams,
+		RegressionNet: RegressionNetParams,
+		TestNet:       TestNetParams,
+		SimNet:        SimNetParams,
+	}
+)
+
+// GetChainParams returns the parameters registered for netID.
+func GetChainParams(netID NetworkID) (*ChainParams, error) {
+	chainParamsMtx.RLock()
+	defer chainParamsMtx.RUnlock()
+	params, ok := chainParamsRegistry[netID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownNetwork, netID)
+	}
+	return params, nil
+}
+
+// RegisterChainParams registers params for params.NetworkID, replacing the registered ones,
+// e.g. for a simnet or regtest node run with other fork heights.
+func RegisterChainParams(params *ChainParams) error {
+	if len(params.RingSchedule) == 0 || params.RingSchedule[0].Height != 0 {
+		return fmt.Errorf("ring schedule of %s does not start at height 0", params.Name)
+	}
+	for i := 1; i < len(params.RingSchedule); i++ {
+		if params.RingSchedule[i].Height < params.RingSchedule[i-1].Height {
+			return fmt.Errorf("ring schedule of %s is not sorted by height", params.Name)
+		}
+	}
+	for _, ring := range params.RingSchedule {
+		if ring.TxoRingSize == 0 || ring.BlockNumPerRingGroup == 0 {
+			return fmt.Errorf("ring schedule of %s has an empty ring at height %d", params.Name, ring.Height)
+		}
+	}
+
+	chainParamsMtx.Lock()
+	defer chainParamsMtx.Unlock()
+	chainParamsRegistry[params.NetworkID] = params
+	return nil
+}
+
+// ChainParams returns the parameters of the network of the node.
+func (client *Client) ChainParams() (*ChainParams, error) {
+	return client.ChainParamsCtx(context.Background())
+}
+func (client *Client) ChainParamsCtx(ctx context.Context) (*ChainParams, error) {
+	netID, err := client.NetworkIDCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get network of the node: %w", err)
+	}
+	return GetChainParams(netID)
+}
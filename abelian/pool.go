@@ -0,0 +1,380 @@
+package abelian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DEFAULT_HEALTH_CHECK_INTERVAL = 10_000 // milliseconds
+	DEFAULT_MAX_TIP_LAG           = 2      // blocks
+)
+
+var ErrNoEndpoint = errors.New("no endpoint available")
+
+type MultiEndpointConfig struct {
+	Endpoints []*ClientConfig // config of each abec node, including its own auth and tls settings
+
+	HealthCheckInterval uint64 // interval in milliseconds between two health checks, default DEFAULT_HEALTH_CHECK_INTERVAL
+	MaxTipLag           int64  // number of blocks a node may lag behind the highest tip and still serve reads, default DEFAULT_MAX_TIP_LAG
+
+	Cache *CacheConfig // cache shared by all endpoints, nil for no cache
+}
+
+func NewMultiEndpointConfig(endpoints []*ClientConfig, options ...MultiEndpointOption) *MultiEndpointConfig {
+	config := &MultiEndpointConfig{
+		Endpoints:           endpoints,
+		HealthCheckInterval: DEFAULT_HEALTH_CHECK_INTERVAL,
+		MaxTipLag:           DEFAULT_MAX_TIP_LAG,
+	}
+
+	for _, opt := range options {
+		opt(config)
+	}
+
+	return config
+}
+
+// MultiEndpointOption change multi-endpoint client config
+type MultiEndpointOption func(*MultiEndpointConfig)
+
+// WithHealthCheckInterval sets the interval in milliseconds between two health checks.
+func WithHealthCheckInterval(interval uint64) MultiEndpointOption {
+	return func(config *MultiEndpointConfig) {
+		config.HealthCheckInterval = interval
+	}
+}
+
+// WithMaxTipLag sets the number of blocks a node may lag behind the highest known tip and still serve reads.
+func WithMaxTipLag(maxTipLag int64) MultiEndpointOption {
+	return func(config *MultiEndpointConfig) {
+		config.MaxTipLag = maxTipLag
+	}
+}
+
+// WithSharedCache enables a response cache in front of all endpoints, see WithCache.
+func WithSharedCache(cache *CacheConfig) MultiEndpointOption {
+	return func(config *MultiEndpointConfig) {
+		config.Cache = cache
+	}
+}
+
+// EndpointStatus is the health of one endpoint as seen by the multi-endpoint client.
+type EndpointStatus struct {
+	Endpoint  string
+	Healthy   bool
+	Tip       int64
+	LastCheck time.Time
+	LastError error
+}
+
+type endpoint struct {
+	client *Client
+
+	mtx       sync.Mutex
+	healthy   bool
+	tip       int64
+	lastCheck time.Time
+	lastErr   error
+}
+
+func (ep *endpoint) status() *EndpointStatus {
+	ep.mtx.Lock()
+	defer ep.mtx.Unlock()
+	return &EndpointStatus{
+		Endpoint:  ep.client.Endpoint,
+		Healthy:   ep.healthy,
+		Tip:       ep.tip,
+		LastCheck: ep.lastCheck,
+		LastError: ep.lastErr,
+	}
+}
+
+func (ep *endpoint) markFailure(err error) {
+	ep.mtx.Lock()
+	defer ep.mtx.Unlock()
+	if ep.healthy {
+		sdkLog.Warnf("endpoint %s is marked unhealthy: %v", ep.client.Endpoint, err)
+	}
+	ep.healthy = false
+	ep.lastErr = err
+}
+
+func (ep *endpoint) markSuccess() {
+	ep.mtx.Lock()
+	defer ep.mtx.Unlock()
+	if !ep.healthy {
+		sdkLog.Infof("endpoint %s is marked healthy", ep.client.Endpoint)
+	}
+	ep.healthy = true
+	ep.lastErr = nil
+}
+
+// endpointPool routes requests among several abec nodes.
+type endpointPool struct {
+	endpoints []*endpoint
+	interval  time.Duration
+	maxTipLag int64
+	next      atomic.Uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewMultiEndpointClient creates a client which spreads requests over several abec nodes.
+// The nodes are health-checked with getinfo, reads are routed to a healthy node close to the
+// highest known tip, and a request failing on one node is transparently sent to the next one,
+// unless it is a broadcast which may have reached the node. RPC errors are answers of a node and
+// are returned as they are.
+// Use Sticky for sequences of calls which must be served by the same node.
+func NewMultiEndpointClient(config *MultiEndpointConfig) (*Client, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoint specified")
+	}
+
+	var cache *responseCache
+	if config.Cache != nil {
+		var err error
+		cache, err = newResponseCache(config.Cache)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the nodes are checked by the health checks and before their first request, so that a node
+	// down at startup does not fail the client
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := &endpointPool{
+		endpoints: make([]*endpoint, 0, len(config.Endpoints)),
+		interval:  time.Duration(config.HealthCheckInterval) * time.Millisecond,
+		maxTipLag: config.MaxTipLag,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	for _, endpointConfig := range config.Endpoints {
+		client, err := newClient(endpointConfig)
+		if err != nil {
+			pool.close()
+			return nil, fmt.Errorf("fail to create client for endpoint %s: %v", endpointConfig.Endpoint, err)
+		}
+		pool.endpoints = append(pool.endpoints, &endpoint{client: client})
+	}
+
+	pool.checkHealth()
+	if pool.interval > 0 {
+		pool.wg.Add(1)
+		go pool.healthCheckHandler()
+	}
+
+	return &Client{
+		readTimeout:      pool.endpoints[0].client.readTimeout,
+		broadcastTimeout: pool.endpoints[0].client.broadcastTimeout,
+		cache:            cache,
+		pool:             pool,
+	}, nil
+}
+
+func (pool *endpointPool) healthCheckHandler() {
+	defer pool.wg.Done()
+
+	ticker := time.NewTicker(pool.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			pool.checkHealth()
+		case <-pool.ctx.Done():
+			return
+		}
+	}
+}
+
+// checkHealth queries getinfo on all endpoints concurrently.
+func (pool *endpointPool) checkHealth() {
+	wg := sync.WaitGroup{}
+	for _, ep := range pool.endpoints {
+		wg.Add(1)
+		go func(ep *endpoint) {
+			defer wg.Done()
+			info, err := ep.client.GetChainInfoCtx(pool.ctx)
+			if err == nil {
+				err = ep.client.verifyNode(pool.ctx)
+			}
+			if pool.ctx.Err() != nil {
+				return
+			}
+
+			ep.mtx.Lock()
+			ep.lastCheck = time.Now()
+			ep.mtx.Unlock()
+			if err != nil {
+				ep.markFailure(err)
+				return
+			}
+			ep.mtx.Lock()
+			ep.tip = info.NumBlocks
+			ep.mtx.Unlock()
+			ep.markSuccess()
+		}(ep)
+	}
+	wg.Wait()
+}
+
+// candidates returns the endpoints in the order they should be tried: the healthy ones close
+// to the highest tip in round-robin order, then the other healthy ones, then the unhealthy ones.
+func (pool *endpointPool) candidates() []*endpoint {
+	statuses := make([]*EndpointStatus, len(pool.endpoints))
+	maxTip := int64(-1)
+	for i, ep := range pool.endpoints {
+		statuses[i] = ep.status()
+		if statuses[i].Healthy && statuses[i].Tip > maxTip {
+			maxTip = statuses[i].Tip
+		}
+	}
+
+	preferred := make([]*endpoint, 0, len(pool.endpoints))
+	lagging := make([]*endpoint, 0, len(pool.endpoints))
+	unhealthy := make([]*endpoint, 0, len(pool.endpoints))
+	for i, ep := range pool.endpoints {
+		switch {
+		case !statuses[i].Healthy:
+			unhealthy = append(unhealthy, ep)
+		case statuses[i].Tip+pool.maxTipLag >= maxTip:
+			preferred = append(preferred, ep)
+		default:
+			lagging = append(lagging, ep)
+		}
+	}
+
+	candidates := make([]*endpoint, 0, len(pool.endpoints))
+	if len(preferred) > 0 {
+		offset := int(pool.next.Add(1) % uint64(len(preferred)))
+		candidates = append(candidates, preferred[offset:]...)
+		candidates = append(candidates, preferred[:offset]...)
+	}
+	return append(append(candidates, lagging...), unhealthy...)
+}
+
+// do sends the request of methods to the candidates until one of them answers. A request which
+// failed after it was sent goes to the next candidate only if all its methods can be sent again,
+// so that a broadcast which may have reached a node is never sent to another one.
+func (pool *endpointPool) do(ctx context.Context, methods []string, call func(ep *endpoint) error) error {
+	lastErr := ErrNoEndpoint
+	for _, ep := range pool.candidates() {
+		err := ep.client.verifyNode(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			ep.markFailure(err)
+			sdkLog.Warnf("endpoint %s failed the node check: %v, fail over to next endpoint", ep.client.Endpoint, err)
+			lastErr = err
+			continue
+		}
+		err = call(ep)
+		if err == nil {
+			ep.markSuccess()
+			return nil
+		}
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) {
+			ep.markSuccess()
+			return err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		ep.markFailure(err)
+		if isPartialResult(err) || !resendable(methods) {
+			return err
+		}
+		sdkLog.Warnf("request to endpoint %s failed: %v, fail over to next endpoint", ep.client.Endpoint, err)
+		lastErr = err
+	}
+	return lastErr
+}
+
+// resendable reports whether all methods can be sent again without side effects.
+func resendable(methods []string) bool {
+	for _, method := range methods {
+		if !retryableMethods[method] {
+			return false
+		}
+	}
+	return true
+}
+
+func (pool *endpointPool) close() {
+	pool.cancel()
+	pool.wg.Wait()
+	for _, ep := range pool.endpoints {
+		ep.client.Close()
+	}
+}
+
+// stickyEndpoint binds a client to the first endpoint which served it.
+type stickyEndpoint struct {
+	mtx sync.Mutex
+	ep  *endpoint
+}
+
+// Sticky returns a client which sends all its requests to the same node of the multi-endpoint
+// client, e.g. for SendRawTx followed by GetRawMempool. The node is the one serving the first
+// request; once bound, failures are returned instead of failing over.
+// For a single-endpoint client, Sticky returns the client itself.
+func (client *Client) Sticky() *Client {
+	if client.pool == nil {
+		return client
+	}
+	return &Client{
+		readTimeout:      client.readTimeout,
+		broadcastTimeout: client.broadcastTimeout,
+		retryPolicy:      client.retryPolicy,
+		cache:            client.cache,
+		pool:             client.pool,
+		sticky:           &stickyEndpoint{},
+	}
+}
+
+// doPool sends the request of methods through the endpoint pool, honoring the sticky binding if any.
+func (client *Client) doPool(ctx context.Context, methods []string, call func(ep *endpoint) error) error {
+	if client.sticky == nil {
+		return client.pool.do(ctx, methods, call)
+	}
+
+	client.sticky.mtx.Lock()
+	stickyEp := client.sticky.ep
+	client.sticky.mtx.Unlock()
+	if stickyEp != nil {
+		return call(stickyEp)
+	}
+	return client.pool.do(ctx, methods, func(ep *endpoint) error {
+		err := call(ep)
+		var rpcErr *RPCError
+		if err == nil || errors.As(err, &rpcErr) {
+			client.sticky.mtx.Lock()
+			if client.sticky.ep == nil {
+				client.sticky.ep = ep
+			}
+			client.sticky.mtx.Unlock()
+		}
+		return err
+	})
+}
+
+// EndpointStatus returns the health of the endpoints of a multi-endpoint client.
+func (client *Client) EndpointStatus() []*EndpointStatus {
+	if client.pool == nil {
+		return nil
+	}
+	statuses := make([]*EndpointStatus, len(client.pool.endpoints))
+	for i, ep := range client.pool.endpoints {
+		statuses[i] = ep.status()
+	}
+	return statuses
+}
@@ -0,0 +1,311 @@
+package abelian_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+// testNode is a node answering getinfo with its tip and getblockhash with its name.
+type testNode struct {
+	*httptest.Server
+	name     string
+	tip      atomic.Int64
+	status   atomic.Int32 // http status of the answers if not 0
+	rpcError atomic.Bool  // answer getblockhash with an RPC error
+	calls    atomic.Int32 // getblockhash calls
+}
+
+func newTestNode(t *testing.T, name string, tip int64) *testNode {
+	t.Helper()
+	node := &testNode{name: name}
+	node.tip.Store(tip)
+	node.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status := node.status.Load(); status != 0 {
+			http.Error(w, http.StatusText(int(status)), int(status))
+			return
+		}
+		req := &abelian.JSONRPCRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := map[string]any{"id": req.ID, "result": nil, "error": nil}
+		switch {
+		case req.Method == "getinfo":
+			resp["result"] = map[string]any{"blocks": node.tip.Load()}
+		case req.Method == "getblockhash" && node.rpcError.Load():
+			node.calls.Add(1)
+			resp["error"] = &abelian.RPCError{Code: -1, Message: "Block number out of range"}
+		case req.Method == "getblockhash":
+			node.calls.Add(1)
+			resp["result"] = node.name
+		default:
+			resp["error"] = &abelian.RPCError{Code: -32601, Message: "Method not found"}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(node.Close)
+	return node
+}
+
+func newMultiEndpointClient(t *testing.T, configs ...*abelian.ClientConfig) *abelian.Client {
+	t.Helper()
+	// no periodic health checks, so that the tests see the state of the first one
+	client, err := abelian.NewMultiEndpointClient(abelian.NewMultiEndpointConfig(configs, abelian.WithHealthCheckInterval(0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// servedBy returns the names of the nodes answering n calls of client.
+func servedBy(t *testing.T, client *abelian.Client, n int) []string {
+	t.Helper()
+	seen := map[string]bool{}
+	for i := 0; i < n; i++ {
+		name, err := client.GetBlockHash(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[name] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestMultiEndpointRouting(t *testing.T) {
+	tests := []struct {
+		name    string
+		tips    [2]int64
+		failing [2]bool
+		want    []string
+	}{
+		{"round robin", [2]int64{10, 10}, [2]bool{}, []string{"a", "b"}},
+		{"within the tip lag", [2]int64{10, 8}, [2]bool{}, []string{"a", "b"}},
+		{"lagging node", [2]int64{10, 5}, [2]bool{}, []string{"a"}},
+		{"failing node", [2]int64{10, 10}, [2]bool{true, false}, []string{"b"}},
+		{"failing node ahead", [2]int64{5, 10}, [2]bool{false, true}, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := newTestNode(t, "a", tt.tips[0]), newTestNode(t, "b", tt.tips[1])
+			for i, node := range []*testNode{a, b} {
+				if tt.failing[i] {
+					node.status.Store(http.StatusServiceUnavailable)
+				}
+			}
+			client := newMultiEndpointClient(t, abelian.NewClientConfig(a.URL), abelian.NewClientConfig(b.URL))
+			if names := servedBy(t, client, 6); !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("served by %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiEndpointFailover(t *testing.T) {
+	a, b := newTestNode(t, "a", 10), newTestNode(t, "b", 10)
+	client := newMultiEndpointClient(t, abelian.NewClientConfig(a.URL), abelian.NewClientConfig(b.URL))
+
+	// a node failing after the health check is failed over and marked unhealthy
+	a.status.Store(http.StatusServiceUnavailable)
+	if names := servedBy(t, client, 4); !reflect.DeepEqual(names, []string{"b"}) {
+		t.Errorf("served by %v, want b", names)
+	}
+	statuses := client.EndpointStatus()
+	var httpErr *abelian.HTTPError
+	if statuses[0].Healthy || !errors.As(statuses[0].LastError, &httpErr) || !statuses[1].Healthy {
+		t.Errorf("statuses = %+v, %+v, want a unhealthy with its http error and b healthy", statuses[0], statuses[1])
+	}
+
+	b.status.Store(http.StatusServiceUnavailable)
+	if _, err := client.GetBlockHash(0); !errors.As(err, &httpErr) {
+		t.Errorf("GetBlockHash error = %v with both nodes failing, want *HTTPError", err)
+	}
+
+	// an RPC error is an answer, which is not failed over
+	a.status.Store(0)
+	b.status.Store(0)
+	a.rpcError.Store(true)
+	b.rpcError.Store(true)
+	calls := a.calls.Load() + b.calls.Load()
+	var rpcErr *abelian.RPCError
+	if _, err := client.GetBlockHash(0); !errors.As(err, &rpcErr) {
+		t.Errorf("GetBlockHash error = %v, want *RPCError", err)
+	}
+	if n := a.calls.Load() + b.calls.Load() - calls; n != 1 {
+		t.Errorf("RPC error sent to %d nodes, want 1", n)
+	}
+}
+
+func TestMultiEndpointSticky(t *testing.T) {
+	a, b := newTestNode(t, "a", 10), newTestNode(t, "b", 10)
+	client := newMultiEndpointClient(t, abelian.NewClientConfig(a.URL), abelian.NewClientConfig(b.URL))
+
+	sticky := client.Sticky()
+	names := servedBy(t, sticky, 6)
+	if len(names) != 1 {
+		t.Fatalf("sticky client served by %v, want one node", names)
+	}
+
+	// once bound, the failures of the node are returned
+	bound := a
+	if names[0] == "b" {
+		bound = b
+	}
+	bound.status.Store(http.StatusServiceUnavailable)
+	if _, err := sticky.GetBlockHash(0); err == nil {
+		t.Error("sticky client failed over from its node")
+	}
+
+	single, err := abelian.NewClient(abelian.NewClientConfig(a.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer single.Close()
+	if single.Sticky() != single {
+		t.Error("Sticky of a single-endpoint client is not the client itself")
+	}
+}
+
+func TestMultiEndpointNodeDown(t *testing.T) {
+	down := abeliantest.NewServer()
+	downConfig := down.ClientConfig(abelian.WithNetworkID(abelian.MainNet))
+	down.Close()
+	up := abeliantest.NewServer()
+	defer up.Close()
+
+	client := newMultiEndpointClient(t, downConfig, up.ClientConfig(abelian.WithNetworkID(abelian.MainNet)))
+	statuses := client.EndpointStatus()
+	if statuses[0].Healthy || !statuses[1].Healthy {
+		t.Errorf("healthy = %v, %v, want false, true", statuses[0].Healthy, statuses[1].Healthy)
+	}
+	if _, err := client.GetBlockCount(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMultiEndpointNetworkMismatch(t *testing.T) {
+	testnet := abeliantest.NewServer(abeliantest.WithNetID(abelian.TestNet))
+	defer testnet.Close()
+	mainnet := abeliantest.NewServer()
+	defer mainnet.Close()
+
+	client := newMultiEndpointClient(t,
+		testnet.ClientConfig(abelian.WithNetworkID(abelian.MainNet)),
+		mainnet.ClientConfig(abelian.WithNetworkID(abelian.MainNet)),
+	)
+	statuses := client.EndpointStatus()
+	if statuses[0].Healthy || !errors.Is(statuses[0].LastError, abelian.ErrNetworkMismatch) {
+		t.Errorf("status of the testnet endpoint = %+v, want unhealthy with a network mismatch", statuses[0])
+	}
+
+	// a failure of the mainnet endpoint is not failed over to the testnet one
+	mainnet.InjectFault("getblockcount", &abeliantest.Fault{Drop: true}, 1)
+	if _, err := client.GetBlockCount(); !errors.Is(err, abelian.ErrNetworkMismatch) {
+		t.Errorf("GetBlockCount error = %v, want the network mismatch of the last endpoint", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetBlockCount(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := testnet.CallCount("getblockcount"); calls != 0 {
+		t.Errorf("getblockcount calls on the testnet endpoint = %d, want 0", calls)
+	}
+
+	txHex, _ := testTx(t, 1)
+	if _, err := client.SendRawTx(txHex); err != nil {
+		t.Fatal(err)
+	}
+	if calls := testnet.CallCount("sendrawtransactionabe"); calls != 0 {
+		t.Errorf("sendrawtransactionabe calls on the testnet endpoint = %d, want 0", calls)
+	}
+}
+
+func TestSendRawTxNetworkMismatch(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	client, err := server.NewClient(abelian.WithNetworkID(abelian.MainNet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// the endpoint now reaches a testnet node
+	server.SetChainInfo(abelian.ChainInfo{NetID: uint8(abelian.TestNet)})
+	txHex, _ := testTx(t, 1)
+	_, err = client.SendRawTx(txHex)
+	var mismatchErr *abelian.NetworkMismatchError
+	if !errors.As(err, &mismatchErr) || mismatchErr.Actual != abelian.TestNet {
+		t.Fatalf("SendRawTx error = %v, want network mismatch with testnet", err)
+	}
+	if calls := server.CallCount("sendrawtransactionabe"); calls != 0 {
+		t.Errorf("sendrawtransactionabe calls = %d, want 0", calls)
+	}
+}
+
+func TestNewClientNetworkMismatch(t *testing.T) {
+	server := abeliantest.NewServer(abeliantest.WithNetID(abelian.TestNet))
+	defer server.Close()
+	_, err := server.NewClient(abelian.WithNetworkID(abelian.MainNet))
+	if !errors.Is(err, abelian.ErrNetworkMismatch) {
+		t.Fatalf("NewClient error = %v, want ErrNetworkMismatch", err)
+	}
+}
+
+func TestMultiEndpointBroadcastNotFailedOver(t *testing.T) {
+	txHex, _ := testTx(t, 1)
+	tests := []struct {
+		name      string
+		method    string // method failing on both nodes once the request is sent
+		call      func(client *abelian.Client) error
+		wantCalls int
+	}{
+		{"read", "getblockhash", func(client *abelian.Client) error {
+			_, err := client.GetBlockHash(0)
+			return err
+		}, 2},
+		{"broadcast", "sendrawtransactionabe", func(client *abelian.Client) error {
+			_, err := client.SendRawTx(txHex)
+			return err
+		}, 1},
+		{"batch with a broadcast", "sendrawtransactionabe", func(client *abelian.Client) error {
+			_, err := client.Batch().GetBlockHash(0).Call("sendrawtransactionabe", []interface{}{txHex}).Send(context.Background())
+			return err
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := abeliantest.NewServer(abeliantest.WithBatch()), abeliantest.NewServer(abeliantest.WithBatch())
+			defer a.Close()
+			defer b.Close()
+			client := newMultiEndpointClient(t, a.ClientConfig(), b.ClientConfig())
+
+			// the connection is dropped after the node got the request, which may have been processed
+			for _, server := range []*abeliantest.Server{a, b} {
+				server.InjectFault(tt.method, &abeliantest.Fault{Drop: true}, 1)
+			}
+			if err := tt.call(client); err == nil {
+				t.Fatal("request dropped by both nodes succeeded")
+			}
+			if calls := a.CallCount(tt.method) + b.CallCount(tt.method); calls != tt.wantCalls {
+				t.Errorf("%s sent to %d nodes, want %d", tt.method, calls, tt.wantCalls)
+			}
+		})
+	}
+}
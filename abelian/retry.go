@@ -0,0 +1,163 @@
+package abelian
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	DEFAULT_RETRY_MAX_ATTEMPTS    = 5
+	DEFAULT_RETRY_INITIAL_BACKOFF = 200 * time.Millisecond
+	DEFAULT_RETRY_MAX_BACKOFF     = 10 * time.Second
+	DEFAULT_RETRY_MULTIPLIER      = 2.0
+	DEFAULT_RETRY_JITTER          = 0.2
+)
+
+// RetryClassifier reports whether a call of method which failed with err can be attempted again.
+type RetryClassifier func(method string, err error) bool
+
+// RetryPolicy describes how failed RPC calls are retried with exponential backoff.
+type RetryPolicy struct {
+	MaxAttempts    int           // total number of attempts including the first one
+	InitialBackoff time.Duration // delay before the second attempt
+	MaxBackoff     time.Duration // upper bound of the delay between two attempts
+	Multiplier     float64       // factor the delay grows by after each attempt
+	Jitter         float64       // fraction in [0, 1] of the delay which is randomized
+	Classifier     RetryClassifier
+}
+
+// DefaultRetryPolicy returns a policy retrying idempotent methods on transient failures.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    DEFAULT_RETRY_MAX_ATTEMPTS,
+		InitialBackoff: DEFAULT_RETRY_INITIAL_BACKOFF,
+		MaxBackoff:     DEFAULT_RETRY_MAX_BACKOFF,
+		Multiplier:     DEFAULT_RETRY_MULTIPLIER,
+		Jitter:         DEFAULT_RETRY_JITTER,
+		Classifier:     IsRetryable,
+	}
+}
+
+// retryableMethods are the methods which can be sent again without side effects.
+var retryableMethods = map[string]bool{
+	"getinfo":            true,
+	"getblockhash":       true,
+	"getblock":           true,
+	"getblockabe":        true,
+	"getrawtransaction":  true,
+	"getrawmempool":      true,
+	"getbestblockhash":   true,
+	"getblockcount":      true,
+	"getblockheader":     true,
+	"getblockchaininfo":  true,
+	"getmempoolinfo":     true,
+	"getdifficulty":      true,
+	"getutxoring":        true,
+	"getblocktemplate":   true,
+	"getmininginfo":      true,
+	"getpeerinfo":        true,
+	"getconnectioncount": true,
+	"getnettotals":       true,
+	"uptime":             true,
+}
+
+// IsRetryable is the default RetryClassifier.
+// Reads such as getblockabe and getrawtransaction are retried on any transient failure.
+// Broadcasts are never sent again: the only failure of sendrawtransactionabe which is safe is
+// ErrTxAlreadyKnown, which SendRawTx turns into a success without another attempt.
+// Other methods are never retried.
+func IsRetryable(method string, err error) bool {
+	if !retryableMethods[method] {
+		return false
+	}
+	return isTransientError(err)
+}
+
+func isTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		// the node is still loading the chain
+		return errors.Is(err, ErrWarmingUp)
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= http.StatusInternalServerError ||
+			httpErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// backoff returns the delay after the specified failed attempt, starting from 1.
+func (policy *RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := policy.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(policy.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if policy.MaxBackoff > 0 && delay > float64(policy.MaxBackoff) {
+		delay = float64(policy.MaxBackoff)
+	}
+	if policy.Jitter > 0 {
+		jitter := math.Min(policy.Jitter, 1)
+		delay -= delay * jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// do runs call until it succeeds, the error is not retryable, the attempts are exhausted or ctx is done.
+func (policy *RetryPolicy) do(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := call(ctx)
+		if err == nil {
+			return nil
+		}
+		if attempt >= policy.MaxAttempts || ctx.Err() != nil || isPartialResult(err) || !policy.isRetryable(method, err) {
+			return err
+		}
+
+		delay := policy.backoff(attempt)
+		sdkLog.Warnf("request method %s failed (attempt %d/%d): %v, retry in %v", method, attempt, policy.MaxAttempts, err, delay)
+		if policy.sleep(ctx, delay) != nil {
+			return err
+		}
+	}
+}
+
+// isRetryable applies the classifier of the policy, IsRetryable if it has none.
+func (policy *RetryPolicy) isRetryable(method string, err error) bool {
+	if policy.Classifier == nil {
+		return IsRetryable(method, err)
+	}
+	return policy.Classifier(method, err)
+}
+
+// sleep waits for delay, or returns the error of ctx if it is done before.
+func (policy *RetryPolicy) sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
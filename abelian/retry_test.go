@@ -0,0 +1,109 @@
+package abelian_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pqabelian/abec/wire"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+func testRetryPolicy() *abelian.RetryPolicy {
+	policy := abelian.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+// testTx returns a serialized transaction which the fake server and the SDK both give its real id.
+func testTx(t *testing.T, height int32) (string, string) {
+	t.Helper()
+	msgTx := wire.NewMsgTxAbe(wire.TxVersion)
+	txIn, err := wire.NewStandardCoinbaseTxIn(height, wire.TxVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgTx.AddTxIn(txIn)
+	var buf bytes.Buffer
+	if err := msgTx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(buf.Bytes()), msgTx.TxHash().String()
+}
+
+func TestRetryReads(t *testing.T) {
+	tests := []struct {
+		name      string
+		fault     *abeliantest.Fault
+		times     int
+		wantErr   bool
+		wantCalls int
+	}{
+		{"transient http error", &abeliantest.Fault{HTTPStatus: http.StatusServiceUnavailable}, 2, false, 3},
+		{"dropped connection", &abeliantest.Fault{Drop: true}, 1, false, 2},
+		{"warming up", &abeliantest.Fault{RPCError: &abelian.RPCError{Code: abelian.RPC_ERR_IN_WARMUP, Message: "warmup"}}, 1, false, 2},
+		{"permanent rpc error", &abeliantest.Fault{RPCError: &abelian.RPCError{Code: abelian.RPC_ERR_INVALID_PARAMETER, Message: "bad"}}, 1, true, 1},
+		{"attempts exhausted", &abeliantest.Fault{HTTPStatus: http.StatusBadGateway}, -1, true, abelian.DEFAULT_RETRY_MAX_ATTEMPTS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := abeliantest.NewServer()
+			defer server.Close()
+			client, err := server.NewClient(abelian.WithRetryPolicy(testRetryPolicy()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			server.InjectFault("getblockcount", tt.fault, tt.times)
+			_, err = client.GetBlockCount()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetBlockCount error = %v, want error %v", err, tt.wantErr)
+			}
+			if calls := server.CallCount("getblockcount"); calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryBroadcast(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	client, err := server.NewClient(abelian.WithRetryPolicy(testRetryPolicy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// a broadcast is not sent again after a transient failure
+	txHex, txID := testTx(t, 1)
+	server.InjectFault("sendrawtransactionabe", &abeliantest.Fault{HTTPStatus: http.StatusServiceUnavailable}, 1)
+	_, err = client.SendRawTx(txHex)
+	var httpErr *abelian.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("SendRawTx error = %v, want *HTTPError", err)
+	}
+	if calls := server.CallCount("sendrawtransactionabe"); calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+
+	res, err := client.SendRawTx(txHex)
+	if err != nil || res != txID {
+		t.Fatalf("SendRawTx = %q, %v, want %s", res, err, txID)
+	}
+	// a transaction the node already has is a success with its id
+	res, err = client.SendRawTx(txHex)
+	if err != nil || res != txID {
+		t.Fatalf("SendRawTx of a known transaction = %q, %v, want %s", res, err, txID)
+	}
+	if calls := server.CallCount("sendrawtransactionabe"); calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
@@ -0,0 +1,376 @@
+package abelian
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	DEFAULT_RING_GROUP_CONCURRENCY = 4  // blocks of a group fetched in parallel
+	DEFAULT_RING_GROUP_CACHE_SIZE  = 16 // groups kept in memory
+)
+
+// RingGroupConfig configures a RingGroupProvider.
+type RingGroupConfig struct {
+	Concurrency int    // blocks of a group fetched in parallel, default DEFAULT_RING_GROUP_CONCURRENCY
+	CacheSize   int    // groups kept in memory with their coin rings, default DEFAULT_RING_GROUP_CACHE_SIZE
+	Dir         string // directory of the on-disk store of the groups, empty to keep them in memory only
+}
+
+func NewRingGroupConfig(options ...RingGroupOption) *RingGroupConfig {
+	config := &RingGroupConfig{
+		Concurrency: DEFAULT_RING_GROUP_CONCURRENCY,
+		CacheSize:   DEFAULT_RING_GROUP_CACHE_SIZE,
+	}
+
+	for _, opt := range options {
+		opt(config)
+	}
+
+	return config
+}
+
+// RingGroupOption change ring group config
+type RingGroupOption func(*RingGroupConfig)
+
+// WithRingGroupConcurrency sets the number of blocks of a group fetched in parallel.
+func WithRingGroupConcurrency(concurrency int) RingGroupOption {
+	return func(config *RingGroupConfig) {
+		config.Concurrency = concurrency
+	}
+}
+
+// WithRingGroupCacheSize sets the number of groups kept in memory.
+func WithRingGroupCacheSize(size int) RingGroupOption {
+	return func(config *RingGroupConfig) {
+		config.CacheSize = size
+	}
+}
+
+// WithRingGroupDir enables the on-disk store of the groups in dir. The store is not bounded in size.
+func WithRingGroupDir(dir string) RingGroupOption {
+	return func(config *RingGroupConfig) {
+		config.Dir = dir
+	}
+}
+
+// RingGroup is the serialized blocks of a ring group, from which the TXO rings of the group are built.
+type RingGroup struct {
+	FirstHeight int64    `json:"firstHeight"`
+	BlockHashes []string `json:"blockHashes"`
+	Blocks      [][]byte `json:"blocks"`
+}
+
+// TxRingBlockDescs returns the blocks of the group as expected by TxDesc.
+func (group *RingGroup) TxRingBlockDescs() map[int64]*TxBlockDesc {
+	descs := make(map[int64]*TxBlockDesc, len(group.Blocks))
+	for i, blockBytes := range group.Blocks {
+		height := group.FirstHeight + int64(i)
+		descs[height] = NewTxBlockDesc(blockBytes, height)
+	}
+	return descs
+}
+
+func (group *RingGroup) lastHeight() int64 {
+	return group.FirstHeight + int64(len(group.Blocks)) - 1
+}
+
+func (group *RingGroup) sameBlocks(other *RingGroup) bool {
+	if group.FirstHeight != other.FirstHeight || len(group.BlockHashes) != len(other.BlockHashes) {
+		return false
+	}
+	for i := range group.BlockHashes {
+		if group.BlockHashes[i] != other.BlockHashes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type ringGroupEntry struct {
+	group *RingGroup
+	rings []*CoinRing // nil until built
+}
+
+// RingGroupProvider fetches the ring groups of a client, and caches them with the coin rings
+// built from them. HandleCoinMaturity-like scans and GenerateUnsignedRawTx need the same groups
+// again and again, so share one provider between them.
+//
+// A cached group is checked against the hash of its last block on the node before it is served,
+// so that a group changed by a reorganization is fetched again.
+type RingGroupProvider struct {
+	client      *Client
+	concurrency int
+	cacheSize   int
+	dir         string
+
+	mtx     sync.Mutex
+	lru     *list.List
+	entries map[int64]*list.Element // by first height
+}
+
+func NewRingGroupProvider(client *Client, config *RingGroupConfig) (*RingGroupProvider, error) {
+	if config == nil {
+		config = NewRingGroupConfig()
+	}
+	if config.Dir != "" {
+		err := os.MkdirAll(config.Dir, 0o755)
+		if err != nil {
+			return nil, fmt.Errorf("fail to create ring group directory: %v", err)
+		}
+	}
+	return &RingGroupProvider{
+		client:      client,
+		concurrency: max(config.Concurrency, 1),
+		cacheSize:   max(config.CacheSize, 1),
+		dir:         config.Dir,
+		lru:         list.New(),
+		entries:     map[int64]*list.Element{},
+	}, nil
+}
+
+// GetRingGroup returns the ring group of the block at height.
+func (provider *RingGroupProvider) GetRingGroup(height int64) (*RingGroup, error) {
+	return provider.GetRingGroupCtx(context.Background(), height)
+}
+func (provider *RingGroupProvider) GetRingGroupCtx(ctx context.Context, height int64) (*RingGroup, error) {
+	entry, err := provider.entry(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	return entry.group, nil
+}
+
+// GetCoinRings returns the coin rings built by BuildCoinRings from the ring group of the block at height.
+func (provider *RingGroupProvider) GetCoinRings(height int64) ([]*CoinRing, error) {
+	return provider.GetCoinRingsCtx(context.Background(), height)
+}
+func (provider *RingGroupProvider) GetCoinRingsCtx(ctx context.Context, height int64) ([]*CoinRing, error) {
+	entry, err := provider.entry(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	return provider.coinRings(entry.group)
+}
+
+// BuildCoinRings is BuildCoinRings for a group returned by the provider, reusing the rings
+// already built for the same blocks.
+func (provider *RingGroupProvider) BuildCoinRings(group *RingGroup) ([]*CoinRing, error) {
+	return provider.coinRings(group)
+}
+
+func (provider *RingGroupProvider) coinRings(group *RingGroup) ([]*CoinRing, error) {
+	provider.mtx.Lock()
+	elem, ok := provider.entries[group.FirstHeight]
+	if ok {
+		entry := elem.Value.(*ringGroupEntry)
+		if entry.rings != nil && entry.group.sameBlocks(group) {
+			provider.mtx.Unlock()
+			return entry.rings, nil
+		}
+	}
+	provider.mtx.Unlock()
+
+	rings, err := BuildCoinRings(group.Blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	provider.mtx.Lock()
+	defer provider.mtx.Unlock()
+	if elem, ok := provider.entries[group.FirstHeight]; ok {
+		entry := elem.Value.(*ringGroupEntry)
+		if entry.group.sameBlocks(group) {
+			entry.rings = rings
+		}
+	}
+	return rings, nil
+}
+
+// Invalidate drops the cached groups with blocks at or above height, e.g. after a reorganization.
+func (provider *RingGroupProvider) Invalidate(height int64) {
+	provider.mtx.Lock()
+	defer provider.mtx.Unlock()
+	for firstHeight, elem := range provider.entries {
+		entry := elem.Value.(*ringGroupEntry)
+		if entry.group.lastHeight() >= height {
+			provider.lru.Remove(elem)
+			delete(provider.entries, firstHeight)
+		}
+	}
+}
+
+// entry returns the cached group of height if it is still on the chain of the node,
+// and fetches it otherwise.
+func (provider *RingGroupProvider) entry(ctx context.Context, height int64) (*ringGroupEntry, error) {
+	params, err := provider.client.ChainParamsCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	firstHeight := params.RingGroupFirstHeight(height)
+
+	provider.mtx.Lock()
+	var entry *ringGroupEntry
+	if elem, ok := provider.entries[firstHeight]; ok {
+		provider.lru.MoveToFront(elem)
+		entry = elem.Value.(*ringGroupEntry)
+	}
+	provider.mtx.Unlock()
+
+	if entry == nil {
+		group := provider.load(firstHeight)
+		if group != nil {
+			entry = &ringGroupEntry{group: group}
+		}
+	}
+	if entry != nil {
+		lastHash, err := provider.client.GetBlockHashCtx(ctx, entry.group.lastHeight())
+		if err != nil {
+			return nil, fmt.Errorf("fail to get block group: %v", err)
+		}
+		if lastHash == entry.group.BlockHashes[len(entry.group.BlockHashes)-1] {
+			provider.add(entry)
+			return entry, nil
+		}
+		sdkLog.Infof("ring group at height %d changed on the node, fetch it again", firstHeight)
+	}
+
+	group, err := fetchRingGroup(ctx, provider.client, params, height, provider.concurrency)
+	if err != nil {
+		return nil, err
+	}
+	entry = &ringGroupEntry{group: group}
+	provider.add(entry)
+	provider.store(group)
+	return entry, nil
+}
+
+// add inserts entry into memory, replacing the one of the same group if any and evicting the least
+// recently used ones.
+func (provider *RingGroupProvider) add(entry *ringGroupEntry) {
+	provider.mtx.Lock()
+	defer provider.mtx.Unlock()
+	if elem, ok := provider.entries[entry.group.FirstHeight]; ok {
+		if elem.Value.(*ringGroupEntry) == entry {
+			return
+		}
+		provider.lru.Remove(elem)
+	}
+	provider.entries[entry.group.FirstHeight] = provider.lru.PushFront(entry)
+	for provider.lru.Len() > provider.cacheSize {
+		elem := provider.lru.Back()
+		provider.lru.Remove(elem)
+		delete(provider.entries, elem.Value.(*ringGroupEntry).group.FirstHeight)
+	}
+}
+
+func (provider *RingGroupProvider) path(firstHeight int64) string {
+	return filepath.Join(provider.dir, fmt.Sprintf("ringgroup-%d.json", firstHeight))
+}
+
+// load reads the group at firstHeight from disk, or returns nil.
+func (provider *RingGroupProvider) load(firstHeight int64) *RingGroup {
+	if provider.dir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(provider.path(firstHeight))
+	if err != nil {
+		return nil
+	}
+	group := &RingGroup{}
+	err = json.Unmarshal(data, group)
+	if err != nil || group.FirstHeight != firstHeight || len(group.Blocks) == 0 || len(group.Blocks) != len(group.BlockHashes) {
+		sdkLog.Warnf("fail to load ring group at height %d, ignore it: %v", firstHeight, err)
+		return nil
+	}
+	return group
+}
+
+func (provider *RingGroupProvider) store(group *RingGroup) {
+	if provider.dir == "" {
+		return
+	}
+	data, err := json.Marshal(group)
+	if err == nil {
+		err = writeFileAtomic(provider.path(group.FirstHeight), data)
+	}
+	if err != nil {
+		sdkLog.Warnf("fail to write ring group at height %d: %v", group.FirstHeight, err)
+	}
+}
+
+// fetchRingGroup fetches the blocks of the ring group of height, concurrency at a time, and checks
+// that each of them follows the previous one.
+func fetchRingGroup(ctx context.Context, client *Client, params *ChainParams, height int64, concurrency int) (*RingGroup, error) {
+	blockNum := int(params.BlockNumPerRingGroupByBlockHeight(height))
+	group := &RingGroup{
+		FirstHeight: params.RingGroupFirstHeight(height),
+		BlockHashes: make([]string, blockNum),
+		Blocks:      make([][]byte, blockNum),
+	}
+	prevHashes := make([]string, blockNum)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	heights := make(chan int, blockNum)
+	for i := 0; i < blockNum; i++ {
+		heights <- i
+	}
+	close(heights)
+
+	errOnce := sync.Once{}
+	var fetchErr error
+	wg := sync.WaitGroup{}
+	for w := 0; w < min(concurrency, blockNum); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range heights {
+				err := fetchRingGroupBlock(ctx, client, group, prevHashes, i)
+				if err != nil {
+					errOnce.Do(func() {
+						fetchErr = err
+						cancel()
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if fetchErr != nil {
+		return nil, fmt.Errorf("fail to get block group: %v", fetchErr)
+	}
+
+	for i := 1; i < blockNum; i++ {
+		if prevHashes[i] != group.BlockHashes[i-1] {
+			return nil, fmt.Errorf("fail to get block group: block %s at height %d does not follow block %s",
+				group.BlockHashes[i], group.FirstHeight+int64(i), group.BlockHashes[i-1])
+		}
+	}
+	return group, nil
+}
+
+func fetchRingGroupBlock(ctx context.Context, client *Client, group *RingGroup, prevHashes []string, i int) error {
+	blockHash, err := client.GetBlockHashCtx(ctx, group.FirstHeight+int64(i))
+	if err != nil {
+		return err
+	}
+	header, err := client.GetBlockHeaderCtx(ctx, blockHash)
+	if err != nil {
+		return err
+	}
+	blockBytes, err := client.GetBlockBytesCtx(ctx, blockHash)
+	if err != nil {
+		return err
+	}
+	group.BlockHashes[i] = blockHash
+	group.Blocks[i] = blockBytes
+	prevHashes[i] = header.PrevBlockHash
+	return nil
+}
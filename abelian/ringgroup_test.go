@@ -0,0 +1,253 @@
+package abelian_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+// newRingGroupServer returns a server of testChainParams with n blocks after the genesis block.
+func newRingGroupServer(t *testing.T, n int) *abeliantest.Server {
+	t.Helper()
+	testChainParams(t)
+	server := abeliantest.NewServer(abeliantest.WithNetID(testNetworkID))
+	for i := 0; i < n; i++ {
+		server.AddBlock()
+	}
+	return server
+}
+
+// groupHashes returns the hashes of the blocks of the server from height first to last.
+func groupHashes(server *abeliantest.Server, first int64, last int64) []string {
+	var hashes []string
+	for height := first; height <= last; height++ {
+		hashes = append(hashes, server.BlockByHeight(height).BlockHash)
+	}
+	return hashes
+}
+
+// inFlightCounter is a middleware measuring the calls of a method in flight at once, each held for delay.
+type inFlightCounter struct {
+	method string
+	delay  time.Duration
+
+	mtx      sync.Mutex
+	inFlight int
+	max      int
+}
+
+func (counter *inFlightCounter) middleware(next abelian.Invoker) abelian.Invoker {
+	return func(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+		if method != counter.method {
+			return next(ctx, method, params)
+		}
+		counter.mtx.Lock()
+		counter.inFlight++
+		counter.max = max(counter.max, counter.inFlight)
+		counter.mtx.Unlock()
+		time.Sleep(counter.delay)
+		defer func() {
+			counter.mtx.Lock()
+			counter.inFlight--
+			counter.mtx.Unlock()
+		}()
+		return next(ctx, method, params)
+	}
+}
+
+func TestRingGroupProviderFetch(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		wantMax     int // getblockabe calls at once
+	}{
+		{"sequential", 1, 1},
+		{"parallel", 4, 4},
+		{"parallel beyond the group size", 8, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRingGroupServer(t, 60)
+			defer server.Close()
+			counter := &inFlightCounter{method: "getblockabe", delay: 20 * time.Millisecond}
+			client, err := server.NewClient(abelian.WithMiddleware(counter.middleware))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+			provider, err := abelian.NewRingGroupProvider(client, abelian.NewRingGroupConfig(abelian.WithRingGroupConcurrency(tt.concurrency)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// groups of 4 blocks from height 50
+			group, err := provider.GetRingGroup(54)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := groupHashes(server, 52, 55); group.FirstHeight != 52 || !reflect.DeepEqual(group.BlockHashes, want) {
+				t.Errorf("group of 54 = %d %v, want 52 %v", group.FirstHeight, group.BlockHashes, want)
+			}
+			if len(group.Blocks) != 4 {
+				t.Errorf("%d blocks in the group, want 4", len(group.Blocks))
+			}
+			for i, blockBytes := range group.Blocks {
+				want, err := client.GetBlockBytes(group.BlockHashes[i])
+				if err != nil || !reflect.DeepEqual(blockBytes, want) {
+					t.Errorf("block %d of the group differs from the block %s", i, group.BlockHashes[i])
+				}
+			}
+			if counter.max != tt.wantMax {
+				t.Errorf("%d blocks fetched at once, want %d", counter.max, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestRingGroupProviderCache(t *testing.T) {
+	server := newRingGroupServer(t, 20)
+	defer server.Close()
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	provider, err := abelian.NewRingGroupProvider(client, abelian.NewRingGroupConfig(abelian.WithRingGroupCacheSize(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		height        int64
+		wantFirst     int64
+		wantBlockGets int // getblockabe calls of the step
+	}{
+		{3, 2, 2},
+		{2, 2, 0}, // same group
+		{5, 4, 2},
+		{3, 2, 0},
+		{7, 6, 2}, // evicts the group of 5, the least recently used
+		{3, 2, 0},
+		{5, 4, 2},
+	}
+	for _, step := range steps {
+		blockGets := server.CallCount("getblockabe")
+		hashChecks := server.CallCount("getblockhash")
+		group, err := provider.GetRingGroup(step.height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if group.FirstHeight != step.wantFirst || !reflect.DeepEqual(group.BlockHashes, groupHashes(server, step.wantFirst, step.wantFirst+1)) {
+			t.Errorf("group of %d = %d %v, want the blocks from %d", step.height, group.FirstHeight, group.BlockHashes, step.wantFirst)
+		}
+		if calls := server.CallCount("getblockabe") - blockGets; calls != step.wantBlockGets {
+			t.Errorf("group of %d: getblockabe calls = %d, want %d", step.height, calls, step.wantBlockGets)
+		}
+		// a cached group is checked by the hash of its last block
+		if calls := server.CallCount("getblockhash") - hashChecks; step.wantBlockGets == 0 && calls != 1 {
+			t.Errorf("group of %d: getblockhash calls = %d, want 1", step.height, calls)
+		}
+	}
+}
+
+func TestRingGroupProviderReorg(t *testing.T) {
+	server := newRingGroupServer(t, 10)
+	defer server.Close()
+	dir := t.TempDir()
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	provider, err := abelian.NewRingGroupProvider(client, abelian.NewRingGroupConfig(abelian.WithRingGroupDir(dir)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := provider.GetRingGroup(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the last block of the group changes between the fetches
+	server.Reorg(3)
+	for i := 0; i < 3; i++ {
+		server.AddBlock()
+	}
+	blockGets := server.CallCount("getblockabe")
+	after, err := provider.GetRingGroup(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := groupHashes(server, 8, 9); !reflect.DeepEqual(after.BlockHashes, want) || reflect.DeepEqual(after.BlockHashes, before.BlockHashes) {
+		t.Errorf("group of 8 after the reorganization = %v, want %v", after.BlockHashes, want)
+	}
+	if calls := server.CallCount("getblockabe") - blockGets; calls != 2 {
+		t.Errorf("getblockabe calls after the reorganization = %d, want 2", calls)
+	}
+
+	// a provider on the same directory serves the group fetched again from disk
+	other, err := abelian.NewRingGroupProvider(client, abelian.NewRingGroupConfig(abelian.WithRingGroupDir(dir)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockGets = server.CallCount("getblockabe")
+	stored, err := other.GetRingGroup(9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stored, after) {
+		t.Errorf("stored group = %v, want %v", stored.BlockHashes, after.BlockHashes)
+	}
+	if calls := server.CallCount("getblockabe") - blockGets; calls != 0 {
+		t.Errorf("getblockabe calls of a stored group = %d, want 0", calls)
+	}
+}
+
+func TestRingGroupProviderReorgDuringFetch(t *testing.T) {
+	server := newRingGroupServer(t, 10)
+	defer server.Close()
+	// the blocks from height 8 are replaced once the first block of the group of 8 is fetched
+	reorg := sync.Once{}
+	client, err := server.NewClient(abelian.WithMiddleware(func(next abelian.Invoker) abelian.Invoker {
+		return func(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+			res, err := next(ctx, method, params)
+			if method == "getblockabe" {
+				reorg.Do(func() {
+					server.Reorg(3)
+					for i := 0; i < 3; i++ {
+						server.AddBlock()
+					}
+				})
+			}
+			return res, err
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	provider, err := abelian.NewRingGroupProvider(client, abelian.NewRingGroupConfig(abelian.WithRingGroupConcurrency(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = provider.GetRingGroup(8)
+	if err == nil || !strings.Contains(err.Error(), "does not follow") {
+		t.Fatalf("group fetched across a reorganization = %v, want an error of linkage", err)
+	}
+	// the next fetch sees the new chain only
+	group, err := provider.GetRingGroup(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := groupHashes(server, 8, 9); !reflect.DeepEqual(group.BlockHashes, want) {
+		t.Errorf("group of 8 = %v, want %v", group.BlockHashes, want)
+	}
+}
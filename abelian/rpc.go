@@ -20,6 +20,16 @@ func (e RPCError) Error() string {
 	return fmt.Sprintf("%d: %s", e.Code, e.Message)
 }
 
+// HTTPError is returned when abec answers with a non-200 HTTP status, e.g. on authentication failure or when it is too busy.
+type HTTPError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("http status %d: %s", e.StatusCode, e.Message)
+}
+
 type JSONRPCResponse struct {
 	Result json.RawMessage `json:"result"`
 	Error  *RPCError       `json:"error"`
@@ -27,12 +37,12 @@ type JSONRPCResponse struct {
 }
 
 type ChainInfo struct {
-	NumBlocks       int64   `json:"blocks"`
-	IsTestnet       bool    `json:"testnet"`
-	Version         int64   `json:"version"`
-	ProtocolVersion int64   `json:"protocolversion"`
-	RelayFee        float64 `json:"relayfee"`
-	NetID           uint8   `json:"netid"`
+	NumBlocks       int64  `json:"blocks"`
+	IsTestnet       bool   `json:"testnet"`
+	Version         int64  `json:"version"`
+	ProtocolVersion int64  `json:"protocolversion"`
+	RelayFee        Amount `json:"relayfee"`
+	NetID           uint8  `json:"netid"`
 }
 
 type Block struct {
@@ -90,8 +100,64 @@ type Tx struct {
 	Size          int64     `json:"size"`
 	FullSize      int64     `json:"fullsize"`
 	Memo          string    `json:"memo"`
-	Fee           float64   `json:"fee"`
+	Fee           Amount    `json:"fee"`
 	Witness       string    `json:"witness"`
 	Vin           []*TxVin  `json:"vin"`
 	Vout          []*TxVout `json:"vout"`
 }
+
+type BlockHeader struct {
+	BlockHash     string  `json:"hash"`
+	Confirmations int64   `json:"confirmations"`
+	Height        int64   `json:"height"`
+	Version       int64   `json:"version"`
+	VersionHex    string  `json:"versionHex"`
+	MerkleRoot    string  `json:"merkleroot"`
+	Time          int64   `json:"time"`
+	Nonce         uint64  `json:"nonce"`
+	Bits          string  `json:"bits"`
+	Difficulty    float64 `json:"difficulty"`
+	PrevBlockHash string  `json:"previousblockhash"`
+	NextBlockHash string  `json:"nextblockhash"`
+}
+
+type BlockChainInfo struct {
+	Chain                string  `json:"chain"`
+	Blocks               int64   `json:"blocks"`
+	Headers              int64   `json:"headers"`
+	BestBlockHash        string  `json:"bestblockhash"`
+	Difficulty           float64 `json:"difficulty"`
+	MedianTime           int64   `json:"mediantime"`
+	VerificationProgress float64 `json:"verificationprogress"`
+	Pruned               bool    `json:"pruned"`
+	PruneHeight          int64   `json:"pruneheight"`
+	ChainWork            string  `json:"chainwork"`
+}
+
+type MempoolInfo struct {
+	Size      int64 `json:"size"`        // number of transactions
+	Bytes     int64 `json:"bytes"`       // total size of the transactions
+	DiskTxNum int64 `json:"disk_tx_num"` // number of transactions stored on disk
+}
+
+// MempoolTx is an entry of the verbose getrawmempool result.
+type MempoolTx struct {
+	Size             int64   `json:"size"`
+	FullSize         int64   `json:"fullsize"`
+	Fee              Amount  `json:"fee"`    // in ABEL
+	Time             int64   `json:"time"`   // time the transaction entered the mempool
+	Height           int64   `json:"height"` // tip height when the transaction entered the mempool
+	StartingPriority float64 `json:"startingpriority"`
+	CurrentPriority  float64 `json:"currentpriority"`
+	Comment          string  `json:"comment"`
+}
+
+// UtxoRing is the state of a TXO ring as returned by getutxoring, the abec counterpart of gettxout.
+type UtxoRing struct {
+	RingBlockHeight      int64     `json:"ringblockheight"`
+	TXORing              TXORing   `json:"OutPointRing"`
+	TxOuts               []*TxVout `json:"TxOuts"`
+	SerialNumbers        []string  `json:"consumedserialnumbers"` // serial numbers of the consumed outputs
+	ConsumingBlockHashes []string  `json:"ConsumingBlockHashs"`   // blocks consuming the outputs
+	IsCoinbase           bool      `json:"coinbase"`
+}
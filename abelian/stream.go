@@ -0,0 +1,395 @@
+package abelian
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// partialResultError is a streamed call which failed after writing part of its result. It is
+// neither retried nor failed over, as the writer can not be rewound.
+type partialResultError struct {
+	err     error
+	written int64
+}
+
+func (e *partialResultError) Error() string {
+	return fmt.Sprintf("fail after writing %d bytes of the result: %v", e.written, e.err)
+}
+
+func (e *partialResultError) Unwrap() error {
+	return e.err
+}
+
+func isPartialResult(err error) bool {
+	var partialErr *partialResultError
+	return errors.As(err, &partialErr)
+}
+
+// GetBlockBytesTo writes the serialized block to w as it is received, without holding the
+// whole response in memory, and returns the number of bytes written. A call failing after
+// writing some bytes is neither retried nor failed over to another endpoint.
+func (client *Client) GetBlockBytesTo(blockID string, w io.Writer) (n int64, err error) {
+	return client.GetBlockBytesToCtx(context.Background(), blockID, w)
+}
+func (client *Client) GetBlockBytesToCtx(ctx context.Context, blockID string, w io.Writer) (n int64, err error) {
+	return client.streamHex(ctx, "getblockabe", []interface{}{blockID, 0}, w)
+}
+
+// GetTxBytesTo writes the serialized transaction to w like GetBlockBytesTo.
+func (client *Client) GetTxBytesTo(txID string, w io.Writer) (n int64, err error) {
+	return client.GetTxBytesToCtx(context.Background(), txID, w)
+}
+func (client *Client) GetTxBytesToCtx(ctx context.Context, txID string, w io.Writer) (n int64, err error) {
+	return client.streamHex(ctx, "getrawtransaction", []interface{}{txID, false}, w)
+}
+
+// streamHexBytes is streamHex into a byte slice.
+func (client *Client) streamHexBytes(ctx context.Context, method string, params []interface{}) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	_, err := client.streamHex(ctx, method, params, buffer)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// streamHex is DoCtx for the calls whose result is a hex string, decoded into w.
+func (client *Client) streamHex(ctx context.Context, method string, params []interface{}, w io.Writer) (n int64, err error) {
+	if client.pool != nil {
+		err = client.doPool(ctx, []string{method}, func(ep *endpoint) error {
+			written, err := ep.client.streamHex(ctx, method, params, w)
+			n += written
+			return err
+		})
+		return n, err
+	}
+
+	attempt := func(ctx context.Context) error {
+		start := 0
+		buffer, isBuffer := w.(*bytes.Buffer)
+		if isBuffer {
+			start = buffer.Len()
+		}
+		written, err := client.streamHexOnce(ctx, method, params, w)
+		if err != nil && isBuffer {
+			// a buffer is rewound, so that the call can be tried again
+			buffer.Truncate(start)
+			written = 0
+		}
+		n += written
+		if err != nil && written > 0 {
+			return &partialResultError{err: err, written: written}
+		}
+		return err
+	}
+	if client.retryPolicy == nil {
+		return n, attempt(ctx)
+	}
+	return n, client.retryPolicy.do(ctx, method, attempt)
+}
+
+// hexStream is the destination of a call whose hex result is decoded as it is read. It travels
+// in the context of the call through the middlewares to the innermost Invoker.
+type hexStream struct {
+	method   string
+	w        io.Writer
+	written  int64
+	streamed bool // set once the innermost Invoker decoded the result into w
+}
+
+type hexStreamKey struct{}
+
+// hexStreamOf returns the destination of the result of method, nil if it is not streamed.
+func hexStreamOf(ctx context.Context, method string) *hexStream {
+	stream, _ := ctx.Value(hexStreamKey{}).(*hexStream)
+	if stream == nil || stream.method != method {
+		return nil
+	}
+	return stream
+}
+
+// streamHexOnce sends the call through the middlewares. The innermost Invoker decodes the result
+// straight into w if the transport is a StreamTransport, and returns a nil result. Otherwise the
+// result, from the transport or from a middleware, is decoded into w here.
+func (client *Client) streamHexOnce(ctx context.Context, method string, params []interface{}, w io.Writer) (written int64, err error) {
+	if client.limiter != nil {
+		release, err := client.limiter.acquire(ctx, MethodClassOf(method), 1)
+		if err != nil {
+			return 0, err
+		}
+		defer release()
+	}
+	ctx, cancel := client.withDefaultDeadline(ctx, method)
+	defer cancel()
+
+	stream := &hexStream{method: method, w: w}
+	raw, err := client.invoke(context.WithValue(ctx, hexStreamKey{}, stream), method, params)
+	if err != nil || stream.streamed {
+		return stream.written, err
+	}
+
+	var resHex string
+	err = json.Unmarshal(raw, &resHex)
+	if err != nil {
+		sdkLog.Errorf("fail to unmarshal json result: %v", err)
+		return 0, err
+	}
+	res, err := hex.DecodeString(resHex)
+	if err != nil {
+		return 0, err
+	}
+	size, err := w.Write(res)
+	return int64(size), err
+}
+
+// roundTripHexStream is the innermost Invoker of a streamed call, decoding the result into stream.
+func (client *Client) roundTripHexStream(ctx context.Context, streamTransport StreamTransport, method string, params []interface{}, stream *hexStream) (json.RawMessage, error) {
+	if stream.written > 0 {
+		// a middleware calling next again can not rewind the writer
+		return nil, &partialResultError{err: fmt.Errorf("result already written"), written: stream.written}
+	}
+	jsonBody, err := json.Marshal(&JSONRPCRequest{
+		JSONRPC: "1.0",
+		Method:  method,
+		Params:  params,
+		ID:      client.nextID(),
+	})
+	if err != nil {
+		sdkLog.Errorf("fail to marshal json request: %v", err)
+		return nil, err
+	}
+	body, err := streamTransport.RoundTripStream(ctx, jsonBody)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	stream.streamed = true
+	written, rpcErr, err := decodeHexResponse(bufio.NewReader(body), stream.w)
+	stream.written += written
+	if err == nil && rpcErr != nil {
+		err = rpcErr
+	}
+	if err != nil {
+		sdkLog.Errorf("request method %s with param %v, fail to decode response: %v", method, params, err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// hexChunkSize is the number of hex digits decoded at once.
+const hexChunkSize = 64 << 10
+
+// decodeHexResponse reads a JSON-RPC response object whose result is a hex string, and writes
+// the decoded result to w as it is read, without buffering the response.
+func decodeHexResponse(r *bufio.Reader, w io.Writer) (n int64, rpcErr *RPCError, err error) {
+	c, err := nextNonSpace(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if c != '{' {
+		return 0, nil, fmt.Errorf("invalid json response: expect object, got %q", c)
+	}
+	for {
+		c, err = nextNonSpace(r)
+		if err != nil {
+			return n, nil, err
+		}
+		if c == '}' {
+			return n, rpcErr, nil
+		}
+		if c == ',' {
+			continue
+		}
+		if c != '"' {
+			return n, nil, fmt.Errorf("invalid json response: expect key, got %q", c)
+		}
+		rawKey, err := readJSONString(r)
+		if err != nil {
+			return n, nil, err
+		}
+		var key string
+		err = json.Unmarshal(rawKey, &key)
+		if err != nil {
+			return n, nil, err
+		}
+		c, err = nextNonSpace(r)
+		if err != nil {
+			return n, nil, err
+		}
+		if c != ':' {
+			return n, nil, fmt.Errorf("invalid json response: expect colon, got %q", c)
+		}
+
+		c, err = nextNonSpace(r)
+		if err != nil {
+			return n, nil, err
+		}
+		if key == "result" && c == '"' {
+			n, err = copyHexString(r, w)
+			if err != nil {
+				return n, nil, err
+			}
+			continue
+		}
+		err = r.UnreadByte()
+		if err != nil {
+			return n, nil, err
+		}
+		raw, err := readJSONValue(r)
+		if err != nil {
+			return n, nil, err
+		}
+		switch key {
+		case "result":
+			if string(raw) != "null" {
+				return n, nil, fmt.Errorf("invalid json response: expect hex string result, got %.32s", raw)
+			}
+		case "error":
+			err = json.Unmarshal(raw, &rpcErr)
+			if err != nil {
+				return n, nil, err
+			}
+		}
+	}
+}
+
+func nextNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			return c, nil
+		}
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// copyHexString decodes the hex digits up to the closing quote of a JSON string to w.
+func copyHexString(r *bufio.Reader, w io.Writer) (int64, error) {
+	n := int64(0)
+	digits := make([]byte, 0, hexChunkSize)
+	decoded := make([]byte, hexChunkSize/2)
+	flush := func(all bool) error {
+		count := len(digits)
+		if !all {
+			count -= count % 2
+		} else if count%2 != 0 {
+			return hex.ErrLength
+		}
+		size, err := hex.Decode(decoded, digits[:count])
+		if err != nil {
+			return err
+		}
+		written, err := w.Write(decoded[:size])
+		n += int64(written)
+		if err != nil {
+			return err
+		}
+		digits = append(digits[:0], digits[count:]...)
+		return nil
+	}
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return n, unexpectedEOF(err)
+		}
+		if c == '"' {
+			return n, flush(true)
+		}
+		if c == '\\' {
+			return n, fmt.Errorf("invalid hex result: unexpected escape")
+		}
+		digits = append(digits, c)
+		if len(digits) == cap(digits) {
+			err = flush(false)
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+}
+
+// readJSONString reads the rest of a JSON string whose opening quote has been read, and returns
+// it with its quotes.
+func readJSONString(r *bufio.Reader) ([]byte, error) {
+	raw := []byte{'"'}
+	escaped := false
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		raw = append(raw, c)
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			return raw, nil
+		}
+	}
+}
+
+// readJSONValue reads the next JSON value, e.g. the error object or the id of the response.
+func readJSONValue(r *bufio.Reader) (json.RawMessage, error) {
+	c, err := nextNonSpace(r)
+	if err != nil {
+		return nil, err
+	}
+	if c == '"' {
+		return readJSONString(r)
+	}
+	raw := []byte{c}
+	if c != '{' && c != '[' {
+		// number, true, false or null
+		for {
+			c, err = r.ReadByte()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			if c == ',' || c == '}' || c == ']' || c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+				return raw, r.UnreadByte()
+			}
+			raw = append(raw, c)
+		}
+	}
+	depth := 1
+	for depth > 0 {
+		c, err = r.ReadByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if c == '"' {
+			str, err := readJSONString(r)
+			if err != nil {
+				return nil, err
+			}
+			raw = append(raw, str...)
+			continue
+		}
+		raw = append(raw, c)
+		switch c {
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		}
+	}
+	return raw, nil
+}
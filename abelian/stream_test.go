@@ -0,0 +1,138 @@
+package abelian_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+func TestGetBlockBytesMiddleware(t *testing.T) {
+	blockBytes := bytes.Repeat([]byte{0xab, 0x01}, 100_000)
+	tests := []struct {
+		name      string
+		transport bool // in memory, which is not a StreamTransport
+	}{
+		{"stream transport", false},
+		{"in-memory transport", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := abeliantest.NewServer()
+			defer server.Close()
+			block := server.AddBlockWithBytes(blockBytes)
+			metrics := abelian.NewMetrics()
+			recorder := &methodRecorder{}
+			options := []abelian.ClientOption{abelian.WithMetrics(metrics), abelian.WithMiddleware(recorder.middleware),
+				abelian.WithVersionCheck(abelian.VersionCheckNone)}
+			if tt.transport {
+				options = append(options, abelian.WithTransport(server.Transport()))
+			}
+			client, err := server.NewClient(options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			res, err := client.GetBlockBytes(block.BlockHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(res, blockBytes) {
+				t.Errorf("GetBlockBytes returned %d bytes, want %d", len(res), len(blockBytes))
+			}
+			if seen := recorder.seen(); len(seen) != 1 || seen[0] != "getblockabe" {
+				t.Errorf("middleware saw %v, want getblockabe", seen)
+			}
+			m := metrics.Snapshot()["getblockabe"]
+			if m == nil || m.Requests != 1 || m.ResultSize.Count != 1 || m.ResultSize.Sum < float64(len(blockBytes)) {
+				t.Errorf("getblockabe metrics = %+v, want 1 request of at least %d bytes", m, len(blockBytes))
+			}
+		})
+	}
+}
+
+func TestGetTxBytesMiddlewareAnswer(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	want := []byte{1, 2, 3, 4}
+	// a middleware answering the call itself, e.g. to inject a result
+	answer := func(next abelian.Invoker) abelian.Invoker {
+		return func(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+			if method == "getrawtransaction" {
+				return json.Marshal(hex.EncodeToString(want))
+			}
+			return next(ctx, method, params)
+		}
+	}
+	client, err := server.NewClient(abelian.WithMiddleware(answer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	res, err := client.GetTxBytes("00")
+	if err != nil || !bytes.Equal(res, want) {
+		t.Errorf("GetTxBytes = %x, %v, want %x", res, err, want)
+	}
+	if calls := server.CallCount("getrawtransaction"); calls != 0 {
+		t.Errorf("getrawtransaction calls = %d, want 0", calls)
+	}
+}
+
+func TestGetBlockBytesMiddlewareRetry(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	blockBytes := []byte{5, 6, 7}
+	block := server.AddBlockWithBytes(blockBytes)
+
+	// a middleware calling the node again after an authentication failure, as after refreshing credentials
+	refreshes := 0
+	refresh := func(next abelian.Invoker) abelian.Invoker {
+		return func(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+			result, err := next(ctx, method, params)
+			if errors.Is(err, abelian.ErrUnauthorized) {
+				refreshes++
+				return next(ctx, method, params)
+			}
+			return result, err
+		}
+	}
+	client, err := server.NewClient(abelian.WithMiddleware(refresh))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	server.InjectFault("getblockabe", &abeliantest.Fault{HTTPStatus: http.StatusUnauthorized}, 1)
+	var buf bytes.Buffer
+	n, err := client.GetBlockBytesTo(block.BlockHash, &buf)
+	if err != nil || n != int64(len(blockBytes)) || !bytes.Equal(buf.Bytes(), blockBytes) {
+		t.Errorf("GetBlockBytesTo = %d, %v, wrote %x, want %x", n, err, buf.Bytes(), blockBytes)
+	}
+	if refreshes != 1 {
+		t.Errorf("refreshes = %d, want 1", refreshes)
+	}
+}
+
+func TestGetBlockBytesTooLarge(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	block := server.AddBlockWithBytes(make([]byte, 4096))
+	client, err := server.NewClient(abelian.WithMaxResponseSize(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	_, err = client.GetBlockBytes(block.BlockHash)
+	if !errors.Is(err, abelian.ErrResponseTooLarge) {
+		t.Errorf("GetBlockBytes error = %v, want ErrResponseTooLarge", err)
+	}
+}
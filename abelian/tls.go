@@ -0,0 +1,99 @@
+package abelian
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+func newTLSConfig(config *ClientConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         config.ServerName,
+		MinVersion:         config.MinTLSVersion,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+	if tlsConfig.MinVersion == 0 {
+		tlsConfig.MinVersion = tls.VersionTLS12
+	}
+	if tlsConfig.ServerName == "" {
+		serverName, err := hostOf(config.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ServerName = serverName
+	}
+
+	if config.CaFile != "" {
+		caCert, err := os.ReadFile(config.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %v", err)
+		}
+
+		caCertPool := x509.NewCertPool()
+		if ok := caCertPool.AppendCertsFromPEM(caCert); !ok {
+			return nil, fmt.Errorf("failed to add CA certificate to pool")
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(config.PinnedSHA256) > 0 {
+		pins := make([][]byte, 0, len(config.PinnedSHA256))
+		for _, pin := range config.PinnedSHA256 {
+			pinBytes, err := hex.DecodeString(strings.ReplaceAll(pin, ":", ""))
+			if err != nil || len(pinBytes) != sha256.Size {
+				return nil, fmt.Errorf("invalid SHA-256 pin %q", pin)
+			}
+			pins = append(pins, pinBytes)
+		}
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifyPins(state, pins)
+		}
+	}
+
+	if config.InsecureSkipVerify {
+		sdkLog.Warnf("tls certificate verification of %s is disabled, do not use in production", config.Endpoint)
+	}
+	return tlsConfig, nil
+}
+
+// verifyPins checks that the leaf certificate or its public key matches one of the pins.
+func verifyPins(state tls.ConnectionState, pins [][]byte) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("no server certificate to check pins against")
+	}
+	leaf := state.PeerCertificates[0]
+	certHash := sha256.Sum256(leaf.Raw)
+	spkiHash := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+	for _, pin := range pins {
+		if subtle.ConstantTimeCompare(pin, certHash[:]) == 1 || subtle.ConstantTimeCompare(pin, spkiHash[:]) == 1 {
+			return nil
+		}
+	}
+	return fmt.Errorf("server certificate %x matches no pin", certHash)
+}
+
+// hostOf returns the host of endpoint without port, for endpoints with or without scheme.
+func hostOf(endpoint string) (string, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %s: %v", endpoint, err)
+	}
+	return endpointURL.Hostname(), nil
+}
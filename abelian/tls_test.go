@@ -0,0 +1,219 @@
+package abelian_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+// testCert is a self-signed certificate for localhost, written to CertFile and KeyFile.
+type testCert struct {
+	tls.Certificate
+	CertFile string
+	KeyFile  string
+}
+
+func newTestCert(t *testing.T, name string) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := &testCert{
+		CertFile: filepath.Join(t.TempDir(), name+".crt"),
+		KeyFile:  filepath.Join(t.TempDir(), name+".key"),
+	}
+	if err := os.WriteFile(cert.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cert.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cert.Certificate, err = tls.LoadX509KeyPair(cert.CertFile, cert.KeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert.Leaf, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// pinOf returns the hex SHA-256 of data in the colon-separated form of openssl.
+func pinOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	hexSum := hex.EncodeToString(sum[:])
+	pairs := make([]string, 0, len(sum))
+	for i := 0; i < len(hexSum); i += 2 {
+		pairs = append(pairs, hexSum[i:i+2])
+	}
+	return strings.Join(pairs, ":")
+}
+
+// startTLSServer serves server over tls with cert, changed by configure if not nil, and returns its
+// https endpoint on localhost.
+func startTLSServer(t *testing.T, server *abeliantest.Server, cert *testCert, configure func(*tls.Config)) string {
+	t.Helper()
+	tlsServer := httptest.NewUnstartedServer(server.Config.Handler)
+	tlsServer.TLS = &tls.Config{Certificates: []tls.Certificate{cert.Certificate}}
+	if configure != nil {
+		configure(tlsServer.TLS)
+	}
+	tlsServer.StartTLS()
+	t.Cleanup(tlsServer.Close)
+	_, port, err := net.SplitHostPort(tlsServer.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "https://" + net.JoinHostPort("localhost", port)
+}
+
+func TestTLS(t *testing.T) {
+	serverCert := newTestCert(t, "server")
+	otherCert := newTestCert(t, "other")
+	clientCert := newTestCert(t, "client")
+	leaf := serverCert.Leaf
+	requireClientCert := func(config *tls.Config) {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = x509.NewCertPool()
+		config.ClientCAs.AddCert(clientCert.Leaf)
+	}
+
+	tests := []struct {
+		name      string
+		configure func(*tls.Config)
+		options   []abelian.ClientOption
+		wantErr   bool
+	}{
+		{"ca", nil, []abelian.ClientOption{abelian.WithTLS(serverCert.CertFile)}, false},
+		{"unknown ca", nil, []abelian.ClientOption{abelian.WithTLS(otherCert.CertFile)}, true},
+		{"server name", nil, []abelian.ClientOption{abelian.WithTLS(serverCert.CertFile), abelian.WithServerName("localhost")}, false},
+		{"wrong server name", nil, []abelian.ClientOption{abelian.WithTLS(serverCert.CertFile), abelian.WithServerName("example.com")}, true},
+		{"leaf pin", nil, []abelian.ClientOption{abelian.WithTLS(serverCert.CertFile), abelian.WithPinnedSHA256(pinOf(leaf.Raw))}, false},
+		{"spki pin", nil, []abelian.ClientOption{abelian.WithTLS(serverCert.CertFile), abelian.WithPinnedSHA256(pinOf(leaf.RawSubjectPublicKeyInfo))}, false},
+		{"one of the pins", nil, []abelian.ClientOption{abelian.WithTLS(serverCert.CertFile),
+			abelian.WithPinnedSHA256(pinOf(otherCert.Leaf.Raw), pinOf(leaf.Raw))}, false},
+		{"wrong pin", nil, []abelian.ClientOption{abelian.WithTLS(serverCert.CertFile), abelian.WithPinnedSHA256(pinOf(otherCert.Leaf.Raw))}, true},
+		{"pin without verification", nil, []abelian.ClientOption{abelian.WithInsecureSkipVerify(), abelian.WithPinnedSHA256(pinOf(leaf.Raw))}, false},
+		{"wrong pin without verification", nil, []abelian.ClientOption{abelian.WithInsecureSkipVerify(), abelian.WithPinnedSHA256(pinOf(otherCert.Leaf.Raw))}, true},
+		{"min version", func(config *tls.Config) { config.MaxVersion = tls.VersionTLS12 },
+			[]abelian.ClientOption{abelian.WithTLS(serverCert.CertFile)}, false},
+		{"min version above the server", func(config *tls.Config) { config.MaxVersion = tls.VersionTLS12 },
+			[]abelian.ClientOption{abelian.WithTLS(serverCert.CertFile), abelian.WithMinTLSVersion(tls.VersionTLS13)}, true},
+		{"default min version above the server", func(config *tls.Config) { config.MaxVersion = tls.VersionTLS11 },
+			[]abelian.ClientOption{abelian.WithTLS(serverCert.CertFile)}, true},
+		{"client cert", requireClientCert,
+			[]abelian.ClientOption{abelian.WithTLS(serverCert.CertFile), abelian.WithClientCert(clientCert.CertFile, clientCert.KeyFile)}, false},
+		{"no client cert", requireClientCert, []abelian.ClientOption{abelian.WithTLS(serverCert.CertFile)}, true},
+		{"unknown client cert", requireClientCert,
+			[]abelian.ClientOption{abelian.WithTLS(serverCert.CertFile), abelian.WithClientCert(otherCert.CertFile, otherCert.KeyFile)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := abeliantest.NewServer()
+			defer server.Close()
+			endpoint := startTLSServer(t, server, serverCert, tt.configure)
+			config := server.ClientConfig(append(tt.options, abelian.WithVersionCheck(abelian.VersionCheckNone))...)
+			config.Endpoint = endpoint
+			client, err := abelian.NewClient(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+			_, err = client.GetBlockCount()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetBlockCount over tls = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTLSServerNameOfEndpoint(t *testing.T) {
+	serverCert := newTestCert(t, "server")
+	server := abeliantest.NewServer()
+	defer server.Close()
+	var mtx sync.Mutex
+	var serverNames []string
+	endpoint := startTLSServer(t, server, serverCert, func(config *tls.Config) {
+		config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			serverNames = append(serverNames, hello.ServerName)
+			return nil, nil
+		}
+	})
+	config := server.ClientConfig(abelian.WithTLS(serverCert.CertFile), abelian.WithVersionCheck(abelian.VersionCheckNone))
+	config.Endpoint = endpoint
+	client, err := abelian.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.GetBlockCount(); err != nil {
+		t.Fatal(err)
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	if len(serverNames) != 1 || serverNames[0] != "localhost" {
+		t.Errorf("server names sent = %q, want localhost", serverNames)
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	cert := newTestCert(t, "server")
+	tests := []struct {
+		name    string
+		options []abelian.ClientOption
+	}{
+		{"missing ca file", []abelian.ClientOption{abelian.WithTLS(filepath.Join(t.TempDir(), "missing.crt"))}},
+		{"ca file without certificate", []abelian.ClientOption{abelian.WithTLS(cert.KeyFile)}},
+		{"missing client key", []abelian.ClientOption{abelian.WithClientCert(cert.CertFile, "")}},
+		{"short pin", []abelian.ClientOption{abelian.WithPinnedSHA256("00:11")}},
+		{"pin not in hex", []abelian.ClientOption{abelian.WithPinnedSHA256(strings.Repeat("zz", sha256.Size))}},
+	}
+	for _, tt := range tests {
+		_, err := abelian.NewClient(abelian.NewClientConfig("https://localhost:1", append(tt.options, abelian.WithVersionCheck(abelian.VersionCheckNone))...))
+		if err == nil {
+			t.Errorf("%s: NewClient succeeded, want error", tt.name)
+		}
+	}
+}
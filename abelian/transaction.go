@@ -16,7 +16,7 @@ type TxInDesc struct {
 	TxID             string
 	TxOutIndex       uint8
 	TxOutData        []byte
-	CoinValue        int64
+	CoinValue        Amount
 	CoinSerialNumber []byte
 }
 
@@ -44,7 +44,7 @@ func SortTxInDescs(txIndescs []*TxInDesc) error {
 
 type TxOutDesc struct {
 	AbelAddress *AbelAddress
-	CoinValue   int64
+	CoinValue   Amount
 }
 
 func SortTxOutDesc(txOutdescs []*TxOutDesc) error {
@@ -61,7 +61,7 @@ func SortTxOutDesc(txOutdescs []*TxOutDesc) error {
 type TxDesc struct {
 	TxInDescs        []*TxInDesc
 	TxOutDescs       []*TxOutDesc
-	TxFee            int64
+	TxFee            Amount
 	TxMemo           []byte
 	TxRingBlockDescs map[int64]*TxBlockDesc
 }
@@ -142,7 +142,7 @@ type TxInDescWithRing struct {
 	TxID             string
 	TxOutIndex       uint8
 	TxOutData        []byte
-	CoinValue        int64
+	CoinValue        Amount
 	CoinSerialNumber []byte
 	TxoRing          *CoinRing
 }
@@ -172,11 +172,11 @@ func SortTxInDescWithRing(txIndescs []*TxInDescWithRing) error {
 type TxDescWithRing struct {
 	TxInDescs  []*TxInDescWithRing
 	TxOutDescs []*TxOutDesc
-	TxFee      int64
+	TxFee      Amount
 	TxMemo     []byte
 }
 
-func NewTxDesc(txInDescs []*TxInDesc, txOutDescs []*TxOutDesc, txFee int64, txRingBlockDescs map[int64]*TxBlockDesc) *TxDesc {
+func NewTxDesc(txInDescs []*TxInDesc, txOutDescs []*TxOutDesc, txFee Amount, txRingBlockDescs map[int64]*TxBlockDesc) *TxDesc {
 	return &TxDesc{
 		TxInDescs:        txInDescs,
 		TxOutDescs:       txOutDescs,
@@ -184,7 +184,7 @@ func NewTxDesc(txInDescs []*TxInDesc, txOutDescs []*TxOutDesc, txFee int64, txRi
 		TxRingBlockDescs: txRingBlockDescs,
 	}
 }
-func NewTxDescWithRing(txInDescs []*TxInDescWithRing, txOutDescs []*TxOutDesc, txFee int64) *TxDescWithRing {
+func NewTxDescWithRing(txInDescs []*TxInDescWithRing, txOutDescs []*TxOutDesc, txFee Amount) *TxDescWithRing {
 	return &TxDescWithRing{
 		TxInDescs:  txInDescs,
 		TxOutDescs: txOutDescs,
@@ -193,7 +193,8 @@ func NewTxDescWithRing(txInDescs []*TxInDescWithRing, txOutDescs []*TxOutDesc, t
 }
 
 type UnsignedRawTx struct {
-	Data []byte
+	Data      []byte
+	NetworkID *NetworkID // network of the output addresses, nil if unknown
 }
 
 type TxBlockDesc struct {
@@ -209,8 +210,24 @@ func NewTxBlockDesc(binData []byte, height int64) *TxBlockDesc {
 }
 
 type SignedRawTx struct {
-	Data []byte
-	TxID string
+	Data      []byte
+	TxID      string
+	NetworkID *NetworkID // network of the output addresses, nil if unknown
+}
+
+// outputsNetworkID returns the network shared by the addresses of the outputs,
+// or *NetworkMismatchError if they are on different networks.
+func outputsNetworkID(txOutDescs []*TxOutDesc) (*NetworkID, error) {
+	var netID *NetworkID
+	for _, txOutDesc := range txOutDescs {
+		outNetID := txOutDesc.AbelAddress.GetNetID()
+		if netID == nil {
+			netID = &outNetID
+		} else if outNetID != *netID {
+			return nil, &NetworkMismatchError{Expected: *netID, Actual: outNetID}
+		}
+	}
+	return netID, nil
 }
 
 // GenerateUnsignedRawTx make an unsigned transaction,
@@ -230,6 +247,12 @@ func GenerateUnsignedRawTx(txDesc *TxDesc) (*UnsignedRawTx, error) {
 	// Prepare serializedBlocksForRingGroup.
 	serializedBlocksForRingGroup := getSerializedBlocksForRingGroup(txDesc.TxRingBlockDescs)
 
+	netID, err := outputsNetworkID(txDesc.TxOutDescs)
+	if err != nil {
+		sdkLog.Errorf("fail to prepare transaction outputs: %v", err)
+		return nil, err
+	}
+
 	// Prepare txRequestOutputDesc.
 	txRequestOutputDescs := make([]*api.TxRequestOutputDesc, 0, len(txDesc.TxOutDescs))
 	for i := 0; i < len(txDesc.TxOutDescs); i++ {
@@ -253,7 +276,8 @@ func GenerateUnsignedRawTx(txDesc *TxDesc) (*UnsignedRawTx, error) {
 	}
 
 	return &UnsignedRawTx{
-		Data: serializedTxRequestDesc,
+		Data:      serializedTxRequestDesc,
+		NetworkID: netID,
 	}, nil
 }
 
@@ -289,6 +313,12 @@ func GenerateUnsignedRawTxWithRing(txDesc *TxDescWithRing) (*UnsignedRawTx, erro
 
 	}
 
+	netID, err := outputsNetworkID(txDesc.TxOutDescs)
+	if err != nil {
+		sdkLog.Errorf("fail to prepare transaction outputs: %v", err)
+		return nil, err
+	}
+
 	// Prepare txRequestOutputDesc.
 	txRequestOutputDescs := make([]*api.TxRequestOutputDesc, 0, len(txDesc.TxOutDescs))
 	for i := 0; i < len(txDesc.TxOutDescs); i++ {
@@ -312,7 +342,8 @@ func GenerateUnsignedRawTxWithRing(txDesc *TxDescWithRing) (*UnsignedRawTx, erro
 	}
 
 	return &UnsignedRawTx{
-		Data: serializedTxRequestDesc,
+		Data:      serializedTxRequestDesc,
+		NetworkID: netID,
 	}, nil
 }
 
@@ -380,8 +411,9 @@ func GenerateSignedRawTx(unsignedRawTx *UnsignedRawTx, signerAccounts []Account)
 	}
 
 	return &SignedRawTx{
-		Data: serializedTxFull,
-		TxID: txid.String(),
+		Data:      serializedTxFull,
+		TxID:      txid.String(),
+		NetworkID: unsignedRawTx.NetworkID,
 	}, nil
 }
 func getSerializedBlocksForRingGroup(ringBlockDescs map[int64]*TxBlockDesc) [][]byte {
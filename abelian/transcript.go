@@ -0,0 +1,348 @@
+package abelian
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// TRANSCRIPT_VERSION is the version of the transcript file format written by Recorder.
+const TRANSCRIPT_VERSION = 1
+
+// A transcript file is a header line followed by one TranscriptEntry per line, in call order.
+type transcriptHeader struct {
+	Version int `json:"version"`
+}
+
+// TranscriptEntry is one recorded JSON-RPC call.
+type TranscriptEntry struct {
+	Method     string          `json:"method"`
+	Params     json.RawMessage `json:"params"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      *RPCError       `json:"error,omitempty"`
+	HTTPStatus int             `json:"http_status,omitempty"` // set if the node answered with a non-200 status
+	HTTPBody   string          `json:"http_body,omitempty"`
+	Batch      bool            `json:"batch,omitempty"` // set if the node answered a whole batch request with this single error
+}
+
+// Recorder is a http.RoundTripper writing the JSON-RPC calls going through it to a transcript file,
+// to be served later by a Replayer. Only the request and response bodies are recorded, so the
+// basic auth credentials never reach the file. The recorder of a client created WithRecorder reads
+// at most MaxResponseSize bytes of a response, which fails with *ResponseTooLargeError otherwise.
+type Recorder struct {
+	next    http.RoundTripper
+	maxSize int64 // maximum size of a response read into memory, 0 for no limit
+
+	mtx  sync.Mutex
+	file *os.File
+}
+
+// NewRecorder creates the transcript file at path and records the calls sent through next,
+// http.DefaultTransport if nil.
+func NewRecorder(path string, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create transcript: %v", err)
+	}
+	header, _ := json.Marshal(&transcriptHeader{Version: TRANSCRIPT_VERSION})
+	_, err = file.Write(append(header, '\n'))
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("fail to write transcript: %v", err)
+	}
+	return &Recorder{next: next, file: file}, nil
+}
+
+func (recorder *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body, 0)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := recorder.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := readBody(&resp.Body, recorder.maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := transcriptEntries(reqBody, resp.StatusCode, respBody)
+	if err != nil {
+		sdkLog.Warnf("fail to record call: %v", err)
+		return resp, nil
+	}
+	recorder.mtx.Lock()
+	defer recorder.mtx.Unlock()
+	for _, entry := range entries {
+		line, _ := json.Marshal(entry)
+		_, err = recorder.file.Write(append(line, '\n'))
+		if err != nil {
+			sdkLog.Errorf("fail to write transcript: %v", err)
+			break
+		}
+	}
+	return resp, nil
+}
+
+// Close closes the transcript file.
+func (recorder *Recorder) Close() error {
+	recorder.mtx.Lock()
+	defer recorder.mtx.Unlock()
+	return recorder.file.Close()
+}
+
+// readBody reads the whole body and replaces it with an in-memory copy. A body longer than
+// limit, if not 0, fails with *ResponseTooLargeError.
+func readBody(body *io.ReadCloser, limit int64) ([]byte, error) {
+	if *body == nil {
+		return nil, nil
+	}
+	reader := io.Reader(*body)
+	if limit > 0 {
+		reader = io.LimitReader(reader, limit+1)
+	}
+	data, err := io.ReadAll(reader)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(len(data)) > limit {
+		return nil, &ResponseTooLargeError{Limit: limit}
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// transcriptEntries pairs the calls of a request body with their answers.
+func transcriptEntries(reqBody []byte, statusCode int, respBody []byte) ([]*TranscriptEntry, error) {
+	reqObjs, isBatch, err := parseRequests(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode != http.StatusOK {
+		entry := &TranscriptEntry{
+			HTTPStatus: statusCode,
+			HTTPBody:   strings.TrimSpace(string(respBody)),
+			Batch:      isBatch,
+		}
+		if !isBatch {
+			entry.Method, entry.Params = reqObjs[0].Method, reqObjs[0].Params
+		}
+		return []*TranscriptEntry{entry}, nil
+	}
+
+	if !isBatch {
+		respObj := &JSONRPCResponse{}
+		err = json.Unmarshal(respBody, respObj)
+		if err != nil {
+			return nil, err
+		}
+		return []*TranscriptEntry{newTranscriptEntry(reqObjs[0], respObj)}, nil
+	}
+
+	var respObjs []*JSONRPCResponse
+	err = json.Unmarshal(respBody, &respObjs)
+	if err != nil {
+		respObj := &JSONRPCResponse{}
+		err = json.Unmarshal(respBody, respObj)
+		if err != nil {
+			return nil, err
+		}
+		return []*TranscriptEntry{{Error: respObj.Error, Batch: true}}, nil
+	}
+	id2Resp := make(map[string]*JSONRPCResponse, len(respObjs))
+	for _, respObj := range respObjs {
+		id2Resp[respObj.ID] = respObj
+	}
+	entries := make([]*TranscriptEntry, 0, len(reqObjs))
+	for _, reqObj := range reqObjs {
+		if respObj, ok := id2Resp[reqObj.ID]; ok {
+			entries = append(entries, newTranscriptEntry(reqObj, respObj))
+		}
+	}
+	return entries, nil
+}
+
+func newTranscriptEntry(reqObj *transcriptRequest, respObj *JSONRPCResponse) *TranscriptEntry {
+	entry := &TranscriptEntry{
+		Method: reqObj.Method,
+		Params: reqObj.Params,
+		Error:  respObj.Error,
+	}
+	if respObj.Error == nil {
+		entry.Result = respObj.Result
+	}
+	return entry
+}
+
+// transcriptRequest is a JSON-RPC request keeping its params as sent.
+type transcriptRequest struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	ID     string          `json:"id"`
+}
+
+func parseRequests(body []byte) ([]*transcriptRequest, bool, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var reqObjs []*transcriptRequest
+		err := json.Unmarshal(body, &reqObjs)
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid batch request: %v", err)
+		}
+		return reqObjs, true, nil
+	}
+	reqObj := &transcriptRequest{}
+	err := json.Unmarshal(body, reqObj)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid request: %v", err)
+	}
+	return []*transcriptRequest{reqObj}, false, nil
+}
+
+// Replayer is a http.RoundTripper serving the calls of a transcript written by Recorder.
+// The calls must come in the recorded order with the recorded method and params,
+// otherwise the request fails.
+type Replayer struct {
+	mtx     sync.Mutex
+	entries []*TranscriptEntry
+	next    int
+}
+
+// NewReplayer loads the transcript file at path.
+func NewReplayer(path string) (*Replayer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open transcript: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<30)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("fail to read transcript header: %v", scanner.Err())
+	}
+	header := &transcriptHeader{}
+	err = json.Unmarshal(scanner.Bytes(), header)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read transcript header: %v", err)
+	}
+	if header.Version != TRANSCRIPT_VERSION {
+		return nil, fmt.Errorf("unsupported transcript version %d", header.Version)
+	}
+
+	replayer := &Replayer{}
+	for scanner.Scan() {
+		entry := &TranscriptEntry{}
+		err = json.Unmarshal(scanner.Bytes(), entry)
+		if err != nil {
+			return nil, fmt.Errorf("fail to read transcript entry %d: %v", len(replayer.entries)+1, err)
+		}
+		replayer.entries = append(replayer.entries, entry)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("fail to read transcript: %v", err)
+	}
+	return replayer, nil
+}
+
+// Remaining returns the number of recorded calls not replayed yet.
+func (replayer *Replayer) Remaining() int {
+	replayer.mtx.Lock()
+	defer replayer.mtx.Unlock()
+	return len(replayer.entries) - replayer.next
+}
+
+func (replayer *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body, 0)
+	if err != nil {
+		return nil, err
+	}
+	reqObjs, isBatch, err := parseRequests(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	replayer.mtx.Lock()
+	defer replayer.mtx.Unlock()
+
+	if isBatch && replayer.next < len(replayer.entries) && replayer.entries[replayer.next].Batch {
+		entry := replayer.entries[replayer.next]
+		replayer.next++
+		if entry.HTTPStatus != 0 {
+			return newReplayResponse(req, entry.HTTPStatus, []byte(entry.HTTPBody)), nil
+		}
+		body, _ := json.Marshal(&JSONRPCResponse{Error: entry.Error})
+		return newReplayResponse(req, http.StatusOK, body), nil
+	}
+
+	respObjs := make([]*replayResponse, 0, len(reqObjs))
+	for _, reqObj := range reqObjs {
+		if replayer.next >= len(replayer.entries) {
+			return nil, fmt.Errorf("unexpected call %s %s: transcript exhausted", reqObj.Method, reqObj.Params)
+		}
+		entry := replayer.entries[replayer.next]
+		if entry.Method != reqObj.Method || !sameJSON(entry.Params, reqObj.Params) {
+			return nil, fmt.Errorf("unexpected call %s %s: expected %s %s", reqObj.Method, reqObj.Params, entry.Method, entry.Params)
+		}
+		replayer.next++
+		if entry.HTTPStatus != 0 {
+			return newReplayResponse(req, entry.HTTPStatus, []byte(entry.HTTPBody)), nil
+		}
+		result := entry.Result
+		if result == nil {
+			result = json.RawMessage("null")
+		}
+		respObjs = append(respObjs, &replayResponse{Result: result, Error: entry.Error, ID: reqObj.ID})
+	}
+
+	var body []byte
+	if isBatch {
+		body, _ = json.Marshal(respObjs)
+	} else {
+		body, _ = json.Marshal(respObjs[0])
+	}
+	return newReplayResponse(req, http.StatusOK, body), nil
+}
+
+type replayResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+	ID     string          `json:"id"`
+}
+
+func newReplayResponse(req *http.Request, statusCode int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// sameJSON reports whether a and b are the same JSON value, ignoring formatting.
+func sameJSON(a json.RawMessage, b json.RawMessage) bool {
+	var aValue, bValue any
+	if json.Unmarshal(a, &aValue) != nil || json.Unmarshal(b, &bValue) != nil {
+		return bytes.Equal(a, b)
+	}
+	aNorm, _ := json.Marshal(aValue)
+	bNorm, _ := json.Marshal(bValue)
+	return bytes.Equal(aNorm, bNorm)
+}
@@ -0,0 +1,179 @@
+package abelian_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+// transcriptCalls makes calls of each kind a transcript keeps, calling beforeFault before the one
+// expected to fail with a http status, and returns their results as strings.
+func transcriptCalls(client *abelian.Client, beforeFault func()) []string {
+	var results []string
+	add := func(res any, err error) {
+		results = append(results, fmt.Sprintf("%v, %v", res, err))
+	}
+	add(client.GetBlockCount())
+	blockHash, err := client.GetBlockHash(1)
+	add(blockHash, err)
+	block, err := client.GetBlock(blockHash)
+	if block != nil {
+		add(block.BlockHash, err)
+	} else {
+		add(nil, err)
+	}
+	add(client.GetBlockHash(100))
+	beforeFault()
+	add(client.GetBlockHash(2))
+	batchResults, err := client.Batch().GetBlockHash(1).GetBlockHash(100).Send(context.Background())
+	for _, res := range batchResults {
+		add(res.Result, res.Err)
+	}
+	if err != nil {
+		add(nil, err)
+	}
+	return results
+}
+
+func TestTranscriptReplay(t *testing.T) {
+	tests := []struct {
+		name  string
+		batch bool // the node accepts batch requests, otherwise the transcript keeps its refusal
+	}{
+		{"batch", true},
+		{"no batch", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []abeliantest.Option
+			if tt.batch {
+				options = append(options, abeliantest.WithBatch())
+			}
+			server := abeliantest.NewServer(options...)
+			defer server.Close()
+			addTestBlocks(server, 3)
+
+			path := filepath.Join(t.TempDir(), "transcript.jsonl")
+			client, err := server.NewClient(abelian.WithRecorder(path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			recorded := transcriptCalls(client, func() {
+				server.InjectFault("getblockhash", &abeliantest.Fault{HTTPStatus: http.StatusServiceUnavailable}, 1)
+			})
+			client.Close()
+			for index, want := range map[int]string{0: "<nil>", 3: "out of range", 4: "503"} {
+				if !strings.Contains(recorded[index], want) {
+					t.Fatalf("recorded result %d = %s, want %s", index, recorded[index], want)
+				}
+			}
+
+			replayer, err := abelian.NewReplayer(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client, err = abelian.NewClient(abelian.NewClientConfig("http://127.0.0.1:1", abelian.WithRoundTripper(replayer)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+			replayed := transcriptCalls(client, func() {})
+			if !reflect.DeepEqual(replayed, recorded) {
+				t.Errorf("replayed results\n%q\nwant\n%q", replayed, recorded)
+			}
+			if remaining := replayer.Remaining(); remaining != 0 {
+				t.Errorf("%d calls not replayed", remaining)
+			}
+
+			// the transcript is exhausted
+			_, err = client.GetBlockCount()
+			if err == nil || !strings.Contains(err.Error(), "transcript exhausted") {
+				t.Errorf("GetBlockCount after the transcript = %v, want transcript exhausted", err)
+			}
+		})
+	}
+}
+
+func TestTranscriptReplayOutOfOrder(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	addTestBlocks(server, 3)
+	path := filepath.Join(t.TempDir(), "transcript.jsonl")
+	client, err := server.NewClient(abelian.WithRecorder(path), abelian.WithVersionCheck(abelian.VersionCheckNone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for height := int64(1); height <= 2; height++ {
+		if _, err := client.GetBlockHash(height); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client.Close()
+
+	replayer, err := abelian.NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err = abelian.NewClient(abelian.NewClientConfig("http://127.0.0.1:1",
+		abelian.WithRoundTripper(replayer), abelian.WithVersionCheck(abelian.VersionCheckNone)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	for _, call := range []func() (string, error){
+		func() (string, error) { return client.GetBlockHash(2) },
+		func() (string, error) { return client.GetBestBlockHash() },
+	} {
+		_, err = call()
+		if err == nil || !strings.Contains(err.Error(), "unexpected call") {
+			t.Errorf("call out of order = %v, want unexpected call", err)
+		}
+	}
+	if remaining := replayer.Remaining(); remaining != 2 {
+		t.Errorf("%d calls remaining after calls out of order, want 2", remaining)
+	}
+	if blockHash, err := client.GetBlockHash(1); err != nil || blockHash != server.BlockByHeight(1).BlockHash {
+		t.Errorf("GetBlockHash(1) = %s, %v, want %s", blockHash, err, server.BlockByHeight(1).BlockHash)
+	}
+}
+
+func TestRecorderMaxResponseSize(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	addTestBlocks(server, 1)
+	path := filepath.Join(t.TempDir(), "transcript.jsonl")
+	client, err := server.NewClient(abelian.WithRecorder(path), abelian.WithMaxResponseSize(256),
+		abelian.WithVersionCheck(abelian.VersionCheckNone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetBlockCount(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.GetBlock(server.BlockByHeight(1).BlockHash)
+	var tooLarge *abelian.ResponseTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 256 {
+		t.Errorf("GetBlock of a block larger than the limit = %v, want *ResponseTooLargeError", err)
+	}
+	if _, err := client.GetBlockCount(); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	// the response over the limit is not recorded
+	replayer, err := abelian.NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining := replayer.Remaining(); remaining != 2 {
+		t.Errorf("%d calls recorded, want 2", remaining)
+	}
+}
@@ -0,0 +1,211 @@
+package abelian
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Transport carries serialized JSON-RPC payloads to a node and back. The payload is a single
+// request object or a batch array, and so is the response. HTTPTransport is the default one.
+type Transport interface {
+	RoundTrip(ctx context.Context, payload []byte) ([]byte, error)
+	Close() error
+}
+
+// StreamTransport is a Transport which can also return the response as a stream, so that large
+// results are decoded as they are received instead of being held in memory. HTTPTransport is one.
+type StreamTransport interface {
+	Transport
+	RoundTripStream(ctx context.Context, payload []byte) (io.ReadCloser, error)
+}
+
+// TransportFunc adapts a function to Transport, e.g. to serve the calls of a client in memory.
+type TransportFunc func(ctx context.Context, payload []byte) ([]byte, error)
+
+func (f TransportFunc) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	return f(ctx, payload)
+}
+
+func (f TransportFunc) Close() error {
+	return nil
+}
+
+// HTTPTransport posts the payloads to the endpoint of abec with basic auth.
+type HTTPTransport struct {
+	client   *http.Client
+	endpoint string
+	recorder *Recorder // set if the calls are recorded
+	maxSize  int64     // maximum size of a response, 0 for no limit
+
+	mtx      sync.RWMutex
+	username string
+	password string
+}
+
+// NewHTTPTransport creates the transport of config: its endpoint, auth, tls settings,
+// RoundTripper and RecordFile.
+func NewHTTPTransport(config *ClientConfig) (*HTTPTransport, error) {
+	httpClient := &http.Client{}
+	if config.RoundTripper != nil {
+		httpClient.Transport = config.RoundTripper
+	} else if config.EnableTLS {
+		tlsConfig, err := newTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = &http.Transport{
+			TLSClientConfig: tlsConfig,
+		}
+	}
+	var recorder *Recorder
+	if config.RecordFile != "" {
+		var err error
+		recorder, err = NewRecorder(config.RecordFile, httpClient.Transport)
+		if err != nil {
+			return nil, err
+		}
+		recorder.maxSize = config.MaxResponseSize
+		httpClient.Transport = recorder
+	}
+	return &HTTPTransport{
+		client:   httpClient,
+		endpoint: config.Endpoint,
+		recorder: recorder,
+		maxSize:  config.MaxResponseSize,
+		username: config.Username,
+		password: config.Password,
+	}, nil
+}
+
+// SetAuth replaces the basic auth credentials of the following requests.
+func (transport *HTTPTransport) SetAuth(username string, password string) {
+	transport.mtx.Lock()
+	defer transport.mtx.Unlock()
+	transport.username = username
+	transport.password = password
+}
+
+func (transport *HTTPTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	body, err := transport.RoundTripStream(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	res, err := io.ReadAll(body)
+	if err != nil {
+		sdkLog.Errorf("fail to read response: %v", err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// RoundTripStream posts payload and returns the body of the response, which must be closed.
+// Reading more than the maximum response size fails with *ResponseTooLargeError.
+func (transport *HTTPTransport) RoundTripStream(ctx context.Context, payload []byte) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, transport.endpoint, bytes.NewBuffer(payload))
+	if err != nil {
+		sdkLog.Errorf("fail to create request: %v", err)
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	transport.mtx.RLock()
+	req.SetBasicAuth(transport.username, transport.password)
+	transport.mtx.RUnlock()
+
+	resp, err := transport.client.Do(req)
+	if err != nil {
+		sdkLog.Errorf("fail to do request: %v", err)
+		return nil, err
+	}
+	if transport.maxSize > 0 && resp.ContentLength > transport.maxSize {
+		resp.Body.Close()
+		err = &ResponseTooLargeError{Limit: transport.maxSize}
+		sdkLog.Errorf("fail to do request: %v", err)
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		err = &HTTPError{
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(message)),
+		}
+		sdkLog.Errorf("fail to do request: %v", err)
+		return nil, err
+	}
+	if transport.maxSize > 0 {
+		return &limitedBody{body: resp.Body, limit: transport.maxSize, remaining: transport.maxSize}, nil
+	}
+	return resp.Body, nil
+}
+
+// limitedBody fails with *ResponseTooLargeError once more than limit bytes are read from body.
+type limitedBody struct {
+	body      io.ReadCloser
+	limit     int64
+	remaining int64
+}
+
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	if lb.remaining < 0 {
+		return 0, &ResponseTooLargeError{Limit: lb.limit}
+	}
+	// read one byte more than remaining to tell a body of exactly limit bytes from a longer one
+	if int64(len(p)) > lb.remaining+1 {
+		p = p[:lb.remaining+1]
+	}
+	n, err := lb.body.Read(p)
+	lb.remaining -= int64(n)
+	if lb.remaining < 0 {
+		return n + int(lb.remaining), &ResponseTooLargeError{Limit: lb.limit}
+	}
+	return n, err
+}
+
+func (lb *limitedBody) Close() error {
+	return lb.body.Close()
+}
+
+// Close closes the idle connections and the transcript file if any.
+func (transport *HTTPTransport) Close() error {
+	transport.client.CloseIdleConnections()
+	if transport.recorder != nil {
+		return transport.recorder.Close()
+	}
+	return nil
+}
+
+// Invoker sends one JSON-RPC call and returns its raw result.
+type Invoker func(ctx context.Context, method string, params []interface{}) (json.RawMessage, error)
+
+// Middleware wraps the Invoker of a client to observe or alter its calls, e.g. for logging,
+// metrics, refreshing credentials or injecting faults:
+//
+//	func logging(next abelian.Invoker) abelian.Invoker {
+//		return func(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+//			start := time.Now()
+//			result, err := next(ctx, method, params)
+//			log.Printf("%s took %v: %v", method, time.Since(start), err)
+//			return result, err
+//		}
+//	}
+//
+// Middlewares see each attempt of a retried call. A batch request is one call of BATCH_METHOD.
+// The serialized blocks and transactions of GetBlockBytes, GetTxBytes and their To variants are
+// decoded as they are read from a StreamTransport, and their calls return a nil result to the
+// middlewares. A middleware may still answer such a call itself with a hex string result.
+type Middleware func(next Invoker) Invoker
+
+// chainMiddlewares wraps invoker with middlewares, the first one being the outermost.
+func chainMiddlewares(invoker Invoker, middlewares []Middleware) Invoker {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		invoker = middlewares[i](invoker)
+	}
+	return invoker
+}
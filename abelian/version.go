@@ -1,7 +1,210 @@
 package abelian
 
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
 // SDKName is the name of this ABELIAN SDK
 const SDKName = "abelian-sdk-go-v2"
 
 // SDKVersion is the version of this SDK
 const SDKVersion = "0.0.1-alpha"
+
+// MIN_NODE_PROTOCOL_VERSION is the lowest protocol version of abec supported by this SDK.
+const MIN_NODE_PROTOCOL_VERSION = 70002
+
+// NodeVersion is the version of abec, reported by getinfo as 1000000*major + 10000*minor + 100*patch.
+type NodeVersion struct {
+	Major int64
+	Minor int64
+	Patch int64
+}
+
+func ParseNodeVersion(version int64) NodeVersion {
+	return NodeVersion{
+		Major: version / 1000000,
+		Minor: version / 10000 % 100,
+		Patch: version / 100 % 100,
+	}
+}
+
+func (v NodeVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or higher than other.
+func (v NodeVersion) Compare(other NodeVersion) int {
+	switch {
+	case v.Major != other.Major:
+		return compareInt64(v.Major, other.Major)
+	case v.Minor != other.Minor:
+		return compareInt64(v.Minor, other.Minor)
+	default:
+		return compareInt64(v.Patch, other.Patch)
+	}
+}
+
+func compareInt64(a int64, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// NodeVersionRange is the versions from Min up to, but excluding, Max.
+type NodeVersionRange struct {
+	Min NodeVersion
+	Max NodeVersion
+}
+
+func (r NodeVersionRange) Contains(v NodeVersion) bool {
+	return v.Compare(r.Min) >= 0 && v.Compare(r.Max) < 0
+}
+
+func (r NodeVersionRange) String() string {
+	return fmt.Sprintf("[%s, %s)", r.Min, r.Max)
+}
+
+// SupportedNodeVersions are the versions of abec this SDK is built and tested against.
+var SupportedNodeVersions = []NodeVersionRange{
+	{Min: NodeVersion{Major: 1}, Max: NodeVersion{Major: 3}},
+}
+
+func supportedNodeVersionsString() string {
+	ranges := make([]string, len(SupportedNodeVersions))
+	for i, r := range SupportedNodeVersions {
+		ranges[i] = r.String()
+	}
+	return strings.Join(ranges, " ")
+}
+
+// CheckNodeVersion returns *UnsupportedNodeVersionError if the node version or protocol version
+// reported by getinfo is not supported by this SDK.
+func CheckNodeVersion(version int64, protocolVersion int64) error {
+	return checkNodeVersion(ParseNodeVersion(version), protocolVersion)
+}
+
+func checkNodeVersion(nodeVersion NodeVersion, protocolVersion int64) error {
+	if protocolVersion >= MIN_NODE_PROTOCOL_VERSION {
+		for _, r := range SupportedNodeVersions {
+			if r.Contains(nodeVersion) {
+				return nil
+			}
+		}
+	}
+	return &UnsupportedNodeVersionError{Version: nodeVersion, ProtocolVersion: protocolVersion}
+}
+
+// VersionCheck is how a client reacts to a node of a version not supported by this SDK.
+type VersionCheck int
+
+const (
+	VersionCheckWarn   VersionCheck = iota // log a warning whenever getinfo reports an unsupported version
+	VersionCheckStrict                     // make NewClient fail with *UnsupportedNodeVersionError, or mark an endpoint unhealthy
+	VersionCheckNone                       // do not check
+)
+
+// nodeInfo is what the client knows about its node from getinfo.
+type nodeInfo struct {
+	networkID       NetworkID
+	version         NodeVersion
+	protocolVersion int64
+}
+
+// observeNode records the node described by getinfo, and warns if its version changed to an
+// unsupported one. The network of a node can not change and is kept from the first call.
+func (client *Client) observeNode(info *ChainInfo) {
+	client.nodeMtx.Lock()
+	defer client.nodeMtx.Unlock()
+	node := nodeInfo{
+		networkID:       NetworkID(info.NetID),
+		version:         ParseNodeVersion(info.Version),
+		protocolVersion: info.ProtocolVersion,
+	}
+	previous := client.node
+	if previous != nil {
+		node.networkID = previous.networkID
+	}
+	client.node = &node
+	// the endpoints of a multi-endpoint client warn on their own
+	if client.versionCheck != VersionCheckWarn || client.pool != nil {
+		return
+	}
+	if previous != nil && previous.version == node.version && previous.protocolVersion == node.protocolVersion {
+		return
+	}
+	err := checkNodeVersion(node.version, node.protocolVersion)
+	if err != nil {
+		sdkLog.Warnf("node %s: %v", client.Endpoint, err)
+	}
+}
+
+// checkNode queries getinfo unless the client checks neither the network nor the version, and returns
+// *NetworkMismatchError or *UnsupportedNodeVersionError if the node does not meet them. With
+// VersionCheckWarn alone, an unsupported version or a failure to query getinfo is only logged.
+func (client *Client) checkNode(ctx context.Context) error {
+	if client.expectedNetwork == nil && client.versionCheck == VersionCheckNone {
+		return nil
+	}
+	node, err := client.nodeInfoCtx(ctx)
+	if err != nil {
+		if client.expectedNetwork == nil && client.versionCheck == VersionCheckWarn {
+			sdkLog.Warnf("node %s: fail to get info of the node: %v", client.Endpoint, err)
+			return nil
+		}
+		return fmt.Errorf("fail to get info of the node: %w", err)
+	}
+	if client.expectedNetwork != nil && node.networkID != *client.expectedNetwork {
+		return &NetworkMismatchError{Expected: *client.expectedNetwork, Actual: node.networkID}
+	}
+	if client.versionCheck == VersionCheckStrict {
+		return checkNodeVersion(node.version, node.protocolVersion)
+	}
+	return nil
+}
+
+// verifyNode is checkNode until the node passes it once.
+func (client *Client) verifyNode(ctx context.Context) error {
+	if client.nodeChecked.Load() {
+		return nil
+	}
+	err := client.checkNode(ctx)
+	if err != nil {
+		return err
+	}
+	client.nodeChecked.Store(true)
+	return nil
+}
+
+// nodeInfoCtx returns what is known about the node, querying getinfo the first time.
+func (client *Client) nodeInfoCtx(ctx context.Context) (nodeInfo, error) {
+	client.nodeMtx.Lock()
+	node := client.node
+	client.nodeMtx.Unlock()
+	if node != nil {
+		return *node, nil
+	}
+
+	_, err := client.GetChainInfoCtx(ctx)
+	if err != nil {
+		return nodeInfo{}, err
+	}
+	client.nodeMtx.Lock()
+	defer client.nodeMtx.Unlock()
+	return *client.node, nil
+}
+
+// NodeVersion returns the version of the node, as last reported by getinfo.
+func (client *Client) NodeVersion() (NodeVersion, error) {
+	return client.NodeVersionCtx(context.Background())
+}
+func (client *Client) NodeVersionCtx(ctx context.Context) (NodeVersion, error) {
+	node, err := client.nodeInfoCtx(ctx)
+	return node.version, err
+}
@@ -0,0 +1,111 @@
+package abelian_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
+	"github.com/pqabelian/abelian-sdk-go-v2/abelian/abeliantest"
+)
+
+func TestNodeVersion(t *testing.T) {
+	tests := []struct {
+		version         int64
+		protocolVersion int64
+		want            abelian.NodeVersion
+		wantSupported   bool
+	}{
+		{1000000, 70002, abelian.NodeVersion{Major: 1}, true},
+		{1000100, 70002, abelian.NodeVersion{Major: 1, Patch: 1}, true},
+		{1020300, 70002, abelian.NodeVersion{Major: 1, Minor: 2, Patch: 3}, true},
+		{120000, 70002, abelian.NodeVersion{Minor: 12}, false},
+		{1000000, 70001, abelian.NodeVersion{Major: 1}, false},
+		{3000000, 70002, abelian.NodeVersion{Major: 3}, false},
+	}
+	for _, tt := range tests {
+		version := abelian.ParseNodeVersion(tt.version)
+		if version != tt.want {
+			t.Errorf("ParseNodeVersion(%d) = %v, want %v", tt.version, version, tt.want)
+		}
+		err := abelian.CheckNodeVersion(tt.version, tt.protocolVersion)
+		if (err == nil) != tt.wantSupported {
+			t.Errorf("CheckNodeVersion(%d, %d) = %v, want supported %v", tt.version, tt.protocolVersion, err, tt.wantSupported)
+		}
+		if err != nil && !errors.Is(err, abelian.ErrUnsupportedNodeVersion) {
+			t.Errorf("CheckNodeVersion(%d, %d) = %v, want ErrUnsupportedNodeVersion", tt.version, tt.protocolVersion, err)
+		}
+	}
+}
+
+func TestClientVersionCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     abelian.NodeVersion
+		check       abelian.VersionCheck
+		wantErr     bool
+		wantGetInfo int // getinfo calls of NewClient
+	}{
+		{"supported", abelian.NodeVersion{Major: 1}, abelian.VersionCheckStrict, false, 1},
+		{"unsupported strict", abelian.NodeVersion{Minor: 12}, abelian.VersionCheckStrict, true, 1},
+		{"unsupported warn", abelian.NodeVersion{Minor: 12}, abelian.VersionCheckWarn, false, 1},
+		{"unsupported none", abelian.NodeVersion{Minor: 12}, abelian.VersionCheckNone, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := abeliantest.NewServer(abeliantest.WithVersion(tt.version, abelian.MIN_NODE_PROTOCOL_VERSION))
+			defer server.Close()
+			client, err := server.NewClient(abelian.WithVersionCheck(tt.check))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewClient error = %v, want error %v", err, tt.wantErr)
+			}
+			if calls := server.CallCount("getinfo"); calls != tt.wantGetInfo {
+				t.Errorf("getinfo calls of NewClient = %d, want %d", calls, tt.wantGetInfo)
+			}
+			if err != nil {
+				return
+			}
+			defer client.Close()
+			version, err := client.NodeVersion()
+			if err != nil || version != tt.version {
+				t.Errorf("NodeVersion = %v, %v, want %v", version, err, tt.version)
+			}
+		})
+	}
+}
+
+func TestDefaultServerVersion(t *testing.T) {
+	server := abeliantest.NewServer()
+	defer server.Close()
+	client, err := server.NewClient(abelian.WithVersionCheck(abelian.VersionCheckStrict))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	version, err := client.NodeVersion()
+	if err != nil || version != (abelian.NodeVersion{Major: 1}) {
+		t.Errorf("NodeVersion = %v, %v, want 1.0.0", version, err)
+	}
+}
+
+func TestClientVersionCheckNodeDown(t *testing.T) {
+	server := abeliantest.NewServer()
+	config := server.ClientConfig()
+	server.Close()
+	tests := []struct {
+		check   abelian.VersionCheck
+		wantErr bool
+	}{
+		{abelian.VersionCheckWarn, false},
+		{abelian.VersionCheckStrict, true},
+	}
+	for _, tt := range tests {
+		config.VersionCheck = tt.check
+		client, err := abelian.NewClient(config)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewClient with check %d of a node down = %v, want error %v", tt.check, err, tt.wantErr)
+		}
+		if client != nil {
+			client.Close()
+		}
+	}
+}
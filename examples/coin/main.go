@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"fmt"
 	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
@@ -25,7 +26,7 @@ func ScanCoins(viewAccounts []*database.ViewAccount, tx *abelian.Tx, isCoinbaseT
 			}
 
 			fmt.Printf("💰 Find coin of account with account id %d: block id %s, block height %d, transacion id %s, index %d, value %v ABELs\n",
-				viewAccount.ID, blockID, blockHeight, tx.TxHash, index, abelian.NeutrinoToAbel(int64(value)))
+				viewAccount.ID, blockID, blockHeight, tx.TxHash, index, abelian.Amount(value))
 
 			_, err = database.InsertCoin(viewAccount.ID, tx.Version, tx.TxID, uint8(index), blockID, blockHeight, int64(value), isCoinbaseTx, txOutData)
 			if err != nil {
@@ -54,7 +55,7 @@ func TrackCoins(tx *abelian.Tx) error {
 					}
 
 					fmt.Printf("💸 Coin of account with account id %d is consumed: block id %s, block height %d, transacion id %s, index %d, value %v ABELs\n",
-						coin.AccountID, coin.BlockHash, coin.BlockHeight, tx.TxHash, index, abelian.NeutrinoToAbel(coin.Value))
+						coin.AccountID, coin.BlockHash, coin.BlockHeight, tx.TxHash, index, coin.Value)
 
 					break
 				}
@@ -65,12 +66,13 @@ func TrackCoins(tx *abelian.Tx) error {
 	return nil
 }
 func BuildCoinRingsWithBlockHeight(height int64) (map[abelian.CoinID]*abelian.CoinRing, error) {
-	serializedBlockGroups, err := abelian.GetRingBlockGroupByHeight(client, height)
+	// the provider fetches the blocks of the group in parallel, and reuses the group and its rings
+	ringGroup, err := ringGroups.GetRingGroup(height)
 	if err != nil {
 		return nil, fmt.Errorf("fail to get block group with height %d: %v", height, err)
 	}
 
-	coinIDRings, err := abelian.BuildCoinRings(serializedBlockGroups)
+	coinIDRings, err := ringGroups.BuildCoinRings(ringGroup)
 	if err != nil {
 		return nil, fmt.Errorf("fail to build txo rings with height %d: %v", height, err)
 	}
@@ -87,12 +89,12 @@ func BuildCoinRingsWithBlockHeight(height int64) (map[abelian.CoinID]*abelian.Co
 func HandleCoinMaturity(height int64) error {
 	// handle coinbase coin maturity
 	fmt.Printf("handle coinbase maturity in block with height %d \n", height)
-	immatureCoinbaseCoins, err := database.LoadImmatureCoinbaseCoins(height - abelian.GetCoinbaseMaturity())
+	immatureCoinbaseCoins, err := database.LoadImmatureCoinbaseCoins(height - chainParams.CoinbaseMaturity)
 	if err != nil {
 		panic(fmt.Errorf("fail to load immature coinbase coins from database"))
 	}
 	for _, coin := range immatureCoinbaseCoins {
-		fmt.Printf("🎉 coinbase coin (%s,%d) %v ABELs is mature\n", coin.TxID, coin.Index, abelian.NeutrinoToAbel(coin.Value))
+		fmt.Printf("🎉 coinbase coin (%s,%d) %v ABELs is mature\n", coin.TxID, coin.Index, coin.Value)
 		err = database.MatureCoin(coin.ID)
 		if err != nil {
 			panic(fmt.Errorf("fail to mature immature coinbase coins"))
@@ -100,7 +102,7 @@ func HandleCoinMaturity(height int64) error {
 	}
 
 	// handle transfer coins maturity
-	blockNum := int64(abelian.GetBlockNumPerRingGroupByBlockHeight(height))
+	blockNum := int64(chainParams.BlockNumPerRingGroupByBlockHeight(height))
 	if height%blockNum != blockNum-1 {
 		return nil
 	}
@@ -186,23 +188,36 @@ func HandleCoinMaturity(height int64) error {
 		if err != nil {
 			panic(err)
 		}
-		fmt.Printf("📢 Ring related for coin (%s,%d) %v ABELs is inserted\n", coin.TxID, coin.Index, abelian.NeutrinoToAbel(coin.Value))
+		fmt.Printf("📢 Ring related for coin (%s,%d) %v ABELs is inserted\n", coin.TxID, coin.Index, coin.Value)
 
 		// lastly mark coin as mature
 		err = database.MatureCoin(coin.ID)
 		if err != nil {
 			panic(fmt.Errorf("fail to mature immature coins"))
 		}
-		fmt.Printf("🎉 transfer coin (%s,%d) %v ABELs mature with ring info (%s,%d) \n", coin.TxID, coin.Index, abelian.NeutrinoToAbel(coin.Value), ringId, ringIndex)
+		fmt.Printf("🎉 transfer coin (%s,%d) %v ABELs mature with ring info (%s,%d) \n", coin.TxID, coin.Index, coin.Value, ringId, ringIndex)
 	}
 
 	return nil
 }
 
 var client *abelian.Client
+var ringGroups *abelian.RingGroupProvider
+var chainParams *abelian.ChainParams
 
 func init() {
 	client = common.Client
+
+	// consensus parameters of the network of the node
+	var err error
+	chainParams, err = client.ChainParams()
+	if err != nil {
+		panic(err)
+	}
+	ringGroups, err = abelian.NewRingGroupProvider(client, abelian.NewRingGroupConfig())
+	if err != nil {
+		panic(err)
+	}
 }
 
 func main() {
@@ -220,20 +235,15 @@ func main() {
 	startScanHeight := common.GetCoinScanStartHeight()
 	endScanHeight := common.GetCoinScanEndHeight()
 
-	// scan blocks with specified height scope
-	for currentHeight := startScanHeight; currentHeight < endScanHeight; currentHeight++ {
-		block, err := client.GetBlockByHeight(currentHeight)
-		if err != nil {
-			panic(fmt.Errorf("fail to query block: %v", err))
-		}
+	// scan blocks with specified height scope, fetched in parallel and delivered in height order
+	stream := client.FetchBlocks(context.Background(), startScanHeight, endScanHeight, nil)
+	defer stream.Close()
+	for stream.Next() {
+		block := stream.Block()
+		currentHeight := block.Height
 
 		fmt.Printf("Scan and track coins in block with height %d\n", block.Height)
-		for i := 0; i < len(block.TxHashes); i++ {
-			tx, err := client.GetRawTx(block.TxHashes[i])
-			if err != nil {
-				panic(fmt.Errorf("fail to query transaction: %v", err))
-			}
-
+		for i, tx := range block.RawTxs {
 			// scan coin in transaction
 			err = ScanCoins(viewAccounts, tx, i == 0, block.BlockHash, block.Height)
 			if err != nil {
@@ -257,4 +267,7 @@ func main() {
 			panic(fmt.Errorf("fail to hanle coin maturity :%v", err))
 		}
 	}
+	if err = stream.Err(); err != nil {
+		panic(fmt.Errorf("fail to query block: %v", err))
+	}
 }
@@ -44,22 +44,14 @@ func init() {
 	clientConfig := abelian.NewClientConfig(
 		config.RPC.Endpoint,
 		abelian.WithAuth(config.RPC.UserName, config.RPC.Password),
+		abelian.WithNetworkID(config.NetworkID),
 	)
+	// the network id of the node is asserted by NewClient
 	Client, err = abelian.NewClient(clientConfig)
-
-	// Assert network
-	info, err := Client.GetChainInfo()
 	if err != nil {
-		panic(fmt.Errorf("fail to get block hash: %v", err))
-	}
-	fmt.Printf("chain info: %#+v\n", info)
-
-	// assert network id
-	if info.NetID == uint8(config.NetworkID) {
-		fmt.Printf("network id is matched: %s\n", config.NetworkID)
-	} else {
-		panic(fmt.Errorf("network id is unmatched: %d", info.NetID))
+		panic(fmt.Errorf("fail to create client: %v", err))
 	}
+	fmt.Printf("network id is matched: %s\n", config.NetworkID)
 }
 
 func GetNetworkID() abelian.NetworkID {
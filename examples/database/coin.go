@@ -144,7 +144,7 @@ WHERE account_id !=0 AND is_coinbase=True AND status = 0 AND block_height < ?`,
 			ID:        id,
 			AccountID: accountID,
 			Coin: abelian.NewCoin(txVersion, txID, outputIndex,
-				blockID, blockHeight, value, "", nil),
+				blockID, blockHeight, abelian.Amount(value), "", nil),
 		})
 	}
 	return coins, err
@@ -186,7 +186,7 @@ WHERE account_id != 0 AND status = 0 AND block_height <= ?`, height)
 			ID:        id,
 			AccountID: accountID,
 			Coin: abelian.NewCoin(txVersion, txID, outputIndex,
-				blockID, blockHeight, value, "", nil),
+				blockID, blockHeight, abelian.Amount(value), "", nil),
 		})
 	}
 	return coins, nil
@@ -231,7 +231,7 @@ func LoadCoinByAccountID(id int64) ([]*Coin, error) {
 			return nil, err
 		}
 		abelianCoin := abelian.NewCoin(txVersion, txID, outputIndex,
-			blockID, blockHeight, value, "", data)
+			blockID, blockHeight, abelian.Amount(value), "", data)
 		abelianCoin.SetRingInfo(ringID, ringIndex)
 		coins = append(coins, &Coin{
 			ID:        id,
@@ -277,7 +277,7 @@ WHERE account_id!=0 AND serial_number = ?`, serialNumber)
 			ID:        id,
 			AccountID: accountID,
 			Coin: abelian.NewCoin(txVersion, txID, outputIndex,
-				blockID, blockHeight, value, "", nil),
+				blockID, blockHeight, abelian.Amount(value), "", nil),
 		})
 	}
 	return coins, err
@@ -326,7 +326,7 @@ func loadCoinByRingID(ringID string) ([]*Coin, error) {
 			return nil, err
 		}
 		abelianCoin := abelian.NewCoin(txVersion, txID, outputIndex,
-			blockID, blockHeight, value, "", data)
+			blockID, blockHeight, abelian.Amount(value), "", data)
 		abelianCoin.SetRingInfo(ringID, ringIndex)
 		coins = append(coins, &Coin{
 			ID:         ID,
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/pqabelian/abelian-sdk-go-v2/abelian"
 	"github.com/pqabelian/abelian-sdk-go-v2/examples/common"
@@ -62,7 +63,7 @@ func main() {
 
 	receiverInfos := []struct {
 		AbelAddress string
-		CoinValue   int64
+		CoinValue   abelian.Amount
 	}{
 		{
 			AbelAddress: receiverAddresses[1],
@@ -73,20 +74,20 @@ func main() {
 			CoinValue:   30_0000000,
 		},
 	}
-	targetValue := int64(0)
+	targetValue := abelian.Amount(0)
 	txOutDescs := make([]*abelian.TxOutDesc, len(receiverInfos))
 	for i, info := range receiverInfos {
 		abelAddress, err := hex.DecodeString(info.AbelAddress)
 		if err != nil {
 			panic("invalid abel address")
 		}
-		address, err := abelian.NewAbelAddress(abelAddress)
+		address, err := client.DecodeAbelAddress(abelAddress)
+		if errors.Is(err, abelian.ErrNetworkMismatch) {
+			panic("abel address with unmatched network id")
+		}
 		if err != nil {
 			panic("invalid abel address")
 		}
-		if address.GetNetID() != common.GetNetworkID() {
-			panic("abel address with unmatched network id")
-		}
 
 		txOutDescs[i] = &abelian.TxOutDesc{
 			AbelAddress: address,
@@ -115,13 +116,16 @@ func main() {
 	})
 
 	selectedCoins := []*database.Coin{}
-	selectValue := int64(0)
+	selectValue := abelian.Amount(0)
 	for i := 0; i < len(availableCoins); i++ {
 		if selectValue >= targetValue {
 			break
 		}
 		selectedCoins = append(selectedCoins, availableCoins[i])
-		selectValue += availableCoins[i].Value
+		selectValue, err = selectValue.Add(availableCoins[i].Value)
+		if err != nil {
+			panic(err)
+		}
 	}
 
 	// Build TxInDesc
@@ -168,24 +172,44 @@ func main() {
 		senderAccountIDs = append(senderAccountIDs, coin2AccountID[abelian.NewCoinID(desc.TxID, desc.TxOutIndex).String()])
 	}
 
-	// Estimated fee
-	estimatedTxFee := abelian.EstimateTxFee(txInDescs, txOutDescs)
+	// change, whose value is settled once the fee is estimated
+	changeAbelAddress, err := abelian.NewAbelAddressForNetwork(changeAddress, common.GetNetworkID())
+	if errors.Is(err, abelian.ErrNetworkMismatch) {
+		panic("change address with unmatched network id")
+	}
+	if err != nil {
+		panic("invalid abel address")
+	}
+	changeTxOutDesc := &abelian.TxOutDesc{
+		AbelAddress: changeAbelAddress,
+		CoinValue:   selectValue - targetValue,
+	}
+	txOutDescs = append(txOutDescs, changeTxOutDesc)
 
-	// change if needed
+	// Estimated fee with the fee rate of the node
+	feeEstimator := abelian.NewFeeEstimator(client, nil)
+	feeEstimate, err := feeEstimator.EstimateFee(txInDescs, txOutDescs, nil)
+	if err != nil {
+		panic(fmt.Errorf("fail to estimate tx fee: %v", err))
+	}
+	estimatedTxFee := feeEstimate.Fee
 	if selectValue-targetValue-estimatedTxFee > 0 {
-		changeAbelAddress, err := abelian.NewAbelAddress(changeAddress)
+		changeTxOutDesc.CoinValue = selectValue - targetValue - estimatedTxFee
+	} else {
+		// no change left, so drop it and estimate again
+		txOutDescs = txOutDescs[:len(txOutDescs)-1]
+		feeEstimate, err = feeEstimator.EstimateFee(txInDescs, txOutDescs, nil)
 		if err != nil {
-			panic("invalid abel address")
+			panic(fmt.Errorf("fail to estimate tx fee: %v", err))
 		}
-		if changeAbelAddress.GetNetID() != common.GetNetworkID() {
-			panic("change address with unmatched network id")
+		estimatedTxFee = feeEstimate.Fee
+		if selectValue-targetValue < estimatedTxFee {
+			panic(fmt.Errorf("insufficient coins to pay tx fee %d", estimatedTxFee))
 		}
-
-		txOutDescs = append(txOutDescs, &abelian.TxOutDesc{
-			AbelAddress: changeAbelAddress,
-			CoinValue:   selectValue - targetValue - estimatedTxFee,
-		})
+		estimatedTxFee = selectValue - targetValue
 	}
+	fmt.Printf("Estimated tx size: %d bytes, fee rate: %d neutrino/kB, fee: %v ABEL\n",
+		feeEstimate.Size.FullSize(), feeEstimate.FeeRate, estimatedTxFee)
 
 	// [IMPORTANT] sort txOutDescs
 	err = abelian.SortTxOutDesc(txOutDescs)
@@ -206,8 +230,12 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	// Broadcast signed transaction
-	returnedTxHash, err := client.SendRawTx(hex.EncodeToString(signedRawTx.Data))
+	if txSize, err := abelian.GetSignedRawTxSize(signedRawTx); err == nil {
+		fmt.Printf("Signed tx size: %d bytes\n", txSize.FullSize())
+	}
+
+	// Broadcast signed transaction, checking its outputs are on the network of the node
+	returnedTxHash, err := client.SendSignedRawTx(signedRawTx)
 	if err != nil {
 		panic(fmt.Errorf("fail to send raw tx: %v", err))
 	}
@@ -3,6 +3,7 @@ module github.com/pqabelian/abelian-sdk-go-v2
 go 1.22
 
 require (
+	github.com/gorilla/websocket v1.4.2
 	github.com/jrick/logrotate v1.0.0
 	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/pqabelian/abec v1.0.1-0.20240914150420-bdd2c1c1808c